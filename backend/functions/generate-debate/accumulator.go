@@ -2,13 +2,15 @@ package generatedebate
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
-	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/store"
 )
 
 // DebateMessage represents an accumulated message
@@ -18,19 +20,37 @@ type DebateMessage struct {
 	PanelistName string
 	AvatarURL    string
 	Text         string
+	Addresses    []string
+	Citations    []firebase.Citation
+	SpeakerType  string
+	Phase        string
 	Sequence     int
 	Timestamp    time.Time
 }
 
 // DebateAccumulator accumulates debate messages during streaming
 type DebateAccumulator struct {
-	DebateID        string
-	Topic           string
-	Panelists       []Panelist
-	Messages        []DebateMessage
-	PanelistMap     map[string]Panelist
-	CurrentSequence int
-	StartedAt       time.Time
+	mu               sync.Mutex
+	version          int // incremented on every change, to skip unchanged checkpoints
+	DebateID         string
+	Topic            string
+	Panelists        []Panelist
+	Messages         []DebateMessage
+	PanelistMap      map[string]Panelist
+	CurrentSequence  int
+	StartedAt        time.Time
+	Format           string
+	Style            firebase.Style
+	Language         string
+	Citations        bool
+	Passages         []firebase.Passage
+	phase            string // ID of the current phase of the format
+	judgement        *firebase.Judgement
+	formatIssues     []string
+	ForkedFrom       string
+	ForkedAtSequence int
+	TranslatedFrom   string
+	restored         int // messages loaded from a stored debate, never extended
 }
 
 // NewDebateAccumulator creates a new accumulator
@@ -47,6 +67,13 @@ func NewDebateAccumulator(debateID, topic string, panelists []Panelist) *DebateA
 		Bio:       "Guiding the conversation",
 		AvatarURL: "/avatars/moderator-avatar.png",
 	}
+	// Add the audience, whose questions are relayed by the moderator
+	panelistMap["audience"] = Panelist{
+		ID:        "audience",
+		Name:      "Audience",
+		Tagline:   "Question from the floor",
+		AvatarURL: "/avatars/placeholder-avatar.png",
+	}
 
 	return &DebateAccumulator{
 		DebateID:        debateID,
@@ -59,35 +86,100 @@ func NewDebateAccumulator(debateID, topic string, panelists []Panelist) *DebateA
 	}
 }
 
-// AddMessage accumulates a message chunk
-func (acc *DebateAccumulator) AddMessage(panelistID, text string) {
+// NewDebateAccumulatorFromDocument creates an accumulator that extends a
+// stored debate, numbering new messages after the existing ones. The
+// judgement, review and format issues of the stored debate are dropped, as
+// they no longer match.
+func NewDebateAccumulatorFromDocument(doc *firebase.DebateDocument) *DebateAccumulator {
+	acc := NewDebateAccumulator(doc.ID, doc.Topic.Text, panelistsFromDocument(doc))
+	acc.StartedAt = doc.StartedAt
+	acc.Format = doc.Format
+	acc.Style = doc.Style
+	acc.Language = doc.Language
+	acc.Citations = doc.Citations
+	acc.Passages = doc.Passages
+	acc.ForkedFrom = doc.ForkedFrom
+	acc.ForkedAtSequence = doc.ForkedAtSequence
+	acc.TranslatedFrom = doc.TranslatedFrom
+
+	for _, msg := range doc.Messages {
+		acc.Messages = append(acc.Messages, DebateMessage{
+			ID:           msg.ID,
+			PanelistID:   msg.PanelistID,
+			PanelistName: msg.PanelistName,
+			AvatarURL:    msg.AvatarURL,
+			Text:         msg.Text,
+			Addresses:    msg.Addresses,
+			Citations:    msg.Citations,
+			SpeakerType:  msg.SpeakerType,
+			Phase:        msg.Phase,
+			Sequence:     msg.Sequence,
+			Timestamp:    msg.Timestamp,
+		})
+		acc.phase = msg.Phase
+		if msg.Sequence >= acc.CurrentSequence {
+			acc.CurrentSequence = msg.Sequence + 1
+		}
+	}
+	acc.restored = len(acc.Messages)
+
+	return acc
+}
+
+// panelistsFromDocument converts the panelists of a stored debate
+func panelistsFromDocument(doc *firebase.DebateDocument) []Panelist {
+	panelists := make([]Panelist, len(doc.Panelists))
+	for i, p := range doc.Panelists {
+		panelists[i] = Panelist{
+			ID:          p.ID,
+			Name:        p.Name,
+			Tagline:     p.Tagline,
+			Bio:         p.Biography,
+			AvatarURL:   p.AvatarURL,
+			Position:    p.Position,
+			Human:       p.Human,
+			Born:        p.Born,
+			Died:        p.Died,
+			Attribution: p.Attribution,
+		}
+	}
+	return panelists
+}
+
+// AddMessage accumulates a message chunk and the IDs it addresses, if any
+func (acc *DebateAccumulator) AddMessage(panelistID, text string, addresses []string) {
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+
+	// Speakers outside the debate are never stored
+	panelist, ok := acc.PanelistMap[panelistID]
+	if !ok {
+		log.Printf("Dropping message of unknown panelist ID: %s", panelistID)
+		return
+	}
+	acc.version++
+
 	// Find if we're continuing the last message or starting a new one
-	if len(acc.Messages) > 0 {
+	if len(acc.Messages) > acc.restored {
 		lastMsg := &acc.Messages[len(acc.Messages)-1]
 		if lastMsg.PanelistID == panelistID {
 			// Continue existing message
 			lastMsg.Text += text
+			lastMsg.Addresses = append(lastMsg.Addresses, addresses...)
 			return
 		}
 	}
 
 	// New message
-	panelist, ok := acc.PanelistMap[panelistID]
-	if !ok {
-		log.Printf("Unknown panelist ID: %s", panelistID)
-		panelist = Panelist{
-			ID:        panelistID,
-			Name:      panelistID,
-			AvatarURL: "/avatars/placeholder-avatar.png",
-		}
-	}
-
 	msg := DebateMessage{
-		ID:           panelistID + "-" + string(rune(acc.CurrentSequence)),
+		ID:           fmt.Sprintf("%s-%d", panelistID, acc.CurrentSequence),
 		PanelistID:   panelistID,
 		PanelistName: panelist.Name,
 		AvatarURL:    panelist.AvatarURL,
 		Text:         text,
+		Addresses:    addresses,
+		SpeakerType:  acc.speakerType(panelistID),
+		Phase:        acc.phase,
 		Sequence:     acc.CurrentSequence,
 		Timestamp:    time.Now(),
 	}
@@ -96,51 +188,92 @@ func (acc *DebateAccumulator) AddMessage(panelistID, text string) {
 	acc.CurrentSequence++
 }
 
-// AccumulatingWriter wraps an http.ResponseWriter to accumulate messages
-type AccumulatingWriter struct {
-	writer      http.ResponseWriter
-	accumulator *DebateAccumulator
-}
+// AddCitations attaches citations to the message being accumulated for panelistID
+func (acc *DebateAccumulator) AddCitations(panelistID string, citations []firebase.Citation) {
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
 
-// Write intercepts writes to accumulate message chunks
-func (aw *AccumulatingWriter) Write(p []byte) (n int, err error) {
-	// Try to parse as StreamChunk
-	var chunk StreamChunk
-	if err := json.Unmarshal(p, &chunk); err == nil {
-		if chunk.Type == "message" && chunk.PanelistID != "" && chunk.Text != "" {
-			// Accumulate this message
-			aw.accumulator.AddMessage(chunk.PanelistID, chunk.Text)
-		}
+	if len(acc.Messages) <= acc.restored || acc.Messages[len(acc.Messages)-1].PanelistID != panelistID {
+		log.Printf("Ignoring citations of %s outside of their message", panelistID)
+		return
 	}
+	acc.version++
 
-	// Pass through to original writer
-	return aw.writer.Write(p)
+	lastMsg := &acc.Messages[len(acc.Messages)-1]
+	lastMsg.Citations = append(lastMsg.Citations, citations...)
 }
 
-// Header passes through to original writer
-func (aw *AccumulatingWriter) Header() http.Header {
-	return aw.writer.Header()
+// SetJudgement records the verdict of the judging pass over the debate
+func (acc *DebateAccumulator) SetJudgement(judgement *firebase.Judgement) {
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+	acc.version++
+
+	acc.judgement = judgement
+}
+
+// SetFormatIssues records how the generated debate departs from the expected format
+func (acc *DebateAccumulator) SetFormatIssues(issues []string) {
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+	acc.version++
+
+	acc.formatIssues = issues
 }
 
-// WriteHeader passes through to original writer
-func (aw *AccumulatingWriter) WriteHeader(statusCode int) {
-	aw.writer.WriteHeader(statusCode)
+// StartPhase records the start of a phase: following messages belong to it
+func (acc *DebateAccumulator) StartPhase(phaseID string) {
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+
+	acc.phase = phaseID
 }
 
-// Flush passes through flush if available
-func (aw *AccumulatingWriter) Flush() {
-	if flusher, ok := aw.writer.(http.Flusher); ok {
-		flusher.Flush()
+// speakerType returns the type of the speaker with the given ID
+func (acc *DebateAccumulator) speakerType(panelistID string) string {
+	switch {
+	case panelistID == "moderator":
+		return firebase.SpeakerModerator
+	case panelistID == "audience":
+		return firebase.SpeakerAudience
+	case acc.PanelistMap[panelistID].Human:
+		return firebase.SpeakerHuman
+	default:
+		return firebase.SpeakerPanelist
 	}
 }
 
-// saveDebateToFirestore saves the completed debate asynchronously
-func saveDebateToFirestore(ctx context.Context, acc *DebateAccumulator, userAgent string) {
-	if firebase.GetClient() == nil {
-		log.Println("Firestore not initialized, skipping debate save")
-		return
+// AccumulatingEmitter accumulates message chunks before passing them on
+type AccumulatingEmitter struct {
+	next        ChunkEmitter
+	accumulator *DebateAccumulator
+}
+
+// Emit accumulates message chunks and forwards every chunk
+func (ae *AccumulatingEmitter) Emit(chunk StreamChunk) {
+	switch {
+	case chunk.Type == "message" && chunk.PanelistID != "":
+		if chunk.Text != "" {
+			ae.accumulator.AddMessage(chunk.PanelistID, chunk.Text, chunk.Addresses)
+		}
+		if len(chunk.Citations) > 0 {
+			ae.accumulator.AddCitations(chunk.PanelistID, chunk.Citations)
+		}
+	case chunk.Type == "phase":
+		ae.accumulator.StartPhase(chunk.Phase)
+	case chunk.Type == "issues":
+		ae.accumulator.SetFormatIssues(chunk.Issues)
 	}
 
+	ae.next.Emit(chunk)
+}
+
+// Document converts the accumulated debate into a stored document with status.
+// Only the last new message of an in-progress debate is marked incomplete.
+func (acc *DebateAccumulator) Document(status, userAgent string) *firebase.DebateDocument {
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+
 	// Clean up message text (trim whitespace)
 	messages := make([]firebase.Message, len(acc.Messages))
 	for i, msg := range acc.Messages {
@@ -150,9 +283,13 @@ func saveDebateToFirestore(ctx context.Context, acc *DebateAccumulator, userAgen
 			PanelistName: msg.PanelistName,
 			AvatarURL:    msg.AvatarURL,
 			Text:         strings.TrimSpace(msg.Text),
+			Addresses:    append([]string(nil), msg.Addresses...),
+			Citations:    append([]firebase.Citation(nil), msg.Citations...),
+			SpeakerType:  msg.SpeakerType,
+			Phase:        msg.Phase,
 			Timestamp:    msg.Timestamp,
 			Sequence:     msg.Sequence,
-			IsComplete:   true,
+			IsComplete:   status != firebase.StatusInProgress || i < len(acc.Messages)-1 || i < acc.restored,
 		}
 	}
 
@@ -160,27 +297,40 @@ func saveDebateToFirestore(ctx context.Context, acc *DebateAccumulator, userAgen
 	panelists := make([]firebase.Panelist, len(acc.Panelists))
 	for i, p := range acc.Panelists {
 		panelists[i] = firebase.Panelist{
-			ID:        p.ID,
-			Name:      p.Name,
-			Tagline:   p.Tagline,
-			Biography: p.Bio,
-			AvatarURL: p.AvatarURL,
-			Position:  p.Position,
+			ID:          p.ID,
+			Name:        p.Name,
+			Tagline:     p.Tagline,
+			Biography:   p.Bio,
+			AvatarURL:   p.AvatarURL,
+			Position:    p.Position,
+			Human:       p.Human,
+			Born:        p.Born,
+			Died:        p.Died,
+			Attribution: p.Attribution,
 		}
 	}
 
 	// Create debate document
-	debate := firebase.DebateDocument{
+	debate := &firebase.DebateDocument{
 		ID: acc.DebateID,
 		Topic: firebase.Topic{
 			Text:       acc.Topic,
 			IsRelevant: true,
 		},
-		Panelists:   panelists,
-		Messages:    messages,
-		Status:      "complete",
-		StartedAt:   acc.StartedAt,
-		CompletedAt: time.Now(),
+		Panelists:        panelists,
+		Messages:         messages,
+		Status:           status,
+		Format:           acc.Format,
+		Style:            acc.Style,
+		Language:         acc.Language,
+		Citations:        acc.Citations,
+		Passages:         acc.Passages,
+		StartedAt:        acc.StartedAt,
+		ForkedFrom:       acc.ForkedFrom,
+		ForkedAtSequence: acc.ForkedAtSequence,
+		TranslatedFrom:   acc.TranslatedFrom,
+		Judgement:        acc.judgement,
+		FormatIssues:     acc.formatIssues,
 		Metadata: firebase.Metadata{
 			CreatedBy:   "anonymous",
 			UserAgent:   userAgent,
@@ -188,11 +338,120 @@ func saveDebateToFirestore(ctx context.Context, acc *DebateAccumulator, userAgen
 			GeneratedBy: "backend",
 		},
 	}
+	if status != firebase.StatusInProgress && status != firebase.StatusAwaitingHuman {
+		debate.CompletedAt = time.Now()
+	}
+
+	return debate
+}
+
+// changes returns the accumulator version
+func (acc *DebateAccumulator) changes() int {
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+	return acc.version
+}
+
+// debateRecorder persists a debate while it is generated: in progress at
+// start, checkpointed periodically, then finalized
+type debateRecorder struct {
+	store       store.DebateStore
+	accumulator *DebateAccumulator
+	userAgent   string
+
+	mu    sync.Mutex // serializes saves
+	saved int        // accumulator version of the last save
+}
+
+// newDebateRecorder creates a recorder; a nil store disables persistence
+func newDebateRecorder(debateStore store.DebateStore, acc *DebateAccumulator, userAgent string) *debateRecorder {
+	if debateStore == nil {
+		log.Println("Debate store not initialized, debate will not be saved")
+	}
+	return &debateRecorder{
+		store:       debateStore,
+		accumulator: acc,
+		userAgent:   userAgent,
+		saved:       -1,
+	}
+}
+
+// Save stores the debate with status. genErr is recorded for failed debates.
+func (r *debateRecorder) Save(ctx context.Context, status string, genErr error) {
+	if r.store == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	version := r.accumulator.changes()
+	debate := r.accumulator.Document(status, r.userAgent)
+	if genErr != nil {
+		debate.Error = genErr.Error()
+	}
+
+	if err := r.store.SaveDebate(ctx, r.accumulator.DebateID, debate); err != nil {
+		log.Printf("Failed to save %s debate %s (debate still proceeds): %v", status, r.accumulator.DebateID, err)
+		return
+	}
+	r.saved = version
+
+	if status != firebase.StatusInProgress {
+		log.Printf("Successfully saved %s debate %s", status, r.accumulator.DebateID)
+	}
+}
+
+// Checkpoint saves the in-progress debate if messages changed since the last save
+func (r *debateRecorder) Checkpoint(ctx context.Context) {
+	r.mu.Lock()
+	unchanged := r.saved == r.accumulator.changes()
+	r.mu.Unlock()
+
+	if !unchanged {
+		r.Save(ctx, firebase.StatusInProgress, nil)
+	}
+}
+
+// StartCheckpoints checkpoints the debate every interval until the returned
+// function is called; it returns once the last checkpoint has finished
+func (r *debateRecorder) StartCheckpoints(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				r.Checkpoint(context.Background())
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
 
-	// Save to Firestore
-	if err := firebase.SaveDebate(ctx, acc.DebateID, &debate); err != nil {
-		log.Printf("Failed to save debate to Firestore (debate still succeeded): %v", err)
-	} else {
-		log.Printf("Successfully saved debate %s to Firestore", acc.DebateID)
+// finalStatus returns the status of a finished generation: awaiting the human
+// panelist when it paused for their turn, aborted when it was cancelled or
+// timed out, failed on any other error
+func finalStatus(ctx context.Context, err error) string {
+	switch {
+	case err == nil:
+		return firebase.StatusComplete
+	case errors.Is(err, errHumanTurn):
+		return firebase.StatusAwaitingHuman
+	case ctx.Err() != nil, errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return firebase.StatusAborted
+	default:
+		return firebase.StatusFailed
 	}
 }
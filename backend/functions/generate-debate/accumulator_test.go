@@ -0,0 +1,165 @@
+package generatedebate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/store"
+)
+
+// countingStore counts the saves made to a memory store
+type countingStore struct {
+	*store.MemoryStore
+	saves int
+}
+
+func (s *countingStore) SaveDebate(ctx context.Context, id string, debate *firebase.DebateDocument) error {
+	s.saves++
+	return s.MemoryStore.SaveDebate(ctx, id, debate)
+}
+
+func TestDebateRecorder(t *testing.T) {
+	ctx := context.Background()
+	s := &countingStore{MemoryStore: store.NewMemoryStore()}
+	acc := NewDebateAccumulator("d1", "Is war ever just?", []Panelist{{ID: "augustine", Name: "Augustine of Hippo"}})
+	recorder := newDebateRecorder(s, acc, "test-agent")
+
+	recorder.Save(ctx, firebase.StatusInProgress, nil)
+	debate, err := s.GetDebate(ctx, "d1")
+	if err != nil {
+		t.Fatalf("debate not saved at start: %v", err)
+	}
+	if debate.Status != firebase.StatusInProgress || !debate.CompletedAt.IsZero() {
+		t.Errorf("initial save = %q completed at %v, want in_progress and no completion time", debate.Status, debate.CompletedAt)
+	}
+
+	// Checkpoints only save when messages changed
+	recorder.Checkpoint(ctx)
+	if s.saves != 1 {
+		t.Errorf("unchanged checkpoint saved the debate (%d saves)", s.saves)
+	}
+
+	acc.AddMessage("moderator", "Welcome. ", nil)
+	acc.AddMessage("augustine", "Peace", nil)
+	recorder.Checkpoint(ctx)
+	if s.saves != 2 {
+		t.Fatalf("checkpoint after new messages made %d saves, want 2", s.saves)
+	}
+
+	debate, _ = s.GetDebate(ctx, "d1")
+	if len(debate.Messages) != 2 || !debate.Messages[0].IsComplete || debate.Messages[1].IsComplete {
+		t.Errorf("checkpointed messages = %+v, want only the last one incomplete", debate.Messages)
+	}
+
+	recorder.Save(ctx, firebase.StatusComplete, nil)
+	debate, _ = s.GetDebate(ctx, "d1")
+	if debate.Status != firebase.StatusComplete || debate.CompletedAt.IsZero() || !debate.Messages[1].IsComplete {
+		t.Errorf("final save = %+v, want complete with completion time", debate)
+	}
+}
+
+func TestDebateRecorderStartCheckpoints(t *testing.T) {
+	s := &countingStore{MemoryStore: store.NewMemoryStore()}
+	acc := NewDebateAccumulator("d1", "Is war ever just?", nil)
+	recorder := newDebateRecorder(s, acc, "")
+
+	stop := recorder.StartCheckpoints(time.Millisecond)
+	acc.AddMessage("moderator", "Welcome.", nil)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if debate, err := s.GetDebate(context.Background(), "d1"); err == nil && len(debate.Messages) == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	stop()
+
+	debate, err := s.GetDebate(context.Background(), "d1")
+	if err != nil || len(debate.Messages) != 1 {
+		t.Fatalf("periodic checkpoint did not save the new message")
+	}
+}
+
+func TestFinalStatus(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want string
+	}{
+		{name: "success", ctx: context.Background(), want: firebase.StatusComplete},
+		{name: "model error", ctx: context.Background(), err: errors.New("overloaded"), want: firebase.StatusFailed},
+		{name: "timeout", ctx: context.Background(), err: fmt.Errorf("stream error: %w", context.DeadlineExceeded), want: firebase.StatusAborted},
+		{name: "cancelled context", ctx: cancelled, err: errors.New("connection reset"), want: firebase.StatusAborted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := finalStatus(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("finalStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccumulatorDropsUnknownSpeakers(t *testing.T) {
+	acc := NewDebateAccumulator("d1", "Is war ever just?", []Panelist{{ID: "augustine", Name: "Augustine of Hippo"}})
+	acc.AddMessage("moderator", "Welcome.", nil)
+	acc.AddMessage("aquinas", "Order.", nil)
+	acc.AddMessage("augustine", "Peace.", nil)
+
+	doc := acc.Document(firebase.StatusComplete, "test-agent")
+	if len(doc.Messages) != 2 || doc.Messages[1].PanelistID != "augustine" || doc.Messages[1].Sequence != 1 {
+		t.Errorf("messages = %+v, want the moderator's and Augustine's only", doc.Messages)
+	}
+	if len(doc.Panelists) != 1 {
+		t.Errorf("panelists = %+v, want Augustine only", doc.Panelists)
+	}
+}
+
+func TestPanelistAttributionRoundTrip(t *testing.T) {
+	attribution := &firebase.Attribution{
+		License:   "CC BY-SA 4.0",
+		Artist:    "Jean",
+		SourceURL: "https://commons.wikimedia.org/wiki/File:Canterbury.jpg",
+	}
+	acc := NewDebateAccumulator("d1", "Is war ever just?", []Panelist{{ID: "augustine", Name: "Augustine of Canterbury", Attribution: attribution}})
+
+	doc := acc.Document(firebase.StatusComplete, "test-agent")
+	if got := doc.Panelists[0].Attribution; got == nil || *got != *attribution {
+		t.Fatalf("stored attribution = %+v, want %+v", got, attribution)
+	}
+	if got := panelistsFromDocument(doc)[0].Attribution; got == nil || *got != *attribution {
+		t.Errorf("restored attribution = %+v, want %+v", got, attribution)
+	}
+}
+
+func TestValidateAttribution(t *testing.T) {
+	panelists := func(a *firebase.Attribution) []Panelist {
+		return []Panelist{{ID: "a", Name: "A", Attribution: a}, {ID: "b", Name: "B"}}
+	}
+
+	req := DebateRequest{Topic: "Is war ever just?", SelectedPanelists: panelists(&firebase.Attribution{
+		License: "<b>CC0</b>",
+		Artist:  "<a href=\"https://example.com\">Jean</a>",
+	})}
+	if err := ValidateDebateRequest(&req); err != nil {
+		t.Fatalf("ValidateDebateRequest() error = %v", err)
+	}
+	if a := req.SelectedPanelists[0].Attribution; a.License != "CC0" || a.Artist != "Jean" {
+		t.Errorf("attribution = %+v, want markup stripped", a)
+	}
+
+	req = DebateRequest{Topic: "Is war ever just?", SelectedPanelists: panelists(&firebase.Attribution{SourceURL: "javascript:alert(1)"})}
+	if err := ValidateDebateRequest(&req); err == nil {
+		t.Error("ValidateDebateRequest() accepted a script link")
+	}
+}
@@ -0,0 +1,264 @@
+package generatedebate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/llm"
+)
+
+// checkMaxTokens bounds the findings of a fidelity check
+const checkMaxTokens = 4096
+
+// yearPattern matches years of the second millennium and this century where
+// the context makes them years, e.g. "in 1648", "AD 1200" or "the 1520s", and
+// not counts such as "1500 souls". The lowercase word following a year after
+// a preposition is captured, to tell "in 1648" from "in 1000 years".
+var yearPattern = regexp.MustCompile(`(?i)\b(?:in|since|until|till|before|after|year|AD|A\.D\.)\s+(1\d{3}|20\d{2})\b(?-i:\s+([a-z]+))?|\b(1\d{3}|20\d{2})(?:'?s\b|\s+(?:AD|CE)\b)`)
+
+// pluralExceptions are words ending in s that do not make the number before
+// them a count
+var pluralExceptions = map[string]bool{"as": true, "has": true, "is": true, "its": true, "his": true, "was": true, "this": true, "thus": true, "us": true}
+
+// irregularPlurals are plural nouns not ending in s
+var irregularPlurals = map[string]bool{"men": true, "women": true, "people": true, "children": true, "feet": true, "folk": true}
+
+// isPlural tells whether word is likely a plural noun, making the number
+// before it a count rather than a year
+func isPlural(word string) bool {
+	if irregularPlurals[word] {
+		return true
+	}
+	return strings.HasSuffix(word, "s") && !pluralExceptions[word]
+}
+
+// handleCheckDebateImpl checks a saved debate for anachronisms and
+// out-of-character claims, stores the findings on the debate for review and
+// returns them
+func handleCheckDebateImpl(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != "POST" {
+		sendError(w, "Method not allowed", ErrInvalidRequest, false, http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse request body
+	var req CheckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, "Invalid request body", ErrInvalidRequest, false, http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	if err := ValidateCheckRequest(&req); err != nil {
+		sendError(w, err.Error(), ErrInvalidRequest, false, http.StatusBadRequest)
+		return
+	}
+
+	debateStore, doc, ok := loadDebate(r.Context(), w, req.DebateID)
+	if !ok {
+		return
+	}
+
+	if doc.Status == firebase.StatusInProgress {
+		sendError(w, "Debate is still being generated", ErrDebateInProgress, true, http.StatusConflict)
+		return
+	}
+
+	// Create Claude client
+	claudeClient, err := newClaudeClient()
+	if err != nil {
+		log.Printf("Failed to create Claude client: %v", err)
+		sendError(w, "Service configuration error", ErrInternalError, true, http.StatusInternalServerError)
+		return
+	}
+
+	review, err := claudeClient.CheckDebate(r.Context(), doc)
+	if err != nil {
+		log.Printf("Failed to check debate %s: %v", doc.ID, err)
+		sendError(w, "Fidelity check is temporarily unavailable. Please try again.", ErrServiceUnavailable, true, http.StatusServiceUnavailable)
+		return
+	}
+
+	// Only the review is stored: the debate may have changed during the check
+	err = debateStore.UpdateDebate(r.Context(), doc.ID, func(debate *firebase.DebateDocument) error {
+		debate.Review = review
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to save review of debate %s: %v", doc.ID, err)
+		sendError(w, "Failed to save review", ErrInternalError, true, http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Checked debate %s: %d findings", doc.ID, len(review.Findings))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
+
+// CheckDebate reviews the panelists' messages of doc against their era and
+// known positions. Dates after a panelist's death are always flagged; the
+// model flags the other anachronisms and out-of-character claims.
+func (c *ClaudeClient) CheckDebate(ctx context.Context, doc *firebase.DebateDocument) (*firebase.Review, error) {
+	panelists := panelistsFromDocument(doc)
+	findings := make([]firebase.Finding, 0)
+	for _, msg := range doc.Messages {
+		if finding, ok := lateYear(panelists, msg); ok {
+			findings = append(findings, finding)
+		}
+	}
+
+	stream := c.model.Stream(ctx, llm.UserPrompt(c.buildCheckPrompt(doc, panelists), checkMaxTokens))
+	defer stream.Close()
+
+	var answer strings.Builder
+	for stream.Next() {
+		answer.WriteString(stream.Current())
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("stream error: %w", err)
+	}
+
+	object, err := jsonObject(answer.String())
+	if err != nil {
+		return nil, err
+	}
+	var review firebase.Review
+	if err := json.Unmarshal([]byte(object), &review); err != nil {
+		return nil, fmt.Errorf("invalid review: %w", err)
+	}
+
+	// Keep findings on panelists' messages, one per message and kind
+	for _, finding := range review.Findings {
+		msg, ok := findMessage(doc.Messages, finding.MessageID)
+		if !ok || !isJudged(panelists, msg.PanelistID) || hasFinding(findings, finding) {
+			continue
+		}
+		if finding.Kind != firebase.FindingAnachronism && finding.Kind != firebase.FindingOutOfCharacter {
+			continue
+		}
+		finding.PanelistID = msg.PanelistID
+		findings = append(findings, finding)
+	}
+
+	return &firebase.Review{Findings: findings, CheckedAt: time.Now()}, nil
+}
+
+// buildCheckPrompt creates the prompt asking for the findings on doc
+func (c *ClaudeClient) buildCheckPrompt(doc *firebase.DebateDocument, panelists []Panelist) string {
+	var prompt strings.Builder
+
+	prompt.WriteString("You are a historian reviewing a generated debate between historical figures for historical fidelity.\n\n")
+	prompt.WriteString(fmt.Sprintf("Topic: %s\n\n", doc.Topic.Text))
+	prompt.WriteString("Panelists:\n")
+	n := 0
+	for _, p := range panelists {
+		if p.Human {
+			continue
+		}
+		n++
+		prompt.WriteString(fmt.Sprintf("%d. %s (ID: %s)\n", n, p.Name, p.ID))
+		if era := lifespan(p); era != "" {
+			prompt.WriteString(fmt.Sprintf("   Lived: %s\n", era))
+		}
+		if p.Position != "" {
+			prompt.WriteString(fmt.Sprintf("   Position: %s\n", p.Position))
+		}
+	}
+
+	prompt.WriteString("\nTranscript, one message per line as [MESSAGE_ID] SPEAKER_ID: text\n")
+	for _, msg := range doc.Messages {
+		prompt.WriteString(fmt.Sprintf("[%s] %s: %s\n", msg.ID, msg.PanelistID, msg.Text))
+	}
+
+	prompt.WriteString("\nFlag the panelists' messages, but not the moderator's or the audience's, that contain:\n")
+	prompt.WriteString(fmt.Sprintf("- %s: a reference to events, people, works, ideas or vocabulary the panelist could not have known in their lifetime. Replying to another panelist of a later era is not an anachronism.\n", firebase.FindingAnachronism))
+	prompt.WriteString(fmt.Sprintf("- %s: a claim contradicting the panelist's documented positions or tradition\n", firebase.FindingOutOfCharacter))
+	prompt.WriteString("Only flag clear problems. For each, give the message ID, the kind, the exact flagged words as excerpt and a one-sentence explanation.\n")
+	prompt.WriteString("\nReturn ONLY a JSON object, with no markdown or other text, and an empty array if nothing is wrong:\n")
+	prompt.WriteString(`{"findings":[{"messageId":"ID","kind":"anachronism","excerpt":"...","explanation":"..."}]}`)
+
+	return prompt.String()
+}
+
+// lateYear flags msg if its panelist mentions a year after their death
+func lateYear(panelists []Panelist, msg firebase.Message) (firebase.Finding, bool) {
+	for _, p := range panelists {
+		if p.ID != msg.PanelistID || p.Human || p.Died == 0 {
+			continue
+		}
+		for _, groups := range yearPattern.FindAllStringSubmatch(msg.Text, -1) {
+			if isPlural(groups[2]) {
+				continue
+			}
+			match := groups[1] + groups[3]
+			if year, _ := strconv.Atoi(match); year > p.Died {
+				return firebase.Finding{
+					MessageID:   msg.ID,
+					PanelistID:  p.ID,
+					Kind:        firebase.FindingAnachronism,
+					Excerpt:     match,
+					Explanation: fmt.Sprintf("%s died in %s and could not refer to %d.", p.Name, formatYear(p.Died), year),
+				}, true
+			}
+		}
+	}
+	return firebase.Finding{}, false
+}
+
+// lifespan describes the years the panelist lived, or "" if they are unknown
+func lifespan(p Panelist) string {
+	switch {
+	case p.Born != 0 && p.Died != 0:
+		return formatYear(p.Born) + "-" + formatYear(p.Died)
+	case p.Born != 0:
+		return "born " + formatYear(p.Born)
+	case p.Died != 0:
+		return "died " + formatYear(p.Died)
+	default:
+		return ""
+	}
+}
+
+// formatYear formats a year, negative before Christ
+func formatYear(year int) string {
+	if year < 0 {
+		return fmt.Sprintf("%d BC", -year)
+	}
+	return strconv.Itoa(year)
+}
+
+// findMessage returns the message with the given ID
+func findMessage(messages []firebase.Message, id string) (firebase.Message, bool) {
+	for _, msg := range messages {
+		if msg.ID == id {
+			return msg, true
+		}
+	}
+	return firebase.Message{}, false
+}
+
+// hasFinding returns whether findings already flag the message of finding
+// for the same kind
+func hasFinding(findings []firebase.Finding, finding firebase.Finding) bool {
+	for _, f := range findings {
+		if f.MessageID == finding.MessageID && f.Kind == finding.Kind {
+			return true
+		}
+	}
+	return false
+}
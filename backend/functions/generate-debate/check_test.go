@@ -0,0 +1,206 @@
+package generatedebate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/llm"
+	"github.com/raphink/debate/shared/store"
+)
+
+// checkedDebate is a debate in which Augustine knows too much
+func checkedDebate(id string) *firebase.DebateDocument {
+	return &firebase.DebateDocument{
+		ID:    id,
+		Topic: firebase.Topic{Text: "Should Christians defy unjust laws?", IsRelevant: true},
+		Panelists: []firebase.Panelist{
+			{ID: "augustine", Name: "Augustine of Hippo", Born: 354, Died: 430},
+			{ID: "mlk", Name: "Martin Luther King Jr.", Born: 1929, Died: 1968},
+		},
+		Messages: []firebase.Message{
+			{ID: "moderator-0", PanelistID: "moderator", Text: "Welcome to our debate, held in 2024."},
+			{ID: "augustine-1", PanelistID: "augustine", Text: "As the march on Washington showed in 1963, an unjust law is no law at all."},
+			{ID: "mlk-2", PanelistID: "mlk", Text: "Violence is the only answer to injustice."},
+		},
+		Status: firebase.StatusComplete,
+	}
+}
+
+func TestHandleCheckDebate(t *testing.T) {
+	fake := llm.NewFake("```json\n" + `{"findings":[` +
+		`{"messageId":"augustine-1","kind":"anachronism","excerpt":"the march on Washington","explanation":"Augustine died in 430."},` +
+		`{"messageId":"mlk-2","kind":"out_of_character","excerpt":"Violence is the only answer","explanation":"King preached nonviolence."},` +
+		`{"messageId":"moderator-0","kind":"anachronism","excerpt":"2024","explanation":"Not a panelist."},` +
+		`{"messageId":"mlk-2","kind":"heresy","excerpt":"Violence","explanation":"Unknown kind."},` +
+		`{"messageId":"missing-9","kind":"anachronism","excerpt":"?","explanation":"No such message."}` +
+		`]}` + "\n```")
+	memStore := useFakes(t, fake)
+	if err := memStore.SaveDebate(context.Background(), "d1", checkedDebate("d1")); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/check-debate", strings.NewReader(`{"debateId": "d1"}`))
+	rec := httptest.NewRecorder()
+	HandleCheckDebate(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+
+	prompt := fake.Requests()[0].Messages[0].Text
+	for _, want := range []string{"Lived: 354-430", "[augustine-1] augustine: As the march"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("check prompt does not contain %q:\n%s", want, prompt)
+		}
+	}
+
+	var review firebase.Review
+	if err := json.Unmarshal(rec.Body.Bytes(), &review); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	want := []firebase.Finding{
+		{MessageID: "augustine-1", PanelistID: "augustine", Kind: firebase.FindingAnachronism, Excerpt: "1963", Explanation: "Augustine of Hippo died in 430 and could not refer to 1963."},
+		{MessageID: "mlk-2", PanelistID: "mlk", Kind: firebase.FindingOutOfCharacter, Excerpt: "Violence is the only answer", Explanation: "King preached nonviolence."},
+	}
+	if len(review.Findings) != len(want) {
+		t.Fatalf("findings = %+v, want %+v", review.Findings, want)
+	}
+	for i := range want {
+		if review.Findings[i] != want[i] {
+			t.Errorf("finding %d = %+v, want %+v", i, review.Findings[i], want[i])
+		}
+	}
+
+	// The review is stored on the debate
+	saved, err := memStore.GetDebate(context.Background(), "d1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Review == nil || len(saved.Review.Findings) != 2 || saved.Review.CheckedAt.IsZero() {
+		t.Errorf("saved review = %+v", saved.Review)
+	}
+}
+
+// modelFunc adapts a function to llm.LanguageModel
+type modelFunc func(ctx context.Context, req llm.Request) llm.Stream
+
+func (f modelFunc) Stream(ctx context.Context, req llm.Request) llm.Stream {
+	return f(ctx, req)
+}
+
+func TestHandleCheckDebateKeepsNewMessages(t *testing.T) {
+	var memStore *store.MemoryStore
+	fake := llm.NewFake(`{"findings":[]}`)
+	memStore = useFakes(t, modelFunc(func(ctx context.Context, req llm.Request) llm.Stream {
+		// A message is added while the model reviews the debate
+		debate, _ := memStore.GetDebate(ctx, "d1")
+		debate.Messages = append(debate.Messages, firebase.Message{ID: "moderator-3", PanelistID: "moderator", Text: "Thank you."})
+		memStore.SaveDebate(ctx, "d1", debate)
+		return fake.Stream(ctx, req)
+	}))
+	memStore.SaveDebate(context.Background(), "d1", checkedDebate("d1"))
+
+	rec := httptest.NewRecorder()
+	HandleCheckDebate(rec, httptest.NewRequest(http.MethodPost, "/check-debate", strings.NewReader(`{"debateId": "d1"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+
+	saved, _ := memStore.GetDebate(context.Background(), "d1")
+	if len(saved.Messages) != 4 || saved.Review == nil {
+		t.Errorf("saved %d messages and review %+v, want the new message kept with the review", len(saved.Messages), saved.Review)
+	}
+}
+
+func TestHandleCheckDebateErrors(t *testing.T) {
+	memStore := useFakes(t, llm.NewFakeScripts(llm.Script{Err: errors.New("overloaded")}))
+	saveTestDebate(t, memStore, "running", firebase.StatusInProgress)
+	saveTestDebate(t, memStore, "d1", firebase.StatusComplete)
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		want   int
+	}{
+		{name: "wrong method", method: http.MethodGet, want: http.StatusMethodNotAllowed},
+		{name: "missing debate ID", method: http.MethodPost, body: `{}`, want: http.StatusBadRequest},
+		{name: "unknown debate", method: http.MethodPost, body: `{"debateId": "missing"}`, want: http.StatusNotFound},
+		{name: "debate in progress", method: http.MethodPost, body: `{"debateId": "running"}`, want: http.StatusConflict},
+		{name: "model unavailable", method: http.MethodPost, body: `{"debateId": "d1"}`, want: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/check-debate", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			HandleCheckDebate(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestLateYear(t *testing.T) {
+	panelists := []Panelist{
+		{ID: "plato", Name: "Plato", Born: -428, Died: -348},
+		{ID: "tutu", Name: "Desmond Tutu", Born: 1931, Died: 2021},
+		{ID: "living", Name: "Living Theologian", Born: 1950},
+	}
+
+	tests := []struct {
+		name string
+		msg  firebase.Message
+		want bool
+	}{
+		{name: "any year after a death before Christ", msg: firebase.Message{PanelistID: "plato", Text: "Since 1054 the Church is divided."}, want: true},
+		{name: "year in the lifetime", msg: firebase.Message{PanelistID: "tutu", Text: "In 1994 we voted for the first time."}, want: false},
+		{name: "year after death", msg: firebase.Message{PanelistID: "tutu", Text: "The synod held in 2023 agreed."}, want: true},
+		{name: "era", msg: firebase.Message{PanelistID: "plato", Text: "By AD 1200 the universities were founded."}, want: true},
+		{name: "decade", msg: firebase.Message{PanelistID: "plato", Text: "The reformers of the 1520s disagreed."}, want: true},
+		{name: "year with era after", msg: firebase.Message{PanelistID: "plato", Text: "Constantinople fell, 1453 AD."}, want: true},
+		{name: "living panelist", msg: firebase.Message{PanelistID: "living", Text: "In 2025 we wrote this."}, want: false},
+		{name: "not a year", msg: firebase.Message{PanelistID: "plato", Text: "Ten thousand and 12 soldiers."}, want: false},
+		{name: "count", msg: firebase.Message{PanelistID: "plato", Text: "A crowd of 1500 souls gathered."}, want: false},
+		{name: "count after a preposition", msg: firebase.Message{PanelistID: "plato", Text: "The book runs to 1200 pages, with 1100 notes."}, want: false},
+		{name: "year without context", msg: firebase.Message{PanelistID: "plato", Text: "Some 1054 monks agreed."}, want: false},
+		{name: "duration", msg: firebase.Message{PanelistID: "plato", Text: "The soul lives on in 1000 years."}, want: false},
+		{name: "count of pages", msg: firebase.Message{PanelistID: "plato", Text: "The argument comes after 1500 pages."}, want: false},
+		{name: "count of people", msg: firebase.Message{PanelistID: "plato", Text: "Wisdom is found in 1200 people."}, want: false},
+		{name: "year followed by a verb", msg: firebase.Message{PanelistID: "plato", Text: "Since 1054 was the schism, the Church is divided."}, want: true},
+		{name: "year followed by a name", msg: firebase.Message{PanelistID: "plato", Text: "In 1517 Luther posted his theses."}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := lateYear(panelists, tt.msg); got != tt.want {
+				t.Errorf("lateYear() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLifespan(t *testing.T) {
+	tests := []struct {
+		panelist Panelist
+		want     string
+	}{
+		{Panelist{Born: -428, Died: -348}, "428 BC-348 BC"},
+		{Panelist{Born: 1950}, "born 1950"},
+		{Panelist{Died: 430}, "died 430"},
+		{Panelist{}, ""},
+	}
+	for _, tt := range tests {
+		if got := lifespan(tt.panelist); got != tt.want {
+			t.Errorf("lifespan(%+v) = %q, want %q", tt.panelist, got, tt.want)
+		}
+	}
+}
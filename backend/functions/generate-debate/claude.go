@@ -2,59 +2,132 @@ package generatedebate
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
-	"io"
-	"net/http"
+	"log"
 	"os"
 	"strings"
 
 	"github.com/anthropics/anthropic-sdk-go"
-	"github.com/anthropics/anthropic-sdk-go/option"
-	"github.com/anthropics/anthropic-sdk-go/packages/ssestream"
+	"github.com/raphink/debate/shared/llm"
 )
 
-// ClaudeClient handles communication with the Anthropic Claude API
+// ClaudeClient generates debates with a language model (Claude by default)
 type ClaudeClient struct {
-	client anthropic.Client
+	model llm.LanguageModel
 }
 
-// NewClaudeClient creates a new Claude API client
+// NewClaudeClient creates a client for the language model configured in the environment
 func NewClaudeClient() (*ClaudeClient, error) {
-	apiKey := os.Getenv("ANTHROPIC_API_KEY")
-	if apiKey == "" {
-		return nil, errors.New("ANTHROPIC_API_KEY environment variable not set")
+	model, err := llm.NewFromEnv(string(anthropic.ModelClaudeSonnet4_5))
+	if err != nil {
+		return nil, err
 	}
 
-	return &ClaudeClient{
-		client: anthropic.NewClient(
-			option.WithAPIKey(apiKey),
-		),
-	}, nil
+	return NewClaudeClientWithModel(model), nil
+}
+
+// NewClaudeClientWithModel creates a client using the given language model
+func NewClaudeClientWithModel(model llm.LanguageModel) *ClaudeClient {
+	return &ClaudeClient{model: model}
+}
+
+// ChunkEmitter receives the chunks of a debate as they are generated
+type ChunkEmitter interface {
+	Emit(chunk StreamChunk)
 }
 
 // GenerateDebate streams a debate between the selected panelists
-func (c *ClaudeClient) GenerateDebate(ctx context.Context, req *DebateRequest, writer io.Writer) error {
+func (c *ClaudeClient) GenerateDebate(ctx context.Context, req *DebateRequest, emitter ChunkEmitter) error {
 	// Build the debate prompt
 	prompt := c.buildDebatePrompt(req)
 
-	// Create streaming request
-	stream := c.client.Messages.NewStreaming(ctx, anthropic.MessageNewParams{
-		Model:     anthropic.ModelClaudeSonnet4_5,
-		MaxTokens: 4096,
-		Messages: []anthropic.MessageParam{
-			anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
-		},
-	})
+	return c.streamDebate(ctx, llm.UserPrompt(prompt, tokenBudget(req, maxExchanges(req))), req, emitter)
+}
+
+// maxContinuations bounds the follow-up requests made when a debate is cut
+// off at the token limit before the moderator's conclusion
+const maxContinuations = 3
+
+// beginDebate ends the prompt of a new debate
+const beginDebate = "Begin the debate:"
+
+// streamDebate streams the model's response to request as debate chunks,
+// stopping at the turn of the human panelist of req, if any
+func (c *ClaudeClient) streamDebate(ctx context.Context, request llm.Request, req *DebateRequest, emitter ChunkEmitter) error {
+	var pauser *humanTurnEmitter
+	if humanID := humanPanelistID(req.SelectedPanelists); humanID != "" {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+
+		pauser = &humanTurnEmitter{next: emitter, humanID: humanID, cancel: cancel}
+		emitter = pauser
+	}
+
+	// Phase markers become phase chunks
+	emitter = &phaseEmitter{next: emitter, format: debateFormat(req)}
+
+	// Speakers are checked before anything else sees them. Only new debates,
+	// made of a single prompt, must open with the moderator.
+	checker := newTurnChecker(emitter, req, len(request.Messages) == 1)
+	emitter = checker
+
+	// Create streaming request, continued if it hits the token limit
+	stream := llm.StreamWithContinuations(ctx, c.model, request, maxContinuations)
+	defer stream.Close()
 
 	// Stream the response
-	return c.streamResponse(stream, writer)
+	var err error
+	if outputMode(req) == OutputModeStructured {
+		err = c.streamStructuredResponse(stream, emitter)
+	} else {
+		err = c.streamResponse(stream, emitter)
+	}
+
+	if pauser != nil && pauser.paused {
+		return errHumanTurn
+	}
+	if err != nil {
+		return err
+	}
+
+	// Ask for the moderator's last message if the model left it out
+	if !checker.concluded() {
+		log.Printf("Debate ended without the moderator, requesting the conclusion")
+		if err := c.concludeDebate(ctx, request, req, checker, emitter); err != nil {
+			log.Printf("Failed to conclude debate (non-blocking): %v", err)
+		}
+	}
+	checker.finish()
+	return nil
+}
+
+// outputMode returns the requested output mode, falling back to
+// DEBATE_OUTPUT_MODE and then to the [ID]: text protocol
+func outputMode(req *DebateRequest) string {
+	if req.OutputMode != "" {
+		return req.OutputMode
+	}
+	if mode := os.Getenv("DEBATE_OUTPUT_MODE"); mode == OutputModeStructured {
+		return mode
+	}
+	return OutputModeText
 }
 
+// openEnding describes the last moderator message of a debate that waits for
+// the audience instead of concluding
+const openEnding = "briefly inviting the audience to ask a question or steer the debate, without concluding it - 1-2 sentences"
+
 // buildDebatePrompt creates the prompt for Claude to generate a debate
 func (c *ClaudeClient) buildDebatePrompt(req *DebateRequest) string {
 	var prompt strings.Builder
+	structured := outputMode(req) == OutputModeStructured
+
+	// How the moderator's turns are introduced in the chosen output mode
+	moderatorTurn := "[moderator]:"
+	if structured {
+		moderatorTurn = `{"speaker":"moderator"}`
+	}
 
 	prompt.WriteString("You are a neutral moderator orchestrating a theological/philosophical debate between historical figures.\n\n")
 	prompt.WriteString(fmt.Sprintf("Topic: %s\n\n", req.Topic))
@@ -68,67 +141,73 @@ func (c *ClaudeClient) buildDebatePrompt(req *DebateRequest) string {
 		if panelist.Position != "" {
 			prompt.WriteString(fmt.Sprintf("   Position: %s\n", panelist.Position))
 		}
+		if panelist.Human {
+			prompt.WriteString("   Human: a real person whose messages you never write\n")
+		}
 	}
+	writePassages(&prompt, req)
 
-	prompt.WriteString("\nGenerate a moderated debate with the following structure:\n")
-	prompt.WriteString("1. FIRST MESSAGE MUST BE: [moderator]: (introducing the topic and panelists)\n")
-	prompt.WriteString("2. Include 12-18 exchanges between panelists\n")
-	prompt.WriteString("3. The moderator may occasionally intervene between panelist exchanges to:\n")
-	prompt.WriteString("   - Redirect the conversation\n")
-	prompt.WriteString("   - Ask clarifying questions\n")
-	prompt.WriteString("   - Highlight contrasting viewpoints\n")
-	prompt.WriteString("   - Summarize progress\n")
-	prompt.WriteString("4. LAST MESSAGE MUST BE: [moderator]: (providing a concluding summary that synthesizes the key points, acknowledges different perspectives, and gracefully ends the debate - 3-5 sentences)\n\n")
-	prompt.WriteString("CRITICAL FORMAT REQUIREMENTS:\n")
-	prompt.WriteString("- Each response MUST start on a new line with the exact format: [ID]: text\n")
-	prompt.WriteString("- Use [moderator]: for moderator messages\n")
-	prompt.WriteString("- Use [PANELIST_ID]: for panelist messages (IDs listed above)\n")
-	prompt.WriteString("- NO extra text before the [ID]: marker\n")
-	prompt.WriteString("- Start your response immediately with [moderator]:\n\n")
+	// Phases of the chosen format
+	format := debateFormat(req)
+	writeFormat(&prompt, format, structured)
+	prompt.WriteString(fmt.Sprintf("Include %s exchanges between panelists in total\n", exchangesOf(req)))
+
+	// Interactive debates stop early so the audience can interject
+	lastMessage := format.Closing
+	if req.Interactive {
+		prompt.WriteString(fmt.Sprintf("This debate is interactive: generate only the first %d-%d exchanges between panelists, wherever they fall in the phases\n", interactiveExchanges-2, interactiveExchanges))
+		lastMessage = openEnding
+	}
+	prompt.WriteString(fmt.Sprintf("FIRST MESSAGE MUST BE: %s (introducing the topic and panelists), right after the first phase marker\n", moderatorTurn))
+	prompt.WriteString(fmt.Sprintf("LAST MESSAGE MUST BE: %s (%s)\n\n", moderatorTurn, lastMessage))
+	if structured {
+		writeStructuredFormat(&prompt)
+		if req.Citations {
+			writeCitations(&prompt)
+		}
+	} else {
+		prompt.WriteString("CRITICAL FORMAT REQUIREMENTS:\n")
+		prompt.WriteString("- Each response MUST start on a new line with the exact format: [ID]: text\n")
+		prompt.WriteString("- Use [moderator]: for moderator messages\n")
+		prompt.WriteString("- Use [PANELIST_ID]: for panelist messages (IDs listed above)\n")
+		prompt.WriteString("- NO extra text before the [ID]: marker\n")
+		prompt.WriteString(fmt.Sprintf("- Use [%s]: for phase markers\n", phaseSpeaker))
+		prompt.WriteString(fmt.Sprintf("- Start your response immediately with [%s]: %s\n\n", phaseSpeaker, format.Phases[0].ID))
+	}
+	writeHumanTurns(&prompt, req, structured)
 	prompt.WriteString("Guidelines:\n")
 	prompt.WriteString("- Moderator responses: 1-3 sentences, neutral and facilitating\n")
-	prompt.WriteString("- Panelist responses: 2-4 sentences (50-100 words)\n")
 	prompt.WriteString("- Maintain each panelist's historical perspective and known positions\n")
 	prompt.WriteString("- Create engaging exchanges with direct responses and counter-arguments\n")
 	prompt.WriteString("- Let panelists speak to each other directly, not just to the moderator\n")
 	prompt.WriteString("- Moderator should intervene naturally, not after every exchange\n")
-	prompt.WriteString("- Ensure philosophical depth while remaining accessible\n\n")
-	prompt.WriteString("Begin the debate:")
+	writeStyle(&prompt, req)
+	prompt.WriteString("\n")
+	prompt.WriteString(beginDebate)
 
 	return prompt.String()
 }
 
-// streamResponse processes the SDK stream and writes formatted chunks
-func (c *ClaudeClient) streamResponse(stream *ssestream.Stream[anthropic.MessageStreamEventUnion], writer io.Writer) error {
+// streamResponse processes the model stream and emits formatted chunks
+func (c *ClaudeClient) streamResponse(stream llm.Stream, emitter ChunkEmitter) error {
 	var patternBuffer strings.Builder // Buffer only for incomplete [handle]: patterns
 	var currentSpeaker string
 	inPattern := false
-	flusher, _ := writer.(http.Flusher)
 
 	sendChunk := func(speaker, text string) {
 		if text == "" {
 			return
 		}
-		chunk := StreamChunk{
+		emitter.Emit(StreamChunk{
 			Type:       "message",
 			PanelistID: speaker,
 			Text:       text,
 			Done:       false,
-		}
-		json.NewEncoder(writer).Encode(chunk)
-		if flusher != nil {
-			flusher.Flush()
-		}
+		})
 	}
 
 	for stream.Next() {
-		event := stream.Current()
-
-		if event.Delta.Text == "" {
-			continue
-		}
-
-		text := event.Delta.Text
+		text := stream.Current()
 
 		// Process character by character (runes, not bytes - handles UTF-8 correctly)
 		for _, char := range text {
@@ -192,14 +271,10 @@ func (c *ClaudeClient) streamResponse(stream *ssestream.Stream[anthropic.Message
 	}
 
 	// Send done signal
-	chunk := StreamChunk{
+	emitter.Emit(StreamChunk{
 		Type: "done",
 		Done: true,
-	}
-	json.NewEncoder(writer).Encode(chunk)
-	if flusher != nil {
-		flusher.Flush()
-	}
+	})
 
 	return nil
 }
@@ -0,0 +1,35 @@
+// Command index-corpus builds the corpus index that debates draw source
+// passages from, out of a directory of .txt and .jsonl files
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/raphink/debate/shared/corpus"
+)
+
+func main() {
+	dir := flag.String("dir", "corpus", "directory of corpus files")
+	path := flag.String("index", os.Getenv("CORPUS_INDEX"), "index file to build (default $CORPUS_INDEX)")
+	flag.Parse()
+
+	if *path == "" {
+		log.Fatal("No index file: set -index or CORPUS_INDEX")
+	}
+
+	ctx := context.Background()
+	index, err := corpus.Open(ctx, *path)
+	if err != nil {
+		log.Fatalf("Failed to open index: %v", err)
+	}
+	defer index.Close()
+
+	count, err := index.Load(ctx, *dir)
+	if err != nil {
+		log.Fatalf("Failed to index %s: %v", *dir, err)
+	}
+	log.Printf("Indexed %d passages from %s into %s", count, *dir, *path)
+}
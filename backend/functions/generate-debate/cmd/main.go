@@ -15,6 +15,12 @@ func main() {
 	}
 
 	http.HandleFunc("/", generatedebate.HandleGenerateDebate)
+	http.HandleFunc("/check-debate", generatedebate.HandleCheckDebate)
+	http.HandleFunc("/continue-debate", generatedebate.HandleContinueDebate)
+	http.HandleFunc("/fork-debate", generatedebate.HandleForkDebate)
+	http.HandleFunc("/interject-debate", generatedebate.HandleInterjectDebate)
+	http.HandleFunc("/reply-debate", generatedebate.HandleReplyDebate)
+	http.HandleFunc("/translate-debate", generatedebate.HandleTranslateDebate)
 
 	log.Printf("Starting generate-debate server on port %s", port)
 	if err := http.ListenAndServe(":"+port, nil); err != nil {
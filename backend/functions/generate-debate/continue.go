@@ -0,0 +1,288 @@
+package generatedebate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/llm"
+	"github.com/raphink/debate/shared/store"
+)
+
+// handleContinueDebateImpl streams additional exchanges for a saved debate
+// and appends them to the stored document.
+// GET requests resume the stream with Last-Event-ID.
+func handleContinueDebateImpl(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method == "GET" {
+		handleResumeDebate(w, r)
+		return
+	}
+
+	if r.Method != "POST" {
+		sendError(w, "Method not allowed", ErrInvalidRequest, false, http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse request body
+	var req ContinueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, "Invalid request body", ErrInvalidRequest, false, http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	if err := ValidateContinueRequest(&req); err != nil {
+		sendError(w, err.Error(), ErrInvalidRequest, false, http.StatusBadRequest)
+		return
+	}
+
+	debateStore, doc, ok := loadDebate(r.Context(), w, req.DebateID)
+	if !ok {
+		return
+	}
+
+	if doc.Status == firebase.StatusInProgress {
+		sendError(w, "Debate is still being generated", ErrDebateInProgress, true, http.StatusConflict)
+		return
+	}
+
+	// Create Claude client
+	claudeClient, err := newClaudeClient()
+	if err != nil {
+		log.Printf("Failed to create Claude client: %v", err)
+		sendError(w, "Service configuration error", ErrInternalError, true, http.StatusInternalServerError)
+		return
+	}
+
+	// Claim the debate, as another request may have started extending it
+	doc, ok = claimDebate(r.Context(), w, debateStore, doc.ID, false)
+	if !ok {
+		return
+	}
+
+	log.Printf("Continuing debate %s with %d exchanges", doc.ID, req.Exchanges)
+
+	debateReq := debateRequestFromDocument(doc, req.OutputMode)
+	accumulator := NewDebateAccumulatorFromDocument(doc)
+
+	// Keep the user agent of the original debate
+	serveGeneration(w, r, debateStore, accumulator, doc.Metadata.UserAgent, func(ctx context.Context, emitter ChunkEmitter) error {
+		return claudeClient.ContinueDebate(ctx, debateReq, doc.Messages, ContinueOptions{Exchanges: req.Exchanges}, emitter)
+	})
+}
+
+// loadDebate loads a debate from the store, sending an error response if it cannot
+func loadDebate(ctx context.Context, w http.ResponseWriter, debateID string) (store.DebateStore, *firebase.DebateDocument, bool) {
+	// The debate store is required to load the debate
+	debateStore, err := store.Default(ctx)
+	if err != nil {
+		log.Printf("Failed to initialize debate store: %v", err)
+		sendError(w, "Debate storage unavailable", ErrServiceUnavailable, true, http.StatusServiceUnavailable)
+		return nil, nil, false
+	}
+
+	doc, err := debateStore.GetDebate(ctx, debateID)
+	if errors.Is(err, store.ErrNotFound) {
+		sendError(w, "Debate not found", ErrDebateNotFound, false, http.StatusNotFound)
+		return nil, nil, false
+	}
+	if err != nil {
+		log.Printf("Failed to load debate %s: %v", debateID, err)
+		sendError(w, "Failed to load debate", ErrInternalError, true, http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	return debateStore, doc, true
+}
+
+// Errors returned when a debate cannot be claimed
+var (
+	errDebateInProgress = errors.New("debate is still being generated")
+	errNotHumanTurn     = errors.New("not the human panelist's turn")
+	errAwaitingHuman    = errors.New("debate is awaiting the human panelist")
+)
+
+// claimDebate atomically moves a debate that is not being generated to
+// in_progress, so that two requests never extend it at once, and returns it
+// as claimed. With humanTurn, the debate must be awaiting the human panelist;
+// without, it must not be, so that the human panelist keeps their turn.
+// It sends an error response if the debate cannot be claimed.
+func claimDebate(ctx context.Context, w http.ResponseWriter, debateStore store.DebateStore, debateID string, humanTurn bool) (*firebase.DebateDocument, bool) {
+	var claimed *firebase.DebateDocument
+	err := debateStore.UpdateDebate(ctx, debateID, func(debate *firebase.DebateDocument) error {
+		if humanTurn && debate.Status != firebase.StatusAwaitingHuman {
+			return errNotHumanTurn
+		}
+		if !humanTurn && debate.Status == firebase.StatusAwaitingHuman {
+			return errAwaitingHuman
+		}
+		if debate.Status == firebase.StatusInProgress {
+			return errDebateInProgress
+		}
+		debate.Status = firebase.StatusInProgress
+		claimed = debate
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, errDebateInProgress):
+		sendError(w, "Debate is still being generated", ErrDebateInProgress, true, http.StatusConflict)
+		return nil, false
+	case errors.Is(err, errNotHumanTurn):
+		sendError(w, "It is not the human panelist's turn", ErrNotHumanTurn, false, http.StatusConflict)
+		return nil, false
+	case errors.Is(err, errAwaitingHuman):
+		sendError(w, "Debate is awaiting the human panelist's reply", ErrAwaitingHuman, false, http.StatusConflict)
+		return nil, false
+	case errors.Is(err, store.ErrNotFound):
+		sendError(w, "Debate not found", ErrDebateNotFound, false, http.StatusNotFound)
+		return nil, false
+	case err != nil:
+		log.Printf("Failed to claim debate %s: %v", debateID, err)
+		sendError(w, "Failed to load debate", ErrInternalError, true, http.StatusInternalServerError)
+		return nil, false
+	}
+	return claimed, true
+}
+
+// debateRequestFromDocument rebuilds the request of a stored debate, to extend
+// it in mode. Debates with citations default to the structured mode.
+func debateRequestFromDocument(doc *firebase.DebateDocument, mode string) *DebateRequest {
+	if doc.Citations && mode == "" {
+		mode = OutputModeStructured
+	}
+	return &DebateRequest{
+		Topic:             doc.Topic.Text,
+		SelectedPanelists: panelistsFromDocument(doc),
+		OutputMode:        mode,
+		Format:            doc.Format,
+		TurnLength:        doc.Style.TurnLength,
+		Register:          doc.Style.Register,
+		Tone:              doc.Style.Tone,
+		Language:          doc.Language,
+		Citations:         doc.Citations,
+		passages:          doc.Passages,
+	}
+}
+
+// ContinueOptions describes how a debate is extended
+type ContinueOptions struct {
+	Exchanges    int      // Number of additional exchanges
+	Instructions []string // Extra directions for the continuation, e.g. panel changes
+	Open         bool     // Leave the debate open for interjections instead of concluding
+}
+
+// ContinueDebate streams additional exchanges following transcript
+func (c *ClaudeClient) ContinueDebate(ctx context.Context, req *DebateRequest, transcript []firebase.Message, opts ContinueOptions, emitter ChunkEmitter) error {
+	// Nothing to continue from: start the debate over, with the same
+	// directions and length
+	if len(transcript) == 0 {
+		fresh := *req
+		if opts.Exchanges > 0 {
+			fresh.Exchanges = opts.Exchanges
+		}
+		prompt := c.buildDebatePrompt(&fresh)
+		if len(opts.Instructions) > 0 {
+			var directions strings.Builder
+			directions.WriteString(strings.TrimSuffix(prompt, beginDebate))
+			directions.WriteString("Additional directions:\n")
+			for _, instruction := range opts.Instructions {
+				directions.WriteString(fmt.Sprintf("- %s\n", instruction))
+			}
+			directions.WriteString("\n")
+			directions.WriteString(beginDebate)
+			prompt = directions.String()
+		}
+		return c.streamDebate(ctx, llm.UserPrompt(prompt, tokenBudget(&fresh, maxExchanges(&fresh))), &fresh, emitter)
+	}
+
+	mode := outputMode(req)
+
+	// Replay the debate as the model's own earlier answer
+	request := llm.Request{
+		Messages: []llm.Message{
+			{Role: llm.RoleUser, Text: c.buildDebatePrompt(req)},
+			{Role: llm.RoleAssistant, Text: formatTranscript(transcript, mode)},
+			{Role: llm.RoleUser, Text: c.buildContinuationPrompt(opts, mode)},
+		},
+		MaxTokens: tokenBudget(req, opts.Exchanges),
+	}
+
+	return c.streamDebate(ctx, request, req, emitter)
+}
+
+// buildContinuationPrompt asks the model to extend the debate it was given
+func (c *ClaudeClient) buildContinuationPrompt(opts ContinueOptions, mode string) string {
+	var prompt strings.Builder
+
+	moderatorTurn := "[moderator]:"
+	if mode == OutputModeStructured {
+		moderatorTurn = `{"speaker":"moderator"}`
+	}
+
+	lastMessage := "a concluding summary of the whole debate - 3-5 sentences"
+	if opts.Open {
+		lastMessage = openEnding
+	}
+
+	prompt.WriteString(fmt.Sprintf("Continue the debate with %d more exchanges between the panelists.\n", opts.Exchanges))
+	for _, instruction := range opts.Instructions {
+		prompt.WriteString(fmt.Sprintf("- %s\n", instruction))
+	}
+	prompt.WriteString("- Pick up where the last message left off, without repeating earlier arguments\n")
+	prompt.WriteString("- Panelists should respond to points already made and develop new ones\n")
+	prompt.WriteString("- The moderator may intervene to open a new line of inquiry\n")
+	prompt.WriteString(fmt.Sprintf("- LAST MESSAGE MUST BE: %s (%s)\n", moderatorTurn, lastMessage))
+	prompt.WriteString("- Use exactly the same format as before, with no extra text\n\n")
+	prompt.WriteString("Continue the debate:")
+
+	return prompt.String()
+}
+
+// formatTranscript renders stored messages in the output format of mode
+func formatTranscript(messages []firebase.Message, mode string) string {
+	var transcript strings.Builder
+
+	phase := ""
+	for _, msg := range messages {
+		// Mark phases as the model did
+		if msg.Phase != "" && msg.Phase != phase {
+			phase = msg.Phase
+			if mode == OutputModeStructured {
+				line, _ := json.Marshal(structuredTurn{Speaker: phaseSpeaker, Text: phase})
+				transcript.Write(line)
+				transcript.WriteString("\n")
+			} else {
+				transcript.WriteString(fmt.Sprintf("[%s]: %s\n\n", phaseSpeaker, phase))
+			}
+		}
+
+		if mode == OutputModeStructured {
+			line, _ := json.Marshal(structuredTurn{
+				Speaker:   msg.PanelistID,
+				Addresses: msg.Addresses,
+				Text:      msg.Text,
+				Citations: msg.Citations,
+			})
+			transcript.Write(line)
+			transcript.WriteString("\n")
+			continue
+		}
+
+		transcript.WriteString(fmt.Sprintf("[%s]: %s\n\n", msg.PanelistID, msg.Text))
+	}
+
+	return strings.TrimSpace(transcript.String())
+}
@@ -0,0 +1,159 @@
+package generatedebate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/llm"
+	"github.com/raphink/debate/shared/store"
+)
+
+// saveTestDebate stores a finished debate ending with the moderator
+func saveTestDebate(t *testing.T, s store.DebateStore, id, status string) {
+	t.Helper()
+
+	err := s.SaveDebate(context.Background(), id, &firebase.DebateDocument{
+		ID:    id,
+		Topic: firebase.Topic{Text: "Should Christians defy unjust laws?", IsRelevant: true},
+		Panelists: []firebase.Panelist{
+			{ID: "augustine", Name: "Augustine of Hippo", AvatarURL: "augustine.jpg"},
+			{ID: "mlk", Name: "Martin Luther King Jr.", AvatarURL: "mlk.jpg"},
+		},
+		Messages: []firebase.Message{
+			{ID: "augustine-0", PanelistID: "augustine", Text: "An unjust law is no law at all.", Sequence: 0, IsComplete: true},
+			{ID: "moderator-1", PanelistID: "moderator", Text: "Thank you both.", Sequence: 1, IsComplete: true},
+		},
+		Status:    status,
+		StartedAt: time.Now().Add(-time.Hour),
+		Metadata:  firebase.Metadata{UserAgent: "original-agent"},
+	})
+	if err != nil {
+		t.Fatalf("SaveDebate() error = %v", err)
+	}
+}
+
+func TestHandleContinueDebate(t *testing.T) {
+	fake := llm.NewFake("[moderator]: Let us go further.\n", "[mlk]: Injustice anywhere is a threat to justice everywhere.\n", "[moderator]: Thank you.")
+	memStore := useFakes(t, fake)
+	saveTestDebate(t, memStore, "d1", firebase.StatusComplete)
+
+	req := httptest.NewRequest(http.MethodPost, "/continue-debate", strings.NewReader(`{"debateId": "d1", "exchanges": 3}`))
+	rec := httptest.NewRecorder()
+	HandleContinueDebate(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	assertMessages(t, mergeMessages(decodeChunks(t, rec.Body.String())), []StreamChunk{
+		{PanelistID: "moderator", Text: "Let us go further."},
+		{PanelistID: "mlk", Text: "Injustice anywhere is a threat to justice everywhere."},
+		{PanelistID: "moderator", Text: "Thank you."},
+	})
+
+	// The transcript is replayed as the model's previous answer
+	messages := fake.Requests()[0].Messages
+	if len(messages) != 3 || messages[1].Role != llm.RoleAssistant {
+		t.Fatalf("model request = %+v, want prompt, transcript and continuation", messages)
+	}
+	if !strings.Contains(messages[1].Text, "[augustine]: An unjust law is no law at all.") {
+		t.Errorf("transcript = %q", messages[1].Text)
+	}
+	if !strings.Contains(messages[2].Text, "3 more exchanges") {
+		t.Errorf("continuation prompt = %q", messages[2].Text)
+	}
+
+	debate := waitForDebate(t, memStore, "d1")
+	if debate.Status != firebase.StatusComplete || debate.Metadata.UserAgent != "original-agent" {
+		t.Errorf("saved debate status %q by %q, want complete by original-agent", debate.Status, debate.Metadata.UserAgent)
+	}
+	if len(debate.Messages) != 5 {
+		t.Fatalf("saved %d messages, want 5", len(debate.Messages))
+	}
+	for i, msg := range debate.Messages {
+		if msg.Sequence != i {
+			t.Errorf("message %d has sequence %d", i, msg.Sequence)
+		}
+	}
+	// The new moderator message is not merged into the stored one
+	if debate.Messages[1].Text != "Thank you both." || debate.Messages[2].Text != "Let us go further." {
+		t.Errorf("moderator messages = %q, %q", debate.Messages[1].Text, debate.Messages[2].Text)
+	}
+	if debate.Messages[3].PanelistName != "Martin Luther King Jr." || debate.Messages[3].ID != "mlk-3" {
+		t.Errorf("new message = %+v, want MLK's name and ID mlk-3", debate.Messages[3])
+	}
+}
+
+func TestHandleContinueDebateErrors(t *testing.T) {
+	memStore := useFakes(t, llm.NewFake())
+	saveTestDebate(t, memStore, "running", firebase.StatusInProgress)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "missing debate ID", body: `{}`, want: http.StatusBadRequest},
+		{name: "too many exchanges", body: `{"debateId": "running", "exchanges": 50}`, want: http.StatusBadRequest},
+		{name: "unknown debate", body: `{"debateId": "missing"}`, want: http.StatusNotFound},
+		{name: "debate in progress", body: `{"debateId": "running"}`, want: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/continue-debate", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			HandleContinueDebate(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestClaimDebate(t *testing.T) {
+	memStore := store.NewMemoryStore()
+	saveTestDebate(t, memStore, "d1", firebase.StatusComplete)
+
+	// Only the first of two requests extending the debate gets it
+	doc, ok := claimDebate(context.Background(), httptest.NewRecorder(), memStore, "d1", false)
+	if !ok || doc.Status != firebase.StatusInProgress || len(doc.Messages) != 2 {
+		t.Fatalf("claimDebate() = %+v, %v, want the debate in progress", doc, ok)
+	}
+	if saved, _ := memStore.GetDebate(context.Background(), "d1"); saved.Status != firebase.StatusInProgress {
+		t.Errorf("stored status = %q, want in_progress", saved.Status)
+	}
+
+	rec := httptest.NewRecorder()
+	if _, ok := claimDebate(context.Background(), rec, memStore, "d1", false); ok || rec.Code != http.StatusConflict {
+		t.Errorf("second claimDebate() = %v with status %d, want a conflict", ok, rec.Code)
+	}
+
+	// The human panelist's turn is kept for them
+	saveTestDebate(t, memStore, "d2", firebase.StatusAwaitingHuman)
+	rec = httptest.NewRecorder()
+	if _, ok := claimDebate(context.Background(), rec, memStore, "d2", false); ok || rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), ErrAwaitingHuman) {
+		t.Errorf("claimDebate() of a debate awaiting the human = %v with %d %s, want a conflict", ok, rec.Code, rec.Body.String())
+	}
+	if saved, _ := memStore.GetDebate(context.Background(), "d2"); saved.Status != firebase.StatusAwaitingHuman {
+		t.Errorf("stored status = %q, want awaiting_human", saved.Status)
+	}
+}
+
+func TestFormatTranscriptStructured(t *testing.T) {
+	transcript := formatTranscript([]firebase.Message{
+		{PanelistID: "moderator", Text: "Welcome."},
+		{PanelistID: "augustine", Addresses: []string{"moderator"}, Text: `He said "peace".`},
+	}, OutputModeStructured)
+
+	want := `{"speaker":"moderator","text":"Welcome."}` + "\n" +
+		`{"speaker":"augustine","addresses":["moderator"],"text":"He said \"peace\"."}`
+	if transcript != want {
+		t.Errorf("formatTranscript() = %q, want %q", transcript, want)
+	}
+}
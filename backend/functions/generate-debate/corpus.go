@@ -0,0 +1,92 @@
+package generatedebate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/raphink/debate/shared/corpus"
+	"github.com/raphink/debate/shared/firebase"
+)
+
+// passagesPerPanelist is the number of corpus passages given for each panelist
+const passagesPerPanelist = 3
+
+// maxPassageLength bounds the length of a passage in the prompt, in runes
+const maxPassageLength = 800
+
+// retrievePassages looks up the passages of the panelists' own writings that
+// are most relevant to the topic. Debates are generated without passages
+// when no corpus is configured or retrieval fails.
+func retrievePassages(ctx context.Context, req *DebateRequest) []firebase.Passage {
+	index, err := corpus.Default(ctx)
+	if err != nil {
+		if !errors.Is(err, corpus.ErrNotConfigured) {
+			log.Printf("Failed to open corpus index (non-blocking): %v", err)
+		}
+		return nil
+	}
+
+	var passages []firebase.Passage
+	for _, p := range req.SelectedPanelists {
+		if p.Human {
+			continue
+		}
+
+		found, err := index.Retrieve(ctx, []string{p.Name, p.ID}, req.Topic+" "+p.Position, passagesPerPanelist)
+		if err != nil {
+			log.Printf("Failed to retrieve passages for %s (non-blocking): %v", p.ID, err)
+			continue
+		}
+		for _, passage := range found {
+			passages = append(passages, firebase.Passage{
+				ID:         passage.ID,
+				PanelistID: p.ID,
+				Work:       passage.Work,
+				Locator:    passage.Locator,
+				Text:       truncatePassage(passage.Text),
+			})
+		}
+	}
+
+	if len(passages) > 0 {
+		log.Printf("Retrieved %d corpus passages for the debate", len(passages))
+	}
+	return passages
+}
+
+// truncatePassage shortens text to maxPassageLength runes
+func truncatePassage(text string) string {
+	runes := []rune(text)
+	if len(runes) <= maxPassageLength {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:maxPassageLength])) + "…"
+}
+
+// writePassages writes the corpus passages of the panelists of req, for the
+// model to draw on rather than invent quotations
+func writePassages(prompt *strings.Builder, req *DebateRequest) {
+	var sources strings.Builder
+	for _, p := range req.SelectedPanelists {
+		for _, passage := range req.passages {
+			if passage.PanelistID != p.ID {
+				continue
+			}
+			source := passage.Work
+			if passage.Locator != "" {
+				source += ", " + passage.Locator
+			}
+			sources.WriteString(fmt.Sprintf("- [%s] %s: %q\n", p.ID, source, passage.Text))
+		}
+	}
+	if sources.Len() == 0 {
+		return
+	}
+
+	prompt.WriteString("\nSOURCE PASSAGES from the panelists' own writings:\n")
+	prompt.WriteString(sources.String())
+	prompt.WriteString("When panelists quote their writings, quote or closely paraphrase these passages rather than inventing quotations.\n")
+}
@@ -0,0 +1,101 @@
+package generatedebate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/raphink/debate/shared/corpus"
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/llm"
+)
+
+// useCorpus indexes a corpus of Augustine's writings as the default index
+func useCorpus(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+	text := "Author: Augustine of Hippo\nWork: On Free Choice of the Will\n\n# Book I, Chapter 5\n\n" +
+		"For it seems to me that a law which is unjust is no law at all.\n\n" +
+		"# Book II, Chapter 1\n\nWhy did God give free choice of the will to man?\n"
+	if err := os.WriteFile(filepath.Join(dir, "free-choice.txt"), []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := corpus.Open(context.Background(), filepath.Join(t.TempDir(), "corpus.db"))
+	if err != nil {
+		t.Fatalf("corpus.Open() error = %v", err)
+	}
+	if _, err := index.Load(context.Background(), dir); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	corpus.SetDefault(index)
+
+	t.Cleanup(func() {
+		corpus.SetDefault(nil)
+		index.Close()
+	})
+}
+
+func TestHandleGenerateDebatePassages(t *testing.T) {
+	fake := llm.NewFake("[moderator]: Welcome.\n[augustine]: An unjust law is no law at all.\n[moderator]: Thank you.")
+	memStore := useFakes(t, fake)
+	useCorpus(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(testDebateBody))
+	rec := httptest.NewRecorder()
+	HandleGenerateDebate(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+
+	prompt := fake.Requests()[0].Messages[0].Text
+	if !strings.Contains(prompt, `- [augustine] On Free Choice of the Will, Book I, Chapter 5: "For it seems to me that a law which is unjust is no law at all."`) {
+		t.Errorf("prompt does not give the passage:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "free choice of the will to man") {
+		t.Errorf("prompt gives an irrelevant passage:\n%s", prompt)
+	}
+
+	// The passages used are recorded on the debate
+	debate := waitForDebate(t, memStore, rec.Header().Get("X-Debate-Id"))
+	want := []firebase.Passage{{
+		ID:         "free-choice.txt#2",
+		PanelistID: "augustine",
+		Work:       "On Free Choice of the Will",
+		Locator:    "Book I, Chapter 5",
+		Text:       "For it seems to me that a law which is unjust is no law at all.",
+	}}
+	if len(debate.Passages) != 1 || debate.Passages[0] != want[0] {
+		t.Errorf("saved passages = %+v, want %+v", debate.Passages, want)
+	}
+}
+
+func TestBuildDebatePromptWithoutPassages(t *testing.T) {
+	client := NewClaudeClientWithModel(llm.NewFake())
+	req := &DebateRequest{
+		Topic:             "Should Christians defy unjust laws?",
+		SelectedPanelists: []Panelist{{ID: "augustine", Name: "Augustine of Hippo"}},
+		passages:          []firebase.Passage{{PanelistID: "aquinas", Work: "Summa Theologica", Text: "Unjust laws are acts of violence."}},
+	}
+
+	// Passages of panelists who left the panel are not given
+	if prompt := client.buildDebatePrompt(req); strings.Contains(prompt, "SOURCE PASSAGES") {
+		t.Errorf("prompt gives passages of another panelist:\n%s", prompt)
+	}
+}
+
+func TestTruncatePassage(t *testing.T) {
+	long := strings.Repeat("é", maxPassageLength+1)
+	if got := []rune(truncatePassage(long)); len(got) != maxPassageLength+1 || got[maxPassageLength] != '…' {
+		t.Errorf("truncatePassage() kept %d runes, want %d and an ellipsis", len(got), maxPassageLength)
+	}
+	if got := truncatePassage("short"); got != "short" {
+		t.Errorf("truncatePassage(%q) = %q", "short", got)
+	}
+}
@@ -0,0 +1,59 @@
+package generatedebate
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/raphink/debate/shared/sse"
+)
+
+// eventLogTTL is how long a client can resume a debate after it finished
+const eventLogTTL = 10 * time.Minute
+
+// debateEvents keeps the event log of recent debates by debate ID
+var debateEvents = sse.NewRegistry(eventLogTTL)
+
+// eventLogEmitter appends chunks to a debate's event log, using the chunk
+// type as the event name
+type eventLogEmitter struct {
+	log *sse.Log
+}
+
+// Emit appends chunk to the event log
+func (e eventLogEmitter) Emit(chunk StreamChunk) {
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		log.Printf("Failed to encode chunk: %v", err)
+		return
+	}
+	e.log.Append(chunk.Type, data)
+}
+
+// handleResumeDebate replays the events of an in-flight or recently finished
+// debate after the client's Last-Event-ID
+func handleResumeDebate(w http.ResponseWriter, r *http.Request) {
+	debateID := r.URL.Query().Get("debateId")
+	if debateID == "" {
+		sendError(w, "debateId is required", ErrInvalidRequest, false, http.StatusBadRequest)
+		return
+	}
+
+	eventLog, ok := debateEvents.Get(debateID)
+	if !ok {
+		sendError(w, "Debate stream not found or expired", ErrStreamNotFound, false, http.StatusNotFound)
+		return
+	}
+
+	lastEventID := sse.LastEventID(r)
+	log.Printf("Resuming debate %s after event %d", debateID, lastEventID)
+
+	sse.SetHeaders(w)
+	w.Header().Set("X-Debate-Id", debateID)
+	w.WriteHeader(http.StatusOK)
+
+	if err := eventLog.Replay(r.Context(), w, lastEventID); err != nil {
+		log.Printf("Client left resumed debate %s: %v", debateID, err)
+	}
+}
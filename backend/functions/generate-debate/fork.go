@@ -0,0 +1,201 @@
+package generatedebate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/raphink/debate/shared/firebase"
+)
+
+// handleForkDebateImpl creates a new debate from a saved one, cut at a given
+// message and with a different panel, and streams its continuation.
+// GET requests resume the stream with Last-Event-ID.
+func handleForkDebateImpl(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method == "GET" {
+		handleResumeDebate(w, r)
+		return
+	}
+
+	if r.Method != "POST" {
+		sendError(w, "Method not allowed", ErrInvalidRequest, false, http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse request body
+	var req ForkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, "Invalid request body", ErrInvalidRequest, false, http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	if err := ValidateForkRequest(&req); err != nil {
+		sendError(w, err.Error(), ErrInvalidRequest, false, http.StatusBadRequest)
+		return
+	}
+
+	debateStore, source, ok := loadDebate(r.Context(), w, req.DebateID)
+	if !ok {
+		return
+	}
+
+	// Build the forked debate and check its panel
+	fork, err := buildFork(source, &req, uuid.New().String())
+	if err != nil {
+		sendError(w, err.Error(), ErrInvalidRequest, false, http.StatusBadRequest)
+		return
+	}
+
+	debateReq := debateRequestFromDocument(fork, req.OutputMode)
+	if err := ValidateDebateRequest(debateReq); err != nil {
+		sendError(w, err.Error(), ErrInvalidPanelists, false, http.StatusBadRequest)
+		return
+	}
+
+	// Create Claude client
+	claudeClient, err := newClaudeClient()
+	if err != nil {
+		log.Printf("Failed to create Claude client: %v", err)
+		sendError(w, "Service configuration error", ErrInternalError, true, http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Forking debate %s at sequence %d as %s", source.ID, fork.ForkedAtSequence, fork.ID)
+
+	opts := ContinueOptions{
+		Exchanges:    req.Exchanges,
+		Instructions: forkInstructions(source, &req),
+	}
+	accumulator := NewDebateAccumulatorFromDocument(fork)
+
+	serveGeneration(w, r, debateStore, accumulator, r.Header.Get("User-Agent"), func(ctx context.Context, emitter ChunkEmitter) error {
+		return claudeClient.ContinueDebate(ctx, debateReq, fork.Messages, opts, emitter)
+	})
+}
+
+// buildFork creates the document of a debate forked from source: the complete
+// messages before the cut point, with the panel changes of req applied
+func buildFork(source *firebase.DebateDocument, req *ForkRequest, id string) (*firebase.DebateDocument, error) {
+	// Messages are numbered from 0, so the end of the debate is the next sequence
+	end := 0
+	for _, msg := range source.Messages {
+		if msg.Sequence >= end {
+			end = msg.Sequence + 1
+		}
+	}
+
+	cut := end
+	if req.AtSequence != nil {
+		cut = *req.AtSequence
+	}
+	if cut > end {
+		return nil, fmt.Errorf("atSequence must not exceed %d", end)
+	}
+
+	messages := make([]firebase.Message, 0, len(source.Messages))
+	for _, msg := range source.Messages {
+		if msg.Sequence < cut && msg.IsComplete {
+			messages = append(messages, msg)
+		}
+	}
+
+	// Apply panel changes
+	removed := make(map[string]bool)
+	for _, panelistID := range req.RemovePanelists {
+		if !hasPanelist(source.Panelists, panelistID) {
+			return nil, fmt.Errorf("panelist %s is not part of the debate", panelistID)
+		}
+		removed[panelistID] = true
+	}
+
+	panelists := make([]firebase.Panelist, 0, len(source.Panelists)+len(req.AddPanelists))
+	for _, p := range source.Panelists {
+		if !removed[p.ID] {
+			panelists = append(panelists, p)
+		}
+	}
+	for _, p := range req.AddPanelists {
+		if hasPanelist(panelists, p.ID) {
+			return nil, fmt.Errorf("panelist %s is already part of the debate", p.ID)
+		}
+		panelists = append(panelists, firebase.Panelist{
+			ID:          p.ID,
+			Name:        p.Name,
+			Tagline:     p.Tagline,
+			Biography:   p.Bio,
+			AvatarURL:   p.AvatarURL,
+			Position:    p.Position,
+			Human:       p.Human,
+			Born:        p.Born,
+			Died:        p.Died,
+			Attribution: p.Attribution,
+		})
+	}
+
+	return &firebase.DebateDocument{
+		ID:               id,
+		Topic:            source.Topic,
+		Format:           source.Format,
+		Style:            source.Style,
+		Language:         source.Language,
+		Citations:        source.Citations,
+		Passages:         source.Passages,
+		Panelists:        panelists,
+		Messages:         messages,
+		Status:           firebase.StatusInProgress,
+		StartedAt:        time.Now(),
+		ForkedFrom:       source.ID,
+		ForkedAtSequence: cut,
+	}, nil
+}
+
+// forkInstructions tells the model about the panel changes and the
+// moderator's question at the start of the fork
+func forkInstructions(source *firebase.DebateDocument, req *ForkRequest) []string {
+	var instructions []string
+
+	for _, p := range source.Panelists {
+		for _, panelistID := range req.RemovePanelists {
+			if p.ID == panelistID {
+				instructions = append(instructions, fmt.Sprintf("%s (ID: %s) has left the panel and must not speak again", p.Name, p.ID))
+			}
+		}
+	}
+
+	for _, p := range req.AddPanelists {
+		instructions = append(instructions, fmt.Sprintf("%s (ID: %s) joins the panel now; the moderator welcomes them before they speak", p.Name, p.ID))
+	}
+
+	if req.ModeratorQuestion != "" {
+		instructions = append(instructions, moderatorQuestion(req.ModeratorQuestion))
+	}
+
+	return instructions
+}
+
+// moderatorQuestion asks the model to open with the moderator putting question to the panel
+func moderatorQuestion(question string) string {
+	return fmt.Sprintf("FIRST MESSAGE MUST BE the moderator asking the panel: %q", question)
+}
+
+// hasPanelist reports whether panelists contains the given ID
+func hasPanelist(panelists []firebase.Panelist, id string) bool {
+	for _, p := range panelists {
+		if p.ID == id {
+			return true
+		}
+	}
+	return false
+}
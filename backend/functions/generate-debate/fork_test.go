@@ -0,0 +1,180 @@
+package generatedebate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/llm"
+)
+
+func TestBuildFork(t *testing.T) {
+	source := &firebase.DebateDocument{
+		ID:    "parent",
+		Topic: firebase.Topic{Text: "Is war ever just?"},
+		Panelists: []firebase.Panelist{
+			{ID: "augustine", Name: "Augustine of Hippo"},
+			{ID: "aquinas", Name: "Thomas Aquinas"},
+		},
+		Messages: []firebase.Message{
+			{PanelistID: "moderator", Sequence: 0, IsComplete: true},
+			{PanelistID: "augustine", Sequence: 1, IsComplete: true},
+			{PanelistID: "aquinas", Sequence: 2, IsComplete: true},
+			{PanelistID: "augustine", Sequence: 3, IsComplete: false},
+		},
+	}
+	at := func(seq int) *int { return &seq }
+
+	tests := []struct {
+		name         string
+		req          ForkRequest
+		wantMessages int
+		wantCut      int
+		wantPanel    string
+		wantErr      string
+	}{
+		{
+			name:         "default cut keeps complete messages",
+			req:          ForkRequest{},
+			wantMessages: 3,
+			wantCut:      4,
+			wantPanel:    "augustine,aquinas",
+		},
+		{
+			name:         "swap a panelist mid-debate",
+			req:          ForkRequest{AtSequence: at(2), RemovePanelists: []string{"aquinas"}, AddPanelists: []Panelist{{ID: "mlk", Name: "Martin Luther King Jr."}}},
+			wantMessages: 2,
+			wantCut:      2,
+			wantPanel:    "augustine,mlk",
+		},
+		{
+			name:         "fork from the start",
+			req:          ForkRequest{AtSequence: at(0)},
+			wantMessages: 0,
+			wantCut:      0,
+			wantPanel:    "augustine,aquinas",
+		},
+		{name: "cut beyond the end", req: ForkRequest{AtSequence: at(9)}, wantErr: "atSequence"},
+		{name: "remove unknown panelist", req: ForkRequest{RemovePanelists: []string{"mlk"}}, wantErr: "not part"},
+		{name: "add existing panelist", req: ForkRequest{AddPanelists: []Panelist{{ID: "aquinas", Name: "Thomas Aquinas"}}}, wantErr: "already part"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fork, err := buildFork(source, &tt.req, "child")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("buildFork() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("buildFork() error = %v", err)
+			}
+
+			if fork.ID != "child" || fork.ForkedFrom != "parent" || fork.ForkedAtSequence != tt.wantCut {
+				t.Errorf("fork = %s from %s at %d, want child from parent at %d", fork.ID, fork.ForkedFrom, fork.ForkedAtSequence, tt.wantCut)
+			}
+			if len(fork.Messages) != tt.wantMessages {
+				t.Errorf("fork has %d messages, want %d", len(fork.Messages), tt.wantMessages)
+			}
+
+			var panel []string
+			for _, p := range fork.Panelists {
+				panel = append(panel, p.ID)
+			}
+			if strings.Join(panel, ",") != tt.wantPanel {
+				t.Errorf("fork panel = %v, want %s", panel, tt.wantPanel)
+			}
+		})
+	}
+}
+
+func TestHandleForkDebate(t *testing.T) {
+	fake := llm.NewFake("[moderator]: Welcome, Thomas. What makes a war just?\n", "[aquinas]: Three conditions.\n", "[moderator]: Thank you.")
+	memStore := useFakes(t, fake)
+	saveTestDebate(t, memStore, "parent", firebase.StatusComplete)
+
+	body := `{
+		"debateId": "parent",
+		"atSequence": 1,
+		"removePanelists": ["mlk"],
+		"addPanelists": [{"id": "aquinas", "name": "Thomas Aquinas"}],
+		"moderatorQuestion": "What makes a war <b>just</b>?"
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/fork-debate", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	HandleForkDebate(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+
+	forkID := rec.Header().Get("X-Debate-Id")
+	if forkID == "" || forkID == "parent" {
+		t.Fatalf("X-Debate-Id = %q, want a new debate ID", forkID)
+	}
+
+	continuation := fake.Requests()[0].Messages[2].Text
+	for _, want := range []string{"Martin Luther King Jr. (ID: mlk) has left the panel", "Thomas Aquinas (ID: aquinas) joins the panel", `"What makes a war just?"`} {
+		if !strings.Contains(continuation, want) {
+			t.Errorf("continuation prompt does not contain %q:\n%s", want, continuation)
+		}
+	}
+
+	fork := waitForDebate(t, memStore, forkID)
+	if fork.ForkedFrom != "parent" || fork.ForkedAtSequence != 1 {
+		t.Errorf("fork links to %q at %d, want parent at 1", fork.ForkedFrom, fork.ForkedAtSequence)
+	}
+	if len(fork.Messages) != 4 || fork.Messages[1].Sequence != 1 || fork.Messages[2].PanelistName != "Thomas Aquinas" {
+		t.Errorf("fork messages = %+v, want the copied message followed by the new ones", fork.Messages)
+	}
+
+	// The parent debate is left untouched
+	parent, _ := memStore.GetDebate(context.Background(), "parent")
+	if len(parent.Messages) != 2 || parent.Messages[1].Text != "Thank you both." {
+		t.Errorf("parent messages changed: %+v", parent.Messages)
+	}
+}
+
+func TestHandleForkDebateFromStart(t *testing.T) {
+	fake := llm.NewFake("[moderator]: Welcome, Thomas. What makes a war just?\n", "[aquinas]: Three conditions.\n", "[moderator]: Thank you.")
+	memStore := useFakes(t, fake)
+	saveTestDebate(t, memStore, "parent", firebase.StatusComplete)
+
+	body := `{
+		"debateId": "parent",
+		"atSequence": 0,
+		"exchanges": 4,
+		"removePanelists": ["mlk"],
+		"addPanelists": [{"id": "aquinas", "name": "Thomas Aquinas"}],
+		"moderatorQuestion": "What makes a war just?"
+	}`
+	rec := httptest.NewRecorder()
+	HandleForkDebate(rec, httptest.NewRequest(http.MethodPost, "/fork-debate", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+
+	// With nothing to continue, the fork starts over with its directions
+	messages := fake.Requests()[0].Messages
+	if len(messages) != 1 {
+		t.Fatalf("got %d prompt messages, want a new debate prompt", len(messages))
+	}
+	for _, want := range []string{"Include 4 exchanges", "Thomas Aquinas (ID: aquinas) joins the panel", `"What makes a war just?"`} {
+		if !strings.Contains(messages[0].Text, want) {
+			t.Errorf("prompt does not contain %q:\n%s", want, messages[0].Text)
+		}
+	}
+	if !strings.HasSuffix(messages[0].Text, "Begin the debate:") {
+		t.Errorf("prompt does not end with the debate start:\n%s", messages[0].Text)
+	}
+
+	fork := waitForDebate(t, memStore, rec.Header().Get("X-Debate-Id"))
+	if fork.ForkedAtSequence != 0 || len(fork.Messages) != 3 || fork.Messages[0].Sequence != 0 {
+		t.Errorf("fork = %+v, want the new messages only", fork.Messages)
+	}
+}
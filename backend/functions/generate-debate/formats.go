@@ -0,0 +1,143 @@
+package generatedebate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Debate formats
+const (
+	FormatRoundtable       = "roundtable"        // Free discussion (default)
+	FormatOxford           = "oxford"            // Opening statements, rebuttals and closings
+	FormatSocratic         = "socratic"          // Question-and-answer dialogue
+	FormatCrossExamination = "cross-examination" // Panelists question each other in pairs
+)
+
+// phaseSpeaker is the speaker ID the model uses to mark the start of a phase
+const phaseSpeaker = "phase"
+
+// DebateFormat describes the structure of a debate as a sequence of phases
+type DebateFormat struct {
+	ID          string
+	Name        string  // Display name
+	Description string  // What the format is about, for the model
+	Phases      []Phase // Phases in order
+	Exchanges   [2]int  // Default range of exchanges between panelists
+	Closing     string  // What the moderator's last message does
+}
+
+// Phase is a section of a debate, announced to clients with a phase chunk
+type Phase struct {
+	ID           string
+	Title        string // Section header for clients
+	Instructions string // What happens during the phase
+}
+
+// formats is the registry of available debate formats, by ID
+var formats = map[string]DebateFormat{
+	FormatRoundtable: {
+		ID:          FormatRoundtable,
+		Name:        "Roundtable",
+		Description: "A free, moderated conversation where panelists respond to each other as the discussion unfolds.",
+		Phases: []Phase{
+			{ID: "introduction", Title: "Introduction", Instructions: "The moderator introduces the topic and panelists"},
+			{ID: "discussion", Title: "Discussion", Instructions: "Panelists respond to each other; the moderator may occasionally intervene to redirect the conversation, ask clarifying questions, highlight contrasting viewpoints or summarize progress"},
+			{ID: "conclusion", Title: "Conclusion", Instructions: "The moderator closes the debate"},
+		},
+		Exchanges: [2]int{12, 18},
+		Closing:   "providing a concluding summary that synthesizes the key points, acknowledges different perspectives, and gracefully ends the debate - 3-5 sentences",
+	},
+	FormatOxford: {
+		ID:          FormatOxford,
+		Name:        "Oxford-style debate",
+		Description: "A formal debate where each panelist defends a position through prepared statements rather than free conversation.",
+		Phases: []Phase{
+			{ID: "opening", Title: "Opening statements", Instructions: "The moderator introduces the topic and panelists, then each panelist in turn states their position in 3-5 sentences"},
+			{ID: "rebuttal", Title: "Rebuttals", Instructions: "Panelists in turn rebut the strongest opposing argument, naming whom they answer"},
+			{ID: "closing", Title: "Closing statements", Instructions: "The moderator invites closing statements, then each panelist in turn sums up their case in 2-3 sentences"},
+		},
+		Exchanges: [2]int{8, 12},
+		Closing:   "summarizing how each position fared and gracefully ending the debate - 3-5 sentences",
+	},
+	FormatSocratic: {
+		ID:          FormatSocratic,
+		Name:        "Socratic dialogue",
+		Description: "A dialogue driven by questions: panelists examine each other's definitions and assumptions rather than making speeches.",
+		Phases: []Phase{
+			{ID: "question", Title: "The question", Instructions: "The moderator introduces the panelists and asks the question at the heart of the topic; a panelist proposes a first answer"},
+			{ID: "inquiry", Title: "Inquiry", Instructions: "Panelists question the answers given, test definitions against examples and expose contradictions; answers are refined or abandoned"},
+			{ID: "aporia", Title: "Where we stand", Instructions: "Panelists acknowledge what was learned and what remains unresolved"},
+		},
+		Exchanges: [2]int{10, 16},
+		Closing:   "stating which answers survived the inquiry and which questions remain open - 3-5 sentences",
+	},
+	FormatCrossExamination: {
+		ID:          FormatCrossExamination,
+		Name:        "Cross-examination",
+		Description: "Panelists state their case, then question each other in pairs under the moderator's supervision.",
+		Phases: []Phase{
+			{ID: "statements", Title: "Statements", Instructions: "The moderator introduces the topic and panelists, then each panelist states their case in 2-4 sentences"},
+			{ID: "cross-examination", Title: "Cross-examination", Instructions: "The moderator pairs panelists; in each pair one panelist asks 2-3 pointed questions and the other answers, then they switch roles; every panelist examines and is examined"},
+			{ID: "closing", Title: "Closing remarks", Instructions: "Each panelist answers the hardest question they were asked in 1-2 sentences"},
+		},
+		Exchanges: [2]int{10, 16},
+		Closing:   "weighing which answers held up under examination and gracefully ending the debate - 3-5 sentences",
+	},
+}
+
+// debateFormat returns the format of req, falling back to the roundtable
+func debateFormat(req *DebateRequest) DebateFormat {
+	if format, ok := formats[req.Format]; ok {
+		return format
+	}
+	return formats[FormatRoundtable]
+}
+
+// phaseTitle returns the title of the phase with the given ID, or the ID
+// itself for phases the format does not define
+func (f DebateFormat) phaseTitle(id string) string {
+	for _, phase := range f.Phases {
+		if phase.ID == id {
+			return phase.Title
+		}
+	}
+	return id
+}
+
+// writeFormat writes the phases of the debate format to the prompt
+func writeFormat(prompt *strings.Builder, format DebateFormat, structured bool) {
+	marker := fmt.Sprintf("[%s]: <phase ID>", phaseSpeaker)
+	if structured {
+		marker = fmt.Sprintf(`{"speaker":"%s","text":"<phase ID>"}`, phaseSpeaker)
+	}
+
+	prompt.WriteString(fmt.Sprintf("\nDebate format: %s. %s\n", format.Name, format.Description))
+	prompt.WriteString("Generate the debate in the following phases:\n")
+	for i, phase := range format.Phases {
+		prompt.WriteString(fmt.Sprintf("%d. %s (phase ID: %s): %s\n", i+1, phase.Title, phase.ID, phase.Instructions))
+	}
+	prompt.WriteString(fmt.Sprintf("Start each phase with the marker %s on its own line, before any message of the phase\n", marker))
+}
+
+// phaseEmitter turns the phase markers written by the model into phase chunks
+type phaseEmitter struct {
+	next    ChunkEmitter
+	format  DebateFormat
+	pending strings.Builder // ID of the phase being announced
+}
+
+// Emit buffers phase markers and forwards every other chunk
+func (e *phaseEmitter) Emit(chunk StreamChunk) {
+	if chunk.Type == "message" && chunk.PanelistID == phaseSpeaker {
+		e.pending.WriteString(chunk.Text)
+		return
+	}
+
+	// The marker ends when the next message starts
+	if id := strings.TrimSpace(e.pending.String()); id != "" {
+		e.next.Emit(StreamChunk{Type: "phase", Phase: id, Text: e.format.phaseTitle(id)})
+	}
+	e.pending.Reset()
+
+	e.next.Emit(chunk)
+}
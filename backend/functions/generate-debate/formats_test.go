@@ -0,0 +1,131 @@
+package generatedebate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/llm"
+)
+
+// phaseChunks returns the phase chunks of a stream
+func phaseChunks(chunks []StreamChunk) []StreamChunk {
+	var phases []StreamChunk
+	for _, chunk := range chunks {
+		if chunk.Type == "phase" {
+			phases = append(phases, chunk)
+		}
+	}
+	return phases
+}
+
+func TestHandleGenerateDebateFormat(t *testing.T) {
+	fake := llm.NewFake(
+		"[phase]: open",
+		"ing\n[moderator]: Welcome.\n[augustine]: An unjust law is no law at all.\n",
+		"[phase]: closing\n[mlk]: Justice too long delayed is justice denied.\n[moderator]: Thank you both.",
+	)
+	memStore := useFakes(t, fake)
+
+	body := strings.Replace(testDebateBody, `"topic"`, `"format": "oxford", "topic"`, 1)
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	HandleGenerateDebate(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+
+	chunks := decodeChunks(t, rec.Body.String())
+	phases := phaseChunks(chunks)
+	if len(phases) != 2 ||
+		phases[0].Phase != "opening" || phases[0].Text != "Opening statements" ||
+		phases[1].Phase != "closing" || phases[1].Text != "Closing statements" {
+		t.Errorf("phase chunks = %+v, want opening and closing statements", phases)
+	}
+	assertMessages(t, mergeMessages(chunks), []StreamChunk{
+		{PanelistID: "moderator", Text: "Welcome."},
+		{PanelistID: "augustine", Text: "An unjust law is no law at all."},
+		{PanelistID: "mlk", Text: "Justice too long delayed is justice denied."},
+		{PanelistID: "moderator", Text: "Thank you both."},
+	})
+
+	prompt := fake.Requests()[0].Messages[0].Text
+	for _, want := range []string{"Oxford-style debate", "Rebuttals (phase ID: rebuttal)", "Start your response immediately with [phase]: opening"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt does not contain %q:\n%s", want, prompt)
+		}
+	}
+
+	// Messages are stored with their phase
+	debate := waitForDebate(t, memStore, rec.Header().Get("X-Debate-Id"))
+	if debate.Format != FormatOxford {
+		t.Errorf("saved format = %q, want %q", debate.Format, FormatOxford)
+	}
+	var got []string
+	for _, msg := range debate.Messages {
+		got = append(got, msg.Phase)
+	}
+	if strings.Join(got, ",") != "opening,opening,closing,closing" {
+		t.Errorf("saved message phases = %v", got)
+	}
+}
+
+func TestGenerateDebateStructuredPhases(t *testing.T) {
+	client := NewClaudeClientWithModel(llm.NewFake(
+		`{"speaker":"phase","text":"inquiry"}`+"\n",
+		`{"speaker":"moderator","text":"What is justice?"}`,
+	))
+
+	var output chunkRecorder
+	req := &DebateRequest{Topic: "What is justice?", Format: FormatSocratic, OutputMode: OutputModeStructured}
+	if err := client.GenerateDebate(context.Background(), req, &output); err != nil {
+		t.Fatalf("GenerateDebate() error = %v", err)
+	}
+
+	phases := phaseChunks(output.chunks)
+	if len(phases) != 1 || phases[0].Phase != "inquiry" || phases[0].Text != "Inquiry" {
+		t.Errorf("phase chunks = %+v, want the inquiry phase", phases)
+	}
+	assertMessages(t, mergeMessages(output.chunks), []StreamChunk{
+		{PanelistID: "moderator", Text: "What is justice?"},
+	})
+}
+
+func TestDebateFormats(t *testing.T) {
+	for id, format := range formats {
+		if format.ID != id || format.Name == "" || format.Closing == "" || len(format.Phases) == 0 {
+			t.Errorf("format %q is incomplete: %+v", id, format)
+		}
+	}
+
+	if got := debateFormat(&DebateRequest{}); got.ID != FormatRoundtable {
+		t.Errorf("default format = %q, want %q", got.ID, FormatRoundtable)
+	}
+
+	err := ValidateDebateRequest(&DebateRequest{
+		Topic:             "Is war ever just?",
+		SelectedPanelists: []Panelist{{ID: "a", Name: "A"}, {ID: "b", Name: "B"}},
+		Format:            "shouting-match",
+	})
+	if err == nil {
+		t.Error("ValidateDebateRequest() accepted an unknown format")
+	}
+}
+
+func TestFormatTranscriptPhases(t *testing.T) {
+	transcript := formatTranscript([]firebase.Message{
+		{PanelistID: "moderator", Text: "Welcome.", Phase: "opening"},
+		{PanelistID: "augustine", Text: "Peace.", Phase: "opening"},
+		{PanelistID: "audience", Text: "Why?"},
+		{PanelistID: "mlk", Text: "Justice.", Phase: "rebuttal"},
+	}, OutputModeText)
+
+	want := "[phase]: opening\n\n[moderator]: Welcome.\n\n[augustine]: Peace.\n\n[audience]: Why?\n\n[phase]: rebuttal\n\n[mlk]: Justice."
+	if transcript != want {
+		t.Errorf("formatTranscript() = %q, want %q", transcript, want)
+	}
+}
@@ -6,28 +6,38 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/sse"
+	"github.com/raphink/debate/shared/store"
 )
 
-// handleGenerateDebateImpl handles debate generation requests with SSE streaming
+// newClaudeClient creates the client used by the handler (replaced in tests)
+var newClaudeClient = NewClaudeClient
+
+// generationTimeout bounds a debate generation, which outlives the request
+const generationTimeout = 5 * time.Minute
+
+// checkpointInterval is how often an in-progress debate is saved
+const checkpointInterval = 5 * time.Second
+
+// handleGenerateDebateImpl handles debate generation requests with SSE streaming.
+// GET requests resume the stream of a debate with Last-Event-ID.
 func handleGenerateDebateImpl(w http.ResponseWriter, r *http.Request) {
-	// Enable CORS - allow configured origin or localhost for dev
-	allowedOrigin := os.Getenv("ALLOWED_ORIGIN")
-	if allowedOrigin == "" {
-		allowedOrigin = "http://localhost:3000"
-	}
-	w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
-	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-	w.Header().Set("Access-Control-Expose-Headers", "X-Debate-Id")
+	setCORSHeaders(w)
 
 	if r.Method == "OPTIONS" {
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 
+	if r.Method == "GET" {
+		handleResumeDebate(w, r)
+		return
+	}
+
 	if r.Method != "POST" {
 		sendError(w, "Method not allowed", ErrInvalidRequest, false, http.StatusMethodNotAllowed)
 		return
@@ -50,60 +60,118 @@ func handleGenerateDebateImpl(w http.ResponseWriter, r *http.Request) {
 	debateID := uuid.New().String()
 	log.Printf("Generated debate ID: %s", debateID)
 
-	// Initialize Firestore (if not already initialized)
+	// Initialize debate store (if not already initialized)
 	ctx := r.Context()
-	if firebase.GetClient() == nil {
-		if err := firebase.InitFirestore(ctx); err != nil {
-			log.Printf("Failed to initialize Firestore (non-blocking): %v", err)
-			// Continue anyway - debate will work, just won't be saved
-		}
+	debateStore, err := store.Default(ctx)
+	if err != nil {
+		log.Printf("Failed to initialize debate store (non-blocking): %v", err)
+		// Continue anyway - debate will work, just won't be saved
 	}
 
 	// Create Claude client
-	claudeClient, err := NewClaudeClient()
+	claudeClient, err := newClaudeClient()
 	if err != nil {
 		log.Printf("Failed to create Claude client: %v", err)
 		sendError(w, "Service configuration error", ErrInternalError, true, http.StatusInternalServerError)
 		return
 	}
 
+	// Ground the panelists in their own writings, when a corpus is available
+	req.passages = retrievePassages(ctx, &req)
+
+	// Create accumulator for debate messages
+	accumulator := NewDebateAccumulator(debateID, req.Topic, req.SelectedPanelists)
+	accumulator.Format = debateFormat(&req).ID
+	accumulator.Style = req.style()
+	accumulator.Language = req.Language
+	accumulator.Citations = req.Citations
+	accumulator.Passages = req.passages
+
+	// Stream the debate
+	serveGeneration(w, r, debateStore, accumulator, r.Header.Get("User-Agent"), func(ctx context.Context, emitter ChunkEmitter) error {
+		if err := claudeClient.GenerateDebate(ctx, &req, emitter); err != nil || !req.Judge {
+			return err
+		}
+		claudeClient.judgeDebate(ctx, &req, accumulator)
+		return nil
+	})
+}
+
+// generateFunc generates debate chunks into emitter
+type generateFunc func(ctx context.Context, emitter ChunkEmitter) error
+
+// serveGeneration runs generate in the background and streams its events to the
+// client. Generation outlives the request so the debate completes and is saved
+// even if the client disconnects; clients can resume from the event log.
+func serveGeneration(w http.ResponseWriter, r *http.Request, debateStore store.DebateStore, accumulator *DebateAccumulator, userAgent string, generate generateFunc) {
+	debateID := accumulator.DebateID
+
 	// Set up Server-Sent Events headers
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx buffering
-	w.Header().Set("X-Debate-Id", debateID)   // Send debate ID to frontend
+	sse.SetHeaders(w)
+	w.Header().Set("X-Debate-Id", debateID) // Send debate ID to frontend
+	w.WriteHeader(http.StatusOK)
 
 	// Flush headers
 	if flusher, ok := w.(http.Flusher); ok {
 		flusher.Flush()
 	}
 
-	// Create accumulator for debate messages
-	accumulator := NewDebateAccumulator(debateID, req.Topic, req.SelectedPanelists)
-
-	// Wrap writer to accumulate messages
-	wrappedWriter := &AccumulatingWriter{
-		writer:      w,
-		accumulator: accumulator,
+	eventLog := debateEvents.Create(debateID)
+	recorder := newDebateRecorder(debateStore, accumulator, userAgent)
+	genCtx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), generationTimeout)
+	go func() {
+		defer cancel()
+		runGeneration(genCtx, generate, eventLog, recorder, accumulator)
+	}()
+
+	// Stream events to this client as they are generated
+	if err := eventLog.Replay(r.Context(), w, 0); err != nil {
+		log.Printf("Client left debate %s, generation continues: %v", debateID, err)
 	}
+}
+
+// runGeneration generates a debate into its event log, saving it as it goes
+func runGeneration(ctx context.Context, generate generateFunc, eventLog *sse.Log, recorder *debateRecorder, accumulator *DebateAccumulator) {
+	// Save the debate as soon as generation starts
+	recorder.Save(ctx, firebase.StatusInProgress, nil)
+	stopCheckpoints := recorder.StartCheckpoints(checkpointInterval)
 
 	// Stream the debate
-	if err := claudeClient.GenerateDebate(ctx, &req, wrappedWriter); err != nil {
+	emitter := eventLogEmitter{log: eventLog}
+	err := generate(ctx, &AccumulatingEmitter{
+		next:        emitter,
+		accumulator: accumulator,
+	})
+	status := finalStatus(ctx, err)
+	if status == firebase.StatusAwaitingHuman {
+		// Not a failure: the human panelist replies with a new request
+		log.Printf("Debate %s paused for the human panelist", accumulator.DebateID)
+		err = nil
+	} else if err != nil {
 		log.Printf("Error generating debate: %v", err)
-		errorChunk := StreamChunk{
+		emitter.Emit(StreamChunk{
 			Type:  "error",
 			Error: "Failed to generate debate. Please try again.",
-		}
-		json.NewEncoder(w).Encode(errorChunk)
-		if flusher, ok := w.(http.Flusher); ok {
-			flusher.Flush()
-		}
-		return
+		})
 	}
+	eventLog.Close()
 
-	// Save completed debate to Firestore (non-blocking)
-	go saveDebateToFirestore(context.Background(), accumulator, r.Header.Get("User-Agent"))
+	// Finalize the debate, even if generation was cut short by the timeout
+	stopCheckpoints()
+	recorder.Save(context.WithoutCancel(ctx), status, err)
+}
+
+// setCORSHeaders allows the configured origin (or localhost for dev) to
+// stream and resume debates
+func setCORSHeaders(w http.ResponseWriter) {
+	allowedOrigin := os.Getenv("ALLOWED_ORIGIN")
+	if allowedOrigin == "" {
+		allowedOrigin = "http://localhost:3000"
+	}
+	w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Last-Event-ID")
+	w.Header().Set("Access-Control-Expose-Headers", "X-Debate-Id")
 }
 
 // sendError sends a JSON error response
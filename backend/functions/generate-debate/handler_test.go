@@ -0,0 +1,222 @@
+package generatedebate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/llm"
+	"github.com/raphink/debate/shared/sse"
+	"github.com/raphink/debate/shared/store"
+)
+
+// useFakes replaces the language model and debate store for the duration of a test
+func useFakes(t *testing.T, model llm.LanguageModel) *store.MemoryStore {
+	t.Helper()
+
+	memStore := store.NewMemoryStore()
+	store.SetDefault(memStore)
+
+	previous := newClaudeClient
+	newClaudeClient = func() (*ClaudeClient, error) {
+		return NewClaudeClientWithModel(model), nil
+	}
+
+	t.Cleanup(func() {
+		newClaudeClient = previous
+		store.SetDefault(nil)
+	})
+
+	return memStore
+}
+
+// waitForDebate polls the store until the debate is saved with a final status
+func waitForDebate(t *testing.T, s store.DebateStore, id string) *firebase.DebateDocument {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if debate, err := s.GetDebate(context.Background(), id); err == nil && debate.Status != firebase.StatusInProgress {
+			return debate
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("debate %s was not finalized", id)
+	return nil
+}
+
+// decodeChunks parses the server-sent events written by the handler and
+// checks that their IDs increase
+func decodeChunks(t *testing.T, output string) []StreamChunk {
+	t.Helper()
+
+	events, err := sse.ReadEvents(strings.NewReader(output))
+	if err != nil {
+		t.Fatalf("invalid event stream: %v", err)
+	}
+
+	var chunks []StreamChunk
+	for i, event := range events {
+		if i > 0 && event.ID <= events[i-1].ID {
+			t.Errorf("event ID %d after %d, want increasing IDs", event.ID, events[i-1].ID)
+		}
+
+		var chunk StreamChunk
+		if err := json.Unmarshal(event.Data, &chunk); err != nil {
+			t.Fatalf("invalid chunk %q: %v", event.Data, err)
+		}
+		if event.Event != chunk.Type {
+			t.Errorf("event name %q for chunk type %q", event.Event, chunk.Type)
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+const testDebateBody = `{
+	"topic": "Should Christians defy unjust laws?",
+	"selectedPanelists": [
+		{"id": "augustine", "name": "Augustine of Hippo", "avatarUrl": "augustine.jpg"},
+		{"id": "mlk", "name": "Martin Luther King Jr.", "avatarUrl": "mlk.jpg"}
+	]
+}`
+
+func TestHandleGenerateDebate(t *testing.T) {
+	memStore := useFakes(t, llm.NewFake(
+		"[moderator]: Welcome to ",
+		"our debate.\n[augus",
+		"tine]: An unjust law is no law at all.\n",
+		"[mlk]: I agree.\n[moderator]: Thank you both.",
+	))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(testDebateBody))
+	rec := httptest.NewRecorder()
+	HandleGenerateDebate(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+
+	debateID := rec.Header().Get("X-Debate-Id")
+	if debateID == "" {
+		t.Fatal("X-Debate-Id header not set")
+	}
+
+	chunks := decodeChunks(t, rec.Body.String())
+	assertMessages(t, mergeMessages(chunks), []StreamChunk{
+		{PanelistID: "moderator", Text: "Welcome to our debate."},
+		{PanelistID: "augustine", Text: "An unjust law is no law at all."},
+		{PanelistID: "mlk", Text: "I agree."},
+		{PanelistID: "moderator", Text: "Thank you both."},
+	})
+
+	debate := waitForDebate(t, memStore, debateID)
+	if debate.Status != "complete" {
+		t.Errorf("saved status = %q, want complete", debate.Status)
+	}
+	if len(debate.Messages) != 4 {
+		t.Fatalf("saved %d messages, want 4", len(debate.Messages))
+	}
+	if debate.Messages[1].PanelistName != "Augustine of Hippo" || debate.Messages[1].AvatarURL != "augustine.jpg" {
+		t.Errorf("saved message = %+v, want Augustine's name and avatar", debate.Messages[1])
+	}
+	if debate.Messages[3].Text != "Thank you both." {
+		t.Errorf("saved closing text = %q", debate.Messages[3].Text)
+	}
+}
+
+func TestHandleGenerateDebateInvalidRequest(t *testing.T) {
+	useFakes(t, llm.NewFake())
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"topic": "short"}`))
+	rec := httptest.NewRecorder()
+	HandleGenerateDebate(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestHandleGenerateDebateResume(t *testing.T) {
+	useFakes(t, llm.NewFake("[moderator]: Welcome.\n", "[augustine]: Peace.\n", "[mlk]: Amen."))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(testDebateBody))
+	rec := httptest.NewRecorder()
+	HandleGenerateDebate(rec, req)
+
+	debateID := rec.Header().Get("X-Debate-Id")
+	all := decodeChunks(t, rec.Body.String())
+
+	// Reconnect after the second event
+	resume := httptest.NewRequest(http.MethodGet, "/?debateId="+debateID, nil)
+	resume.Header.Set("Last-Event-ID", "2")
+	resumed := httptest.NewRecorder()
+	HandleGenerateDebate(resumed, resume)
+
+	if resumed.Code != http.StatusOK {
+		t.Fatalf("resume status = %d, want 200: %s", resumed.Code, resumed.Body.String())
+	}
+
+	events, _ := sse.ReadEvents(resumed.Body)
+	if len(events) != len(all)-2 || events[0].ID != 3 {
+		t.Fatalf("resumed %d events starting at %d, want %d starting at 3", len(events), events[0].ID, len(all)-2)
+	}
+	if events[len(events)-1].Event != "done" {
+		t.Errorf("last resumed event = %q, want done", events[len(events)-1].Event)
+	}
+}
+
+func TestHandleGenerateDebateResumeUnknown(t *testing.T) {
+	useFakes(t, llm.NewFake())
+
+	req := httptest.NewRequest(http.MethodGet, "/?debateId=unknown", nil)
+	rec := httptest.NewRecorder()
+	HandleGenerateDebate(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+}
+
+func TestHandleGenerateDebateClientDisconnect(t *testing.T) {
+	memStore := useFakes(t, llm.NewFake("[moderator]: Welcome.\n", "[augustine]: Peace.\n", "[moderator]: Farewell."))
+
+	// The client is gone before anything is streamed
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(testDebateBody)).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	HandleGenerateDebate(rec, req)
+
+	// Generation carries on and the debate is still saved
+	debate := waitForDebate(t, memStore, rec.Header().Get("X-Debate-Id"))
+	if len(debate.Messages) != 3 {
+		t.Errorf("saved %d messages, want 3", len(debate.Messages))
+	}
+}
+
+func TestHandleGenerateDebateSavesFailedDebate(t *testing.T) {
+	memStore := useFakes(t, llm.NewFakeScripts(llm.Script{
+		Deltas: []string{"[moderator]: Welcome.\n", "[augustine]: Peace"},
+		Err:    errors.New("overloaded"),
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(testDebateBody))
+	rec := httptest.NewRecorder()
+	HandleGenerateDebate(rec, req)
+
+	debate := waitForDebate(t, memStore, rec.Header().Get("X-Debate-Id"))
+	if debate.Status != firebase.StatusFailed || !strings.Contains(debate.Error, "overloaded") {
+		t.Errorf("saved status = %q with error %q, want failed with the model error", debate.Status, debate.Error)
+	}
+	if len(debate.Messages) != 2 {
+		t.Errorf("saved %d partial messages, want 2", len(debate.Messages))
+	}
+}
@@ -0,0 +1,162 @@
+package generatedebate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/raphink/debate/shared/firebase"
+)
+
+// errHumanTurn stops generation when it is the human panelist's turn to speak
+var errHumanTurn = errors.New("waiting for the human panelist")
+
+// humanPanelistID returns the ID of the panelist played by the user, if any
+func humanPanelistID(panelists []Panelist) string {
+	for _, p := range panelists {
+		if p.Human {
+			return p.ID
+		}
+	}
+	return ""
+}
+
+// writeHumanTurns tells the model to stop when the human panelist of req is
+// given the floor, instead of writing their message
+func writeHumanTurns(prompt *strings.Builder, req *DebateRequest, structured bool) {
+	for _, p := range req.SelectedPanelists {
+		if !p.Human {
+			continue
+		}
+
+		turn := fmt.Sprintf("[%s]: ...", p.ID)
+		if structured {
+			turn = fmt.Sprintf(`{"speaker":"%s","text":"..."}`, p.ID)
+		}
+
+		prompt.WriteString("HUMAN PANELIST:\n")
+		prompt.WriteString(fmt.Sprintf("- %s (ID: %s) is a real person taking part in the debate\n", p.Name, p.ID))
+		prompt.WriteString(fmt.Sprintf("- When it is their turn to speak, write exactly %s and stop: NEVER write their arguments\n", turn))
+		prompt.WriteString("- The moderator and panelists respond directly to their messages, engaging with their actual arguments\n")
+		prompt.WriteString("- Give them the floor regularly, at least every 3-4 exchanges\n\n")
+	}
+}
+
+// humanTurnEmitter stops generation at the human panelist's turn. The model
+// only opens the turn: it is replaced with a turn chunk and the rest of the
+// response is dropped.
+type humanTurnEmitter struct {
+	next    ChunkEmitter
+	humanID string
+	cancel  context.CancelFunc
+	paused  bool
+}
+
+// Emit forwards chunks until the human panelist is given the floor
+func (e *humanTurnEmitter) Emit(chunk StreamChunk) {
+	if e.paused {
+		return
+	}
+
+	if chunk.Type == "message" && chunk.PanelistID == e.humanID {
+		e.paused = true
+		e.cancel()
+		e.next.Emit(StreamChunk{Type: "turn", PanelistID: e.humanID})
+		return
+	}
+
+	e.next.Emit(chunk)
+}
+
+// handleReplyDebateImpl takes the message of the human panelist of a paused
+// debate and streams the next exchanges in response, until the human's next
+// turn or the end of the debate.
+// GET requests resume the stream with Last-Event-ID.
+func handleReplyDebateImpl(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method == "GET" {
+		handleResumeDebate(w, r)
+		return
+	}
+
+	if r.Method != "POST" {
+		sendError(w, "Method not allowed", ErrInvalidRequest, false, http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse request body
+	var req ReplyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, "Invalid request body", ErrInvalidRequest, false, http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	if err := ValidateReplyRequest(&req); err != nil {
+		sendError(w, err.Error(), ErrInvalidRequest, false, http.StatusBadRequest)
+		return
+	}
+
+	debateStore, doc, ok := loadDebate(r.Context(), w, req.DebateID)
+	if !ok {
+		return
+	}
+
+	if doc.Status != firebase.StatusAwaitingHuman {
+		sendError(w, "It is not the human panelist's turn", ErrNotHumanTurn, false, http.StatusConflict)
+		return
+	}
+
+	debateReq := debateRequestFromDocument(doc, req.OutputMode)
+	humanID := humanPanelistID(debateReq.SelectedPanelists)
+	if humanID == "" {
+		sendError(w, "Debate has no human panelist", ErrInvalidRequest, false, http.StatusBadRequest)
+		return
+	}
+
+	// Create Claude client
+	claudeClient, err := newClaudeClient()
+	if err != nil {
+		log.Printf("Failed to create Claude client: %v", err)
+		sendError(w, "Service configuration error", ErrInternalError, true, http.StatusInternalServerError)
+		return
+	}
+
+	// Claim the debate, as the human panelist may have replied twice
+	doc, ok = claimDebate(r.Context(), w, debateStore, doc.ID, true)
+	if !ok {
+		return
+	}
+
+	log.Printf("Human panelist %s replied in debate %s", humanID, doc.ID)
+
+	accumulator := NewDebateAccumulatorFromDocument(doc)
+
+	// The reply ends the transcript the model responds to
+	transcript := append(append([]firebase.Message(nil), doc.Messages...), firebase.Message{
+		PanelistID:  humanID,
+		Text:        req.Text,
+		SpeakerType: firebase.SpeakerHuman,
+	})
+	opts := ContinueOptions{
+		Exchanges:    req.Exchanges,
+		Instructions: []string{fmt.Sprintf("The last message is from %s, the human panelist: the moderator and panelists respond to it directly", humanID)},
+	}
+
+	// Keep the user agent of the original debate
+	serveGeneration(w, r, debateStore, accumulator, doc.Metadata.UserAgent, func(ctx context.Context, emitter ChunkEmitter) error {
+		// Stream and store the reply before the responses
+		emitter.Emit(StreamChunk{Type: "message", PanelistID: humanID, Text: req.Text})
+		return claudeClient.ContinueDebate(ctx, debateReq, transcript, opts, emitter)
+	})
+}
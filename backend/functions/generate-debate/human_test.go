@@ -0,0 +1,169 @@
+package generatedebate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/llm"
+	"github.com/raphink/debate/shared/store"
+)
+
+// saveHumanDebate stores a debate paused for the human panelist "you"
+func saveHumanDebate(t *testing.T, s store.DebateStore, id, status string) {
+	t.Helper()
+
+	err := s.SaveDebate(context.Background(), id, &firebase.DebateDocument{
+		ID:    id,
+		Topic: firebase.Topic{Text: "Is war ever just?", IsRelevant: true},
+		Panelists: []firebase.Panelist{
+			{ID: "augustine", Name: "Augustine of Hippo"},
+			{ID: "you", Name: "Raphael", Human: true},
+		},
+		Messages: []firebase.Message{
+			{ID: "moderator-0", PanelistID: "moderator", Text: "Raphael, your opening?", Sequence: 0, IsComplete: true},
+		},
+		Status:    status,
+		StartedAt: time.Now().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("SaveDebate() error = %v", err)
+	}
+}
+
+func TestHandleGenerateDebatePausesForHuman(t *testing.T) {
+	fake := llm.NewFake(
+		"[moderator]: Welcome. Raphael, your opening?\n",
+		"[you]: ...\n",
+		"[augustine]: Let me answer for him.",
+	)
+	memStore := useFakes(t, fake)
+
+	body := `{"topic": "Is war ever just?", "selectedPanelists": [
+		{"id": "augustine", "name": "Augustine of Hippo"},
+		{"id": "you", "name": "Raphael", "human": true}
+	]}`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	HandleGenerateDebate(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+
+	// The stream stops with the human's turn
+	chunks := decodeChunks(t, rec.Body.String())
+	if last := chunks[len(chunks)-1]; last.Type != "turn" || last.PanelistID != "you" {
+		t.Errorf("last chunk = %+v, want the human's turn", last)
+	}
+	assertMessages(t, mergeMessages(chunks), []StreamChunk{
+		{PanelistID: "moderator", Text: "Welcome. Raphael, your opening?"},
+	})
+
+	prompt := fake.Requests()[0].Messages[0].Text
+	if !strings.Contains(prompt, "Raphael (ID: you) is a real person") || !strings.Contains(prompt, "write exactly [you]: ...") {
+		t.Errorf("prompt does not describe the human panelist:\n%s", prompt)
+	}
+
+	debate := waitForDebate(t, memStore, rec.Header().Get("X-Debate-Id"))
+	if debate.Status != firebase.StatusAwaitingHuman || debate.Error != "" || !debate.CompletedAt.IsZero() {
+		t.Errorf("saved debate = %q (%q) completed at %v, want awaiting_human", debate.Status, debate.Error, debate.CompletedAt)
+	}
+	if len(debate.Messages) != 1 || !debate.Panelists[1].Human {
+		t.Errorf("saved debate = %+v, want the moderator's message and the human panelist", debate)
+	}
+}
+
+func TestHandleReplyDebate(t *testing.T) {
+	fake := llm.NewFake("[augustine]: Peace is the aim of war.\n", "[moderator]: Thank you both.")
+	memStore := useFakes(t, fake)
+	saveHumanDebate(t, memStore, "d1", firebase.StatusAwaitingHuman)
+
+	body := `{"debateId": "d1", "text": "War is <script>alert(1)</script>never just."}`
+	req := httptest.NewRequest(http.MethodPost, "/reply-debate", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	HandleReplyDebate(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	assertMessages(t, mergeMessages(decodeChunks(t, rec.Body.String())), []StreamChunk{
+		{PanelistID: "you", Text: "War is never just."},
+		{PanelistID: "augustine", Text: "Peace is the aim of war."},
+		{PanelistID: "moderator", Text: "Thank you both."},
+	})
+
+	// The model responds to the sanitized reply
+	messages := fake.Requests()[0].Messages
+	if !strings.HasSuffix(messages[1].Text, "[you]: War is never just.") {
+		t.Errorf("transcript does not end with the reply:\n%s", messages[1].Text)
+	}
+
+	debate := waitForDebate(t, memStore, "d1")
+	if debate.Status != firebase.StatusComplete || len(debate.Messages) != 4 {
+		t.Fatalf("saved debate = %q with %d messages, want complete with 4", debate.Status, len(debate.Messages))
+	}
+	if reply := debate.Messages[1]; reply.SpeakerType != firebase.SpeakerHuman || reply.PanelistName != "Raphael" || reply.Sequence != 1 {
+		t.Errorf("saved reply = %+v, want Raphael's message at sequence 1", reply)
+	}
+}
+
+func TestHandleReplyDebateErrors(t *testing.T) {
+	memStore := useFakes(t, llm.NewFake())
+	saveHumanDebate(t, memStore, "paused", firebase.StatusAwaitingHuman)
+	saveHumanDebate(t, memStore, "done", firebase.StatusComplete)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "empty reply", body: `{"debateId": "paused", "text": "  "}`, want: http.StatusBadRequest},
+		{name: "unknown debate", body: `{"debateId": "missing", "text": "Hello"}`, want: http.StatusNotFound},
+		{name: "not the human's turn", body: `{"debateId": "done", "text": "Hello"}`, want: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/reply-debate", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			HandleReplyDebate(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestClaimDebateHumanTurn(t *testing.T) {
+	memStore := store.NewMemoryStore()
+	saveHumanDebate(t, memStore, "d1", firebase.StatusAwaitingHuman)
+
+	// The human's turn is taken by the first reply only
+	if _, ok := claimDebate(context.Background(), httptest.NewRecorder(), memStore, "d1", true); !ok {
+		t.Fatal("claimDebate() failed on the human's turn")
+	}
+	rec := httptest.NewRecorder()
+	if _, ok := claimDebate(context.Background(), rec, memStore, "d1", true); ok || rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), ErrNotHumanTurn) {
+		t.Errorf("second claimDebate() = %v with %d %s, want a conflict", ok, rec.Code, rec.Body.String())
+	}
+}
+
+func TestValidateDebateRequestSingleHuman(t *testing.T) {
+	req := &DebateRequest{
+		Topic: "Is war ever just?",
+		SelectedPanelists: []Panelist{
+			{ID: "you", Name: "Raphael", Human: true},
+			{ID: "friend", Name: "Friend", Human: true},
+		},
+	}
+
+	if err := ValidateDebateRequest(req); err == nil {
+		t.Error("ValidateDebateRequest() accepted two human panelists")
+	}
+}
@@ -0,0 +1,101 @@
+package generatedebate
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/raphink/debate/shared/firebase"
+)
+
+// handleInterjectDebateImpl takes an audience question or a question for the
+// moderator between the turns of a saved debate, and streams the next
+// exchanges in response. The debate is left open for further interjections.
+// GET requests resume the stream with Last-Event-ID.
+func handleInterjectDebateImpl(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method == "GET" {
+		handleResumeDebate(w, r)
+		return
+	}
+
+	if r.Method != "POST" {
+		sendError(w, "Method not allowed", ErrInvalidRequest, false, http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse request body
+	var req InterjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, "Invalid request body", ErrInvalidRequest, false, http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	if err := ValidateInterjectRequest(&req); err != nil {
+		sendError(w, err.Error(), ErrInvalidRequest, false, http.StatusBadRequest)
+		return
+	}
+
+	debateStore, doc, ok := loadDebate(r.Context(), w, req.DebateID)
+	if !ok {
+		return
+	}
+
+	if doc.Status == firebase.StatusInProgress {
+		sendError(w, "Debate is still being generated", ErrDebateInProgress, true, http.StatusConflict)
+		return
+	}
+
+	// Create Claude client
+	claudeClient, err := newClaudeClient()
+	if err != nil {
+		log.Printf("Failed to create Claude client: %v", err)
+		sendError(w, "Service configuration error", ErrInternalError, true, http.StatusInternalServerError)
+		return
+	}
+
+	// Claim the debate, as another request may have started extending it
+	doc, ok = claimDebate(r.Context(), w, debateStore, doc.ID, false)
+	if !ok {
+		return
+	}
+
+	log.Printf("Interjection (%s) in debate %s", req.Kind, doc.ID)
+
+	debateReq := debateRequestFromDocument(doc, req.OutputMode)
+	accumulator := NewDebateAccumulatorFromDocument(doc)
+
+	transcript := doc.Messages
+	opts := ContinueOptions{Exchanges: req.Exchanges, Open: true}
+	if req.Kind == InterjectionAudience {
+		// The question is part of the debate, as the last message before the answers
+		transcript = append(append([]firebase.Message(nil), doc.Messages...), firebase.Message{
+			PanelistID:  "audience",
+			Text:        req.Text,
+			SpeakerType: firebase.SpeakerAudience,
+		})
+		opts.Instructions = []string{
+			"The last message is a question from the audience: the moderator relays it and the panelists answer it",
+			"Never write messages for the audience",
+		}
+	} else {
+		opts.Instructions = []string{moderatorQuestion(req.Text)}
+	}
+
+	// Keep the user agent of the original debate
+	serveGeneration(w, r, debateStore, accumulator, doc.Metadata.UserAgent, func(ctx context.Context, emitter ChunkEmitter) error {
+		if req.Kind == InterjectionAudience {
+			// Stream and store the question before the answers
+			emitter.Emit(StreamChunk{Type: "message", PanelistID: "audience", Text: req.Text})
+		}
+		return claudeClient.ContinueDebate(ctx, debateReq, transcript, opts, emitter)
+	})
+}
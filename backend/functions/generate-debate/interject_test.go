@@ -0,0 +1,122 @@
+package generatedebate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/llm"
+)
+
+func TestHandleInterjectDebateAudience(t *testing.T) {
+	fake := llm.NewFake("[moderator]: A question from the floor.\n", "[mlk]: We must obey God rather than men.\n", "[moderator]: Any other questions?")
+	memStore := useFakes(t, fake)
+	saveTestDebate(t, memStore, "d1", firebase.StatusComplete)
+
+	body := `{"debateId": "d1", "text": "What about <i>civil</i> disobedience?"}`
+	req := httptest.NewRequest(http.MethodPost, "/interject-debate", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	HandleInterjectDebate(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+
+	// The question is streamed before the answers
+	chunks := mergeMessages(decodeChunks(t, rec.Body.String()))
+	if len(chunks) != 4 || chunks[0].PanelistID != "audience" || chunks[0].Text != "What about civil disobedience?" {
+		t.Fatalf("streamed messages = %+v, want the audience question first", chunks)
+	}
+
+	// The question ends the transcript and the debate is left open
+	messages := fake.Requests()[0].Messages
+	if !strings.HasSuffix(messages[1].Text, "[audience]: What about civil disobedience?") {
+		t.Errorf("transcript does not end with the question:\n%s", messages[1].Text)
+	}
+	if !strings.Contains(messages[2].Text, "3 more exchanges") || !strings.Contains(messages[2].Text, "without concluding") {
+		t.Errorf("continuation prompt = %q", messages[2].Text)
+	}
+
+	debate := waitForDebate(t, memStore, "d1")
+	if len(debate.Messages) != 6 {
+		t.Fatalf("saved %d messages, want 6", len(debate.Messages))
+	}
+	wantTypes := []string{"", "", firebase.SpeakerAudience, firebase.SpeakerModerator, firebase.SpeakerPanelist, firebase.SpeakerModerator}
+	for i, msg := range debate.Messages {
+		if msg.Sequence != i || msg.SpeakerType != wantTypes[i] {
+			t.Errorf("message %d = sequence %d by %q, want %q", i, msg.Sequence, msg.SpeakerType, wantTypes[i])
+		}
+	}
+	if audience := debate.Messages[2]; audience.PanelistName != "Audience" || audience.ID != "audience-2" {
+		t.Errorf("audience message = %+v", audience)
+	}
+}
+
+func TestHandleInterjectDebateModerator(t *testing.T) {
+	fake := llm.NewFake("[moderator]: What is a just law?\n", "[augustine]: One that agrees with the eternal law.\n", "[moderator]: Thank you.")
+	memStore := useFakes(t, fake)
+	saveTestDebate(t, memStore, "d1", firebase.StatusComplete)
+
+	body := `{"debateId": "d1", "kind": "moderator", "text": "What is a just law?", "exchanges": 1}`
+	req := httptest.NewRequest(http.MethodPost, "/interject-debate", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	HandleInterjectDebate(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+
+	continuation := fake.Requests()[0].Messages[2].Text
+	if !strings.Contains(continuation, `moderator asking the panel: "What is a just law?"`) {
+		t.Errorf("continuation prompt does not ask the question:\n%s", continuation)
+	}
+
+	// Only the generated messages are stored
+	debate := waitForDebate(t, memStore, "d1")
+	if len(debate.Messages) != 5 || debate.Messages[2].PanelistID != "moderator" {
+		t.Errorf("saved messages = %+v, want the moderator's question then the answer", debate.Messages)
+	}
+}
+
+func TestHandleInterjectDebateErrors(t *testing.T) {
+	memStore := useFakes(t, llm.NewFake())
+	saveTestDebate(t, memStore, "running", firebase.StatusInProgress)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "missing text", body: `{"debateId": "running", "text": "<b></b>"}`, want: http.StatusBadRequest},
+		{name: "unknown kind", body: `{"debateId": "running", "kind": "panelist", "text": "Hello?"}`, want: http.StatusBadRequest},
+		{name: "unknown debate", body: `{"debateId": "missing", "text": "Hello?"}`, want: http.StatusNotFound},
+		{name: "debate in progress", body: `{"debateId": "running", "text": "Hello?"}`, want: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/interject-debate", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			HandleInterjectDebate(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestBuildDebatePromptInteractive(t *testing.T) {
+	client := NewClaudeClientWithModel(llm.NewFake())
+	req := &DebateRequest{Topic: "Is war ever just?", Interactive: true}
+
+	prompt := client.buildDebatePrompt(req)
+	if !strings.Contains(prompt, "4-6 exchanges") || !strings.Contains(prompt, "inviting the audience") {
+		t.Errorf("interactive prompt does not stop for the audience:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "concluding summary") {
+		t.Errorf("interactive prompt asks for a conclusion:\n%s", prompt)
+	}
+}
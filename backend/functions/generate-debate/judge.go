@@ -0,0 +1,138 @@
+package generatedebate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/language"
+	"github.com/raphink/debate/shared/llm"
+)
+
+// judgeMaxTokens bounds the judgement of a debate
+const judgeMaxTokens = 2048
+
+// Range of the scores of a verdict
+const (
+	minScore = 1
+	maxScore = 10
+)
+
+// judgeDebate runs the judging pass over the debate accumulated in acc and
+// records the judgement. The debate stays complete if judging fails.
+func (c *ClaudeClient) judgeDebate(ctx context.Context, req *DebateRequest, acc *DebateAccumulator) {
+	judgement, err := c.JudgeDebate(ctx, req, acc.Document(firebase.StatusComplete, "").Messages)
+	if err != nil {
+		log.Printf("Failed to judge debate %s: %v", acc.DebateID, err)
+		return
+	}
+	acc.SetJudgement(judgement)
+}
+
+// JudgeDebate asks the model for a verdict on each panelist of the debate
+// with the given transcript, and an overall summary
+func (c *ClaudeClient) JudgeDebate(ctx context.Context, req *DebateRequest, transcript []firebase.Message) (*firebase.Judgement, error) {
+	stream := c.model.Stream(ctx, llm.UserPrompt(c.buildJudgePrompt(req, transcript), judgeMaxTokens))
+	defer stream.Close()
+
+	var answer strings.Builder
+	for stream.Next() {
+		answer.WriteString(stream.Current())
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("stream error: %w", err)
+	}
+
+	object, err := jsonObject(answer.String())
+	if err != nil {
+		return nil, err
+	}
+	var judgement firebase.Judgement
+	if err := json.Unmarshal([]byte(object), &judgement); err != nil {
+		return nil, fmt.Errorf("invalid judgement: %w", err)
+	}
+
+	// Keep one verdict per panelist, with scores in range
+	verdicts := make([]firebase.Verdict, 0, len(judgement.Verdicts))
+	for _, verdict := range judgement.Verdicts {
+		if !isJudged(req.SelectedPanelists, verdict.PanelistID) || hasVerdict(verdicts, verdict.PanelistID) {
+			continue
+		}
+		verdict.ArgumentStrength = clampScore(verdict.ArgumentStrength)
+		verdict.Evidence = clampScore(verdict.Evidence)
+		verdict.Responsiveness = clampScore(verdict.Responsiveness)
+		verdict.Fidelity = clampScore(verdict.Fidelity)
+		verdicts = append(verdicts, verdict)
+	}
+	if len(verdicts) == 0 {
+		return nil, errors.New("judgement has no verdict on the panelists")
+	}
+
+	judgement.Verdicts = verdicts
+	judgement.JudgedAt = time.Now()
+	return &judgement, nil
+}
+
+// buildJudgePrompt creates the prompt asking for a judgement of transcript
+func (c *ClaudeClient) buildJudgePrompt(req *DebateRequest, transcript []firebase.Message) string {
+	var prompt strings.Builder
+
+	prompt.WriteString("You are an impartial judge assessing a theological/philosophical debate between historical figures.\n\n")
+	prompt.WriteString(fmt.Sprintf("Topic: %s\n\n", req.Topic))
+	prompt.WriteString("Panelists:\n")
+	for i, panelist := range req.SelectedPanelists {
+		prompt.WriteString(fmt.Sprintf("%d. %s (ID: %s)\n", i+1, panelist.Name, panelist.ID))
+		if panelist.Position != "" {
+			prompt.WriteString(fmt.Sprintf("   Position: %s\n", panelist.Position))
+		}
+	}
+
+	prompt.WriteString("\nTranscript:\n")
+	prompt.WriteString(formatTranscript(transcript, OutputModeText))
+	prompt.WriteString("\n\n")
+
+	prompt.WriteString(fmt.Sprintf("Score each panelist, but not the moderator or the audience, from %d (poor) to %d (excellent) on:\n", minScore, maxScore))
+	prompt.WriteString("- argumentStrength: the logic and persuasiveness of their arguments\n")
+	prompt.WriteString("- evidence: their use of evidence, sources and examples\n")
+	prompt.WriteString("- responsiveness: how directly they engaged with the other panelists' points\n")
+	prompt.WriteString("- fidelity: how faithfully they represented their documented historical position\n")
+	prompt.WriteString("Add a comment on each panelist (1-2 sentences) and an overall summary of the debate (3-5 sentences) saying who argued most convincingly and why.\n")
+	if req.Language != "" && req.Language != language.Default {
+		prompt.WriteString(fmt.Sprintf("Write the comments and the summary in %s.\n", language.Name(req.Language)))
+	}
+	prompt.WriteString("\nReturn ONLY a JSON object, with no markdown or other text:\n")
+	prompt.WriteString(`{"verdicts":[{"panelistId":"ID","argumentStrength":7,"evidence":6,"responsiveness":8,"fidelity":9,"comment":"..."}],"summary":"..."}`)
+
+	return prompt.String()
+}
+
+// isJudged returns whether the panelist with the given ID is judged: the
+// user's contributions are not scored
+func isJudged(panelists []Panelist, panelistID string) bool {
+	for _, p := range panelists {
+		if p.ID == panelistID {
+			return !p.Human
+		}
+	}
+	return false
+}
+
+// hasVerdict returns whether verdicts include one on the panelist with the given ID
+func hasVerdict(verdicts []firebase.Verdict, panelistID string) bool {
+	for _, verdict := range verdicts {
+		if verdict.PanelistID == panelistID {
+			return true
+		}
+	}
+	return false
+}
+
+// clampScore brings score within the range of verdict scores
+func clampScore(score int) int {
+	return min(max(score, minScore), maxScore)
+}
@@ -0,0 +1,118 @@
+package generatedebate
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/llm"
+)
+
+func TestHandleGenerateDebateJudge(t *testing.T) {
+	fake := llm.NewFakeScripts(
+		llm.Script{Deltas: []string{"[moderator]: Welcome.\n[augustine]: An unjust law is no law at all.\n[mlk]: Justice too long delayed is justice denied.\n[moderator]: Thank you both."}},
+		llm.Script{Deltas: []string{"```json\n" + `{"verdicts":[` +
+			`{"panelistId":"augustine","argumentStrength":8,"evidence":12,"responsiveness":0,"fidelity":9,"comment":"Rigorous."},` +
+			`{"panelistId":"moderator","argumentStrength":5,"evidence":5,"responsiveness":5,"fidelity":5},` +
+			`{"panelistId":"mlk","argumentStrength":9,"evidence":7,"responsiveness":8,"fidelity":10,"comment":"Compelling."},` +
+			`{"panelistId":"mlk","argumentStrength":1,"evidence":1,"responsiveness":1,"fidelity":1}` +
+			`],"summary":"King argued most convincingly."}` + "\n```"}},
+	)
+	memStore := useFakes(t, fake)
+
+	body := strings.Replace(testDebateBody, `"topic"`, `"judge": true, "topic"`, 1)
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	HandleGenerateDebate(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+
+	// The judge reads the transcript of the generated debate
+	requests := fake.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("%d model requests, want the debate and its judgement", len(requests))
+	}
+	if prompt := requests[1].Messages[0].Text; !strings.Contains(prompt, "[mlk]: Justice too long delayed is justice denied.") {
+		t.Errorf("judge prompt does not contain the transcript:\n%s", prompt)
+	}
+
+	debate := waitForDebate(t, memStore, rec.Header().Get("X-Debate-Id"))
+	if debate.Status != firebase.StatusComplete {
+		t.Errorf("saved status = %q, want complete", debate.Status)
+	}
+	judgement := debate.Judgement
+	if judgement == nil {
+		t.Fatal("saved debate has no judgement")
+	}
+	if judgement.Summary != "King argued most convincingly." || judgement.JudgedAt.IsZero() {
+		t.Errorf("judgement = %+v", judgement)
+	}
+	if len(judgement.Verdicts) != 2 {
+		t.Fatalf("verdicts = %+v, want one per panelist", judgement.Verdicts)
+	}
+	augustine, mlk := judgement.Verdicts[0], judgement.Verdicts[1]
+	if augustine.PanelistID != "augustine" || augustine.Evidence != maxScore || augustine.Responsiveness != minScore {
+		t.Errorf("augustine verdict = %+v, want scores clamped", augustine)
+	}
+	if mlk.PanelistID != "mlk" || mlk.Fidelity != 10 || mlk.Comment != "Compelling." {
+		t.Errorf("mlk verdict = %+v, want the first verdict", mlk)
+	}
+}
+
+func TestHandleGenerateDebateJudgeFailure(t *testing.T) {
+	memStore := useFakes(t, llm.NewFakeScripts(
+		llm.Script{Deltas: []string{"[moderator]: Welcome.\n[augustine]: Peace.\n[moderator]: Thank you."}},
+		llm.Script{Err: errors.New("overloaded")},
+	))
+
+	body := strings.Replace(testDebateBody, `"topic"`, `"judge": true, "topic"`, 1)
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	HandleGenerateDebate(rec, req)
+
+	// The debate is complete without a judgement
+	debate := waitForDebate(t, memStore, rec.Header().Get("X-Debate-Id"))
+	if debate.Status != firebase.StatusComplete || debate.Judgement != nil {
+		t.Errorf("saved debate %q with judgement %+v, want complete without judgement", debate.Status, debate.Judgement)
+	}
+	for _, chunk := range decodeChunks(t, rec.Body.String()) {
+		if chunk.Type == "error" {
+			t.Errorf("judging failure streamed an error: %+v", chunk)
+		}
+	}
+}
+
+func TestJudgeDebateLanguage(t *testing.T) {
+	fake := llm.NewFake(`{"verdicts":[],"summary":"Rien à juger."}`)
+	client := NewClaudeClientWithModel(fake)
+
+	req := &DebateRequest{
+		Topic:             "La guerre peut-elle être juste ?",
+		SelectedPanelists: []Panelist{{ID: "augustine", Name: "Augustin"}},
+		Language:          "fr",
+	}
+	if _, err := client.JudgeDebate(context.Background(), req, nil); err == nil {
+		t.Error("JudgeDebate() accepted a judgement without verdicts")
+	}
+	if prompt := fake.Requests()[0].Messages[0].Text; !strings.Contains(prompt, "summary in French") {
+		t.Errorf("judge prompt does not ask for French:\n%s", prompt)
+	}
+}
+
+func TestValidateDebateRequestJudgeInteractive(t *testing.T) {
+	err := ValidateDebateRequest(&DebateRequest{
+		Topic:             "Is war ever just?",
+		SelectedPanelists: []Panelist{{ID: "a", Name: "A"}, {ID: "b", Name: "B"}},
+		Judge:             true,
+		Interactive:       true,
+	})
+	if err == nil {
+		t.Error("ValidateDebateRequest() accepted judging an interactive debate")
+	}
+}
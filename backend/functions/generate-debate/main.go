@@ -14,3 +14,45 @@ func HandleGenerateDebate(w http.ResponseWriter, r *http.Request) {
 	// Delegate to the existing handler
 	handleGenerateDebateImpl(w, r)
 }
+
+// HandleContinueDebate is the entry point for the continue-debate Cloud Function
+func HandleContinueDebate(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Continue debate request received: %s %s", r.Method, r.URL.Path)
+
+	handleContinueDebateImpl(w, r)
+}
+
+// HandleForkDebate is the entry point for the fork-debate Cloud Function
+func HandleForkDebate(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Fork debate request received: %s %s", r.Method, r.URL.Path)
+
+	handleForkDebateImpl(w, r)
+}
+
+// HandleInterjectDebate is the entry point for the interject-debate Cloud Function
+func HandleInterjectDebate(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Interject debate request received: %s %s", r.Method, r.URL.Path)
+
+	handleInterjectDebateImpl(w, r)
+}
+
+// HandleReplyDebate is the entry point for the reply-debate Cloud Function
+func HandleReplyDebate(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Reply debate request received: %s %s", r.Method, r.URL.Path)
+
+	handleReplyDebateImpl(w, r)
+}
+
+// HandleTranslateDebate is the entry point for the translate-debate Cloud Function
+func HandleTranslateDebate(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Translate debate request received: %s %s", r.Method, r.URL.Path)
+
+	handleTranslateDebateImpl(w, r)
+}
+
+// HandleCheckDebate is the entry point for the check-debate Cloud Function
+func HandleCheckDebate(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Check debate request received: %s %s", r.Method, r.URL.Path)
+
+	handleCheckDebateImpl(w, r)
+}
@@ -0,0 +1,141 @@
+package generatedebate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/language"
+)
+
+// Turn lengths
+const (
+	TurnLengthShort  = "short"
+	TurnLengthMedium = "medium" // Default
+	TurnLengthLong   = "long"
+)
+
+// turnLength describes how long panelist turns are
+type turnLength struct {
+	Description string // For the model
+	Tokens      int    // Upper estimate of the tokens of a turn
+}
+
+var turnLengths = map[string]turnLength{
+	TurnLengthShort:  {Description: "1-2 sentences (20-50 words)", Tokens: 80},
+	TurnLengthMedium: {Description: "2-4 sentences (50-100 words)", Tokens: 160},
+	TurnLengthLong:   {Description: "4-6 sentences (100-180 words)", Tokens: 300},
+}
+
+// Registers of language
+const (
+	RegisterAcademic = "academic"
+	RegisterPopular  = "popular"
+	RegisterTeen     = "teen"
+)
+
+var registers = map[string]string{
+	RegisterAcademic: "Use an academic register: precise terminology, named arguments and references to the panelists' own works",
+	RegisterPopular:  "Use a popular register: plain language and concrete examples, explaining any technical term",
+	RegisterTeen:     "Write for teenagers: short sentences, relatable everyday examples and no unexplained jargon",
+}
+
+// Tones of the debate
+const (
+	ToneCombative = "combative"
+	ToneIrenic    = "irenic"
+)
+
+var tones = map[string]string{
+	ToneCombative: "Make the debate combative: panelists press hard on each other's weaknesses and concede little, while staying courteous",
+	ToneIrenic:    "Make the debate irenic: panelists look for common ground, acknowledge good points and disagree charitably",
+}
+
+// Limits on the exchanges of a debate and on its token budget
+const (
+	MinDebateExchanges = 2
+	MaxDebateExchanges = 30
+
+	interactiveExchanges = 6 // Exchanges before an interactive debate waits for the audience
+	minDebateTokens      = 1024
+	maxDebateTokens      = 16384
+
+	// Tokens each turn takes beyond its text in the structured output mode:
+	// the JSON object, speaker and addresses, and escaping
+	structuredTurnTokens = 20
+	// Tokens each turn takes for its "citations" array: work, locator and quote
+	citationTurnTokens = 60
+)
+
+// turnLengthOf returns the turn length of req, falling back to medium
+func turnLengthOf(req *DebateRequest) turnLength {
+	if length, ok := turnLengths[req.TurnLength]; ok {
+		return length
+	}
+	return turnLengths[TurnLengthMedium]
+}
+
+// exchangesOf returns the number of exchanges to ask for: the requested
+// number, or a range from the debate format
+func exchangesOf(req *DebateRequest) string {
+	if req.Exchanges > 0 {
+		return fmt.Sprint(req.Exchanges)
+	}
+	format := debateFormat(req)
+	return fmt.Sprintf("%d-%d", format.Exchanges[0], format.Exchanges[1])
+}
+
+// maxExchanges returns the largest number of exchanges the model may generate for req
+func maxExchanges(req *DebateRequest) int {
+	switch {
+	case req.Interactive:
+		return interactiveExchanges
+	case req.Exchanges > 0:
+		return req.Exchanges
+	default:
+		return debateFormat(req).Exchanges[1]
+	}
+}
+
+// tokenBudget returns the max tokens needed for exchanges turns of req's
+// length and output mode, so that debates are not cut off mid-sentence
+func tokenBudget(req *DebateRequest, exchanges int) int {
+	// Panelist turns, plus the moderator's introduction, interventions and conclusion
+	turns := exchanges + exchanges/3 + 2
+	budget := turns * turnLengthOf(req).Tokens * 5 / 4 // Leave a margin for markers
+
+	// Structured turns are wrapped in JSON, and may carry citations
+	if outputMode(req) == OutputModeStructured {
+		budget += turns * structuredTurnTokens
+		if req.Citations {
+			budget += turns * citationTurnTokens
+		}
+	}
+
+	return min(max(budget, minDebateTokens), maxDebateTokens)
+}
+
+// writeStyle writes the length, register, tone and language guidelines of req
+func writeStyle(prompt *strings.Builder, req *DebateRequest) {
+	prompt.WriteString(fmt.Sprintf("- Panelist responses: %s\n", turnLengthOf(req).Description))
+	if register, ok := registers[req.Register]; ok {
+		prompt.WriteString(fmt.Sprintf("- %s\n", register))
+	} else {
+		prompt.WriteString("- Ensure philosophical depth while remaining accessible\n")
+	}
+	if tone, ok := tones[req.Tone]; ok {
+		prompt.WriteString(fmt.Sprintf("- %s\n", tone))
+	}
+	if req.Language != "" && req.Language != language.Default {
+		prompt.WriteString(fmt.Sprintf("- Write every message, including the moderator's, in %s; keep the speaker and phase IDs exactly as listed above\n", language.Name(req.Language)))
+	}
+}
+
+// style returns the style options of req, as stored with the debate
+func (req *DebateRequest) style() firebase.Style {
+	return firebase.Style{
+		TurnLength: req.TurnLength,
+		Register:   req.Register,
+		Tone:       req.Tone,
+	}
+}
@@ -0,0 +1,147 @@
+package generatedebate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/raphink/debate/shared/llm"
+)
+
+func TestTokenBudget(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       DebateRequest
+		exchanges int
+		want      int
+	}{
+		{name: "default debate", req: DebateRequest{}, exchanges: 18, want: 5200},
+		{name: "long turns", req: DebateRequest{TurnLength: TurnLengthLong}, exchanges: 18, want: 9750},
+		{name: "short continuation", req: DebateRequest{TurnLength: TurnLengthShort}, exchanges: 3, want: minDebateTokens},
+		{name: "capped", req: DebateRequest{TurnLength: TurnLengthLong}, exchanges: 40, want: maxDebateTokens},
+		{name: "structured", req: DebateRequest{OutputMode: OutputModeStructured}, exchanges: 18, want: 5200 + 26*structuredTurnTokens},
+		{name: "citations", req: DebateRequest{OutputMode: OutputModeStructured, Citations: true}, exchanges: 18, want: 5200 + 26*(structuredTurnTokens+citationTurnTokens)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokenBudget(&tt.req, tt.exchanges); got != tt.want {
+				t.Errorf("tokenBudget() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateDebateStyle(t *testing.T) {
+	fake := llm.NewFake("[moderator]: Welcome")
+	client := NewClaudeClientWithModel(fake)
+
+	req := &DebateRequest{
+		Topic:      "Is war ever just?",
+		Format:     FormatOxford,
+		Exchanges:  24,
+		TurnLength: TurnLengthLong,
+		Register:   RegisterTeen,
+		Tone:       ToneCombative,
+	}
+
+	var output chunkRecorder
+	if err := client.GenerateDebate(context.Background(), req, &output); err != nil {
+		t.Fatalf("GenerateDebate() error = %v", err)
+	}
+
+	request := fake.Requests()[0]
+	for _, want := range []string{"Include 24 exchanges", "4-6 sentences", "Write for teenagers", "combative"} {
+		if !strings.Contains(request.Messages[0].Text, want) {
+			t.Errorf("prompt does not contain %q:\n%s", want, request.Messages[0].Text)
+		}
+	}
+	if strings.Contains(request.Messages[0].Text, "philosophical depth") {
+		t.Error("prompt keeps the default register")
+	}
+	if request.MaxTokens != tokenBudget(req, 24) {
+		t.Errorf("MaxTokens = %d, want %d", request.MaxTokens, tokenBudget(req, 24))
+	}
+
+	// Without options, the format gives the number of exchanges
+	fake = llm.NewFake("[moderator]: Welcome")
+	client = NewClaudeClientWithModel(fake)
+	if err := client.GenerateDebate(context.Background(), &DebateRequest{Topic: "Is war ever just?"}, &output); err != nil {
+		t.Fatalf("GenerateDebate() error = %v", err)
+	}
+	if prompt := fake.Requests()[0].Messages[0].Text; !strings.Contains(prompt, "Include 12-18 exchanges") {
+		t.Errorf("default prompt does not use the format's exchanges:\n%s", prompt)
+	}
+}
+
+func TestHandleGenerateDebateLanguage(t *testing.T) {
+	fake := llm.NewFake("[phase]: opening\n[moderator]: Bienvenue.\n[augustine]: Une loi injuste n'est pas une loi.")
+	memStore := useFakes(t, fake)
+
+	body := strings.Replace(testDebateBody, `"topic"`, `"language": "fr", "topic"`, 1)
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	HandleGenerateDebate(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+
+	prompt := fake.Requests()[0].Messages[0].Text
+	if !strings.Contains(prompt, "including the moderator's, in French") {
+		t.Errorf("prompt does not ask for French:\n%s", prompt)
+	}
+
+	debate := waitForDebate(t, memStore, rec.Header().Get("X-Debate-Id"))
+	if debate.Language != "fr" {
+		t.Errorf("saved language = %q, want fr", debate.Language)
+	}
+
+	// Continuations are written in the language of the debate
+	if got := debateRequestFromDocument(debate, OutputModeText).Language; got != "fr" {
+		t.Errorf("rebuilt request language = %q, want fr", got)
+	}
+
+	// English debates keep the default prompt
+	fake = llm.NewFake("[moderator]: Welcome")
+	client := NewClaudeClientWithModel(fake)
+	var output chunkRecorder
+	if err := client.GenerateDebate(context.Background(), &DebateRequest{Topic: "Is war ever just?", Language: "en"}, &output); err != nil {
+		t.Fatalf("GenerateDebate() error = %v", err)
+	}
+	if prompt := fake.Requests()[0].Messages[0].Text; strings.Contains(prompt, "Write every message") {
+		t.Errorf("English prompt has a language guideline:\n%s", prompt)
+	}
+}
+
+func TestValidateDebateRequestStyle(t *testing.T) {
+	panelists := []Panelist{{ID: "a", Name: "A"}, {ID: "b", Name: "B"}}
+
+	tests := []struct {
+		name    string
+		req     DebateRequest
+		wantErr bool
+	}{
+		{name: "all options", req: DebateRequest{Exchanges: 20, TurnLength: TurnLengthShort, Register: RegisterAcademic, Tone: ToneIrenic}},
+		{name: "too few exchanges", req: DebateRequest{Exchanges: 1}, wantErr: true},
+		{name: "too many exchanges", req: DebateRequest{Exchanges: MaxDebateExchanges + 1}, wantErr: true},
+		{name: "unknown turn length", req: DebateRequest{TurnLength: "epic"}, wantErr: true},
+		{name: "unknown register", req: DebateRequest{Register: "legalese"}, wantErr: true},
+		{name: "unknown tone", req: DebateRequest{Tone: "sarcastic"}, wantErr: true},
+		{name: "language", req: DebateRequest{Language: "fr"}},
+		{name: "unknown language", req: DebateRequest{Language: "tlh"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Topic = "Is war ever just?"
+			tt.req.SelectedPanelists = panelists
+
+			if err := ValidateDebateRequest(&tt.req); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDebateRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,434 @@
+package generatedebate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/llm"
+)
+
+// writeStructuredFormat writes the output instructions for structured mode
+func writeStructuredFormat(prompt *strings.Builder) {
+	prompt.WriteString("CRITICAL FORMAT REQUIREMENTS:\n")
+	prompt.WriteString("- Output each message as one JSON object on its own line (JSON Lines)\n")
+	prompt.WriteString("- Fields, in this order: \"speaker\" (the speaker ID), \"addresses\" (optional array of the IDs being responded to), \"text\" (the message)\n")
+	prompt.WriteString("- Use \"moderator\" as the speaker for moderator messages\n")
+	prompt.WriteString("- Use the panelist IDs listed above as the speaker for panelist messages\n")
+	prompt.WriteString("- NO markdown, code fences or text outside the JSON objects\n")
+	prompt.WriteString("- Example: {\"speaker\":\"moderator\",\"addresses\":[],\"text\":\"Welcome to today's debate.\"}\n")
+	prompt.WriteString("- Start your response immediately with the JSON object of the first phase marker\n\n")
+}
+
+// jsonObject returns the JSON object in a model answer, ignoring anything
+// around it such as code fences
+func jsonObject(answer string) (string, error) {
+	start, end := strings.Index(answer, "{"), strings.LastIndex(answer, "}")
+	if start < 0 || end < start {
+		return "", errors.New("no JSON object in the answer")
+	}
+	return answer[start : end+1], nil
+}
+
+// writeCitations writes the instructions for panelists to cite their sources
+// in structured mode
+func writeCitations(prompt *strings.Builder) {
+	prompt.WriteString("CITATIONS:\n")
+	prompt.WriteString("- Panelists support their claims with references to their own writings or to the primary sources they rely on\n")
+	prompt.WriteString("- Add them to panelist messages as \"citations\", after \"text\": an array of objects with \"work\" (the title), \"locator\" (book, chapter, section or page) and an optional \"quote\" (a short exact quotation)\n")
+	prompt.WriteString("- Only cite works and passages you are confident exist: leave out the locator or quote rather than invent one\n")
+	prompt.WriteString("- Example: {\"speaker\":\"aquinas\",\"text\":\"A human law that deviates from natural law is no longer a law but a perversion of law.\",\"citations\":[{\"work\":\"Summa Theologica\",\"locator\":\"I-II, q. 95, a. 2\"}]}\n\n")
+}
+
+// structuredTurn is a turn in the structured output format
+type structuredTurn struct {
+	Speaker   string              `json:"speaker"`
+	Addresses []string            `json:"addresses,omitempty"`
+	Text      string              `json:"text"`
+	Citations []firebase.Citation `json:"citations,omitempty"`
+}
+
+// streamStructuredResponse processes a model stream of JSON turns and emits
+// the same message chunks as the [ID]: text protocol
+func (c *ClaudeClient) streamStructuredResponse(stream llm.Stream, emitter ChunkEmitter) error {
+	parser := newTurnParser(func(speaker, text string, addresses []string, citations []firebase.Citation) {
+		emitter.Emit(StreamChunk{
+			Type:       "message",
+			PanelistID: speaker,
+			Text:       text,
+			Addresses:  addresses,
+			Citations:  citations,
+		})
+	})
+
+	for stream.Next() {
+		parser.Feed(stream.Current())
+	}
+
+	if err := stream.Err(); err != nil {
+		return fmt.Errorf("stream error: %w", err)
+	}
+
+	parser.Close()
+
+	emitter.Emit(StreamChunk{
+		Type: "done",
+		Done: true,
+	})
+
+	return nil
+}
+
+// parseState is the position of the turnParser within the JSON stream
+type parseState int
+
+const (
+	stateSeek      parseState = iota // between turns, waiting for '{'
+	stateKey                         // inside a turn, waiting for a key
+	stateKeyString                   // reading a key
+	stateColon                       // waiting for ':' after a key
+	stateValue                       // waiting for a value
+	stateText                        // reading the "text" string, streamed out
+	stateString                      // reading any other string value
+	stateRaw                         // reading a non-string value (array, number, ...)
+)
+
+// turnParser incrementally parses a stream of JSON objects of the form
+// {"speaker": "...", "addresses": [...], "text": "...", "citations": [...]}.
+// Text is emitted as it arrives rather than once the object is complete;
+// citations given after the text are emitted at the end of the turn.
+// Anything outside of objects (whitespace, commas, array brackets, code
+// fences) is ignored.
+type turnParser struct {
+	emit func(speaker, text string, addresses []string, citations []firebase.Citation)
+
+	state parseState
+	str   jsonString      // decoder for the current string literal
+	key   strings.Builder // current key
+	value strings.Builder // current non-text value
+
+	// Raw value tracking
+	depth    int
+	inString bool
+	escaped  bool
+
+	// Current turn
+	speaker       string
+	addresses     []string
+	addressesSent bool
+	citations     []firebase.Citation
+	citationsSent bool
+	pending       strings.Builder // text to emit once the speaker is known
+}
+
+// newTurnParser creates a parser calling emit for each piece of turn text
+func newTurnParser(emit func(speaker, text string, addresses []string, citations []firebase.Citation)) *turnParser {
+	return &turnParser{emit: emit}
+}
+
+// Feed consumes a delta of model output and emits the text it completes
+func (p *turnParser) Feed(delta string) {
+	for _, r := range delta {
+		p.feedRune(r)
+	}
+
+	// Flush at the end of each delta for responsiveness
+	p.flush()
+}
+
+// Close flushes a turn left incomplete by a truncated stream
+func (p *turnParser) Close() {
+	if p.state != stateSeek {
+		p.endTurn()
+	}
+}
+
+func (p *turnParser) feedRune(r rune) {
+	switch p.state {
+	case stateSeek:
+		if r == '{' {
+			p.startTurn()
+		}
+
+	case stateKey:
+		switch r {
+		case '"':
+			p.key.Reset()
+			p.str = jsonString{}
+			p.state = stateKeyString
+		case '}':
+			p.endTurn()
+		}
+
+	case stateKeyString:
+		text, done := p.str.feed(r)
+		p.key.WriteString(text)
+		if done {
+			p.state = stateColon
+		}
+
+	case stateColon:
+		if r == ':' {
+			p.state = stateValue
+		}
+
+	case stateValue:
+		switch {
+		case unicode.IsSpace(r):
+		case r == '"':
+			p.str = jsonString{}
+			p.value.Reset()
+			if p.key.String() == "text" {
+				p.state = stateText
+			} else {
+				p.state = stateString
+			}
+		default:
+			p.value.Reset()
+			p.inString, p.escaped = false, false
+			p.depth = 0
+			p.state = stateRaw
+			p.feedRaw(r)
+		}
+
+	case stateText:
+		text, done := p.str.feed(r)
+		p.pending.WriteString(text)
+		if done {
+			p.flush()
+			p.state = stateKey
+		} else if p.pending.Len() >= 10 || r == ' ' || text == "\n" {
+			// Send chunks frequently for responsiveness (every 10 chars or at word boundaries)
+			p.flush()
+		}
+
+	case stateString:
+		text, done := p.str.feed(r)
+		p.value.WriteString(text)
+		if done {
+			p.setField(p.key.String(), p.value.String())
+			p.state = stateKey
+		}
+
+	case stateRaw:
+		p.feedRaw(r)
+	}
+}
+
+// feedRaw accumulates a non-string value until it is complete
+func (p *turnParser) feedRaw(r rune) {
+	if p.inString {
+		p.value.WriteRune(r)
+		switch {
+		case p.escaped:
+			p.escaped = false
+		case r == '\\':
+			p.escaped = true
+		case r == '"':
+			p.inString = false
+		}
+		return
+	}
+
+	if p.depth == 0 && (r == ',' || r == '}' || unicode.IsSpace(r)) {
+		p.endRaw()
+		if r == '}' {
+			p.endTurn()
+		}
+		return
+	}
+
+	p.value.WriteRune(r)
+	switch r {
+	case '"':
+		p.inString = true
+	case '[', '{':
+		p.depth++
+	case ']', '}':
+		p.depth--
+		if p.depth == 0 {
+			p.endRaw()
+		}
+	}
+}
+
+// endRaw decodes the completed raw value
+func (p *turnParser) endRaw() {
+	p.state = stateKey
+	switch p.key.String() {
+	case "addresses":
+		var addresses []string
+		if err := json.Unmarshal([]byte(p.value.String()), &addresses); err != nil {
+			log.Printf("Ignoring invalid addresses %q: %v", p.value.String(), err)
+			return
+		}
+		p.addresses = addresses
+	case "citations":
+		var citations []firebase.Citation
+		if err := json.Unmarshal([]byte(p.value.String()), &citations); err != nil {
+			log.Printf("Ignoring invalid citations %q: %v", p.value.String(), err)
+			return
+		}
+		p.citations = validCitations(citations)
+	}
+}
+
+// validCitations keeps the citations that name a work, trimmed
+func validCitations(citations []firebase.Citation) []firebase.Citation {
+	valid := make([]firebase.Citation, 0, len(citations))
+	for _, citation := range citations {
+		citation.Work = strings.TrimSpace(citation.Work)
+		citation.Locator = strings.TrimSpace(citation.Locator)
+		citation.Quote = strings.TrimSpace(citation.Quote)
+		if citation.Work != "" {
+			valid = append(valid, citation)
+		}
+	}
+	return valid
+}
+
+// setField records a string field of the current turn
+func (p *turnParser) setField(key, value string) {
+	switch key {
+	case "speaker":
+		p.speaker = strings.TrimSpace(value)
+		p.flush()
+	case "addresses":
+		// Tolerate a single ID instead of an array
+		p.addresses = []string{value}
+	}
+}
+
+func (p *turnParser) startTurn() {
+	p.speaker = ""
+	p.addresses = nil
+	p.addressesSent = false
+	p.citations = nil
+	p.citationsSent = false
+	p.pending.Reset()
+	p.state = stateKey
+}
+
+func (p *turnParser) endTurn() {
+	if p.speaker == "" && p.pending.Len() > 0 {
+		log.Printf("Dropping turn without speaker: %q", p.pending.String())
+	}
+	p.flush()
+
+	// Citations given after the text close the message
+	if p.speaker != "" && !p.citationsSent && len(p.citations) > 0 {
+		p.emit(p.speaker, "", nil, p.citations)
+		p.citationsSent = true
+	}
+	p.state = stateSeek
+}
+
+// flush emits pending text once the speaker of the turn is known
+func (p *turnParser) flush() {
+	if p.speaker == "" || p.pending.Len() == 0 {
+		return
+	}
+
+	var addresses []string
+	if !p.addressesSent && len(p.addresses) > 0 {
+		addresses = p.addresses
+		p.addressesSent = true
+	}
+	var citations []firebase.Citation
+	if !p.citationsSent && len(p.citations) > 0 {
+		citations = p.citations
+		p.citationsSent = true
+	}
+
+	p.emit(p.speaker, p.pending.String(), addresses, citations)
+	p.pending.Reset()
+}
+
+// jsonString decodes a JSON string literal one rune at a time
+type jsonString struct {
+	escape bool
+	hex    []rune // digits of a \uXXXX escape being read
+	high   rune   // high surrogate waiting for its pair
+}
+
+// feed consumes the next rune of the literal (after the opening quote) and
+// returns the decoded text it completes and whether the literal is closed
+func (s *jsonString) feed(r rune) (string, bool) {
+	if s.hex != nil {
+		s.hex = append(s.hex, r)
+		if len(s.hex) < 4 {
+			return "", false
+		}
+
+		code, err := strconv.ParseUint(string(s.hex), 16, 32)
+		s.hex = nil
+		if err != nil {
+			return string(unicode.ReplacementChar), false
+		}
+		return s.decodeUnicode(rune(code)), false
+	}
+
+	if s.escape {
+		s.escape = false
+		switch r {
+		case 'u':
+			s.hex = make([]rune, 0, 4)
+			return "", false
+		case 'n':
+			return s.literal("\n"), false
+		case 't':
+			return s.literal("\t"), false
+		case 'r':
+			return s.literal("\r"), false
+		case 'b':
+			return s.literal("\b"), false
+		case 'f':
+			return s.literal("\f"), false
+		default: // \" \\ \/
+			return s.literal(string(r)), false
+		}
+	}
+
+	switch r {
+	case '\\':
+		s.escape = true
+		return "", false
+	case '"':
+		return s.literal(""), true
+	default:
+		return s.literal(string(r)), false
+	}
+}
+
+// decodeUnicode combines surrogate pairs from consecutive \u escapes
+func (s *jsonString) decodeUnicode(code rune) string {
+	if s.high != 0 {
+		high := s.high
+		s.high = 0
+		if combined := utf16.DecodeRune(high, code); combined != unicode.ReplacementChar {
+			return string(combined)
+		}
+		return string(unicode.ReplacementChar) + s.decodeUnicode(code)
+	}
+
+	if utf16.IsSurrogate(code) && code < 0xDC00 {
+		s.high = code
+		return ""
+	}
+	return string(code)
+}
+
+// literal returns text, preceded by a replacement for an unpaired surrogate
+func (s *jsonString) literal(text string) string {
+	if s.high != 0 {
+		s.high = 0
+		return string(unicode.ReplacementChar) + text
+	}
+	return text
+}
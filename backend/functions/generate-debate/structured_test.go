@@ -0,0 +1,324 @@
+package generatedebate
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/raphink/debate/shared/llm"
+)
+
+// runStructuredStream feeds deltas through streamStructuredResponse using the fake model
+func runStructuredStream(t *testing.T, deltas ...string) ([]StreamChunk, error) {
+	t.Helper()
+
+	client := NewClaudeClientWithModel(llm.NewFake(deltas...))
+	stream := client.model.Stream(context.Background(), llm.Request{})
+
+	var output chunkRecorder
+	err := client.streamStructuredResponse(stream, &output)
+	return output.chunks, err
+}
+
+func TestStreamStructuredResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		deltas   []string
+		expected []StreamChunk
+	}{
+		{
+			name: "JSON lines",
+			deltas: []string{
+				`{"speaker":"moderator","text":"Welcome to the debate."}` + "\n",
+				`{"speaker":"augustine","addresses":["moderator"],"text":"Thank you."}` + "\n",
+			},
+			expected: []StreamChunk{
+				{PanelistID: "moderator", Text: "Welcome to the debate."},
+				{PanelistID: "augustine", Text: "Thank you."},
+			},
+		},
+		{
+			name: "Objects split across deltas",
+			deltas: []string{
+				`{"spea`,
+				`ker": "moder`,
+				`ator", "text": "Welcome to our `,
+				`debate on ethics."}`,
+				"\n{\"speaker\":\"aquinas\",\"te",
+				`xt":"I respectfully disagree."}`,
+			},
+			expected: []StreamChunk{
+				{PanelistID: "moderator", Text: "Welcome to our debate on ethics."},
+				{PanelistID: "aquinas", Text: "I respectfully disagree."},
+			},
+		},
+		{
+			name:   "Brackets and markers inside text",
+			deltas: []string{`{"speaker":"augustine","text":"As I wrote [in Confessions]: \"Late have I loved thee\"."}`},
+			expected: []StreamChunk{
+				{PanelistID: "augustine", Text: `As I wrote [in Confessions]: "Late have I loved thee".`},
+			},
+		},
+		{
+			name: "Escapes split across deltas",
+			deltas: []string{
+				`{"speaker":"aquinas","text":"Summa\`,
+				`nTheologi\u00`,
+				`e6 \ud83d`,
+				`\ude4f"}`,
+			},
+			expected: []StreamChunk{
+				{PanelistID: "aquinas", Text: "Summa\nTheologiæ 🙏"},
+			},
+		},
+		{
+			name:   "Text before speaker",
+			deltas: []string{`{"text":"Peace be with you.","speaker":"augustine"}`},
+			expected: []StreamChunk{
+				{PanelistID: "augustine", Text: "Peace be with you."},
+			},
+		},
+		{
+			name: "Array in a code fence",
+			deltas: []string{
+				"```json\n[\n",
+				`  {"speaker": "moderator", "text": "Welcome."},` + "\n",
+				`  {"speaker": "mlk", "text": "Thank you."}` + "\n",
+				"]\n```",
+			},
+			expected: []StreamChunk{
+				{PanelistID: "moderator", Text: "Welcome."},
+				{PanelistID: "mlk", Text: "Thank you."},
+			},
+		},
+		{
+			name: "Unknown fields are skipped",
+			deltas: []string{
+				`{"speaker":"moderator","tone":{"calm":true,"notes":["a}","b"]},"round":2,"text":"Welcome."}`,
+			},
+			expected: []StreamChunk{
+				{PanelistID: "moderator", Text: "Welcome."},
+			},
+		},
+		{
+			name:     "Turn without speaker is dropped",
+			deltas:   []string{`{"text":"Who said this?"}`},
+			expected: nil,
+		},
+		{
+			name:   "Truncated stream flushes the last turn",
+			deltas: []string{`{"speaker":"moderator","text":"Thank you all for`},
+			expected: []StreamChunk{
+				{PanelistID: "moderator", Text: "Thank you all for"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, err := runStructuredStream(t, tt.deltas...)
+			if err != nil {
+				t.Fatalf("streamStructuredResponse failed: %v", err)
+			}
+			assertMessages(t, mergeMessages(chunks), tt.expected)
+
+			if last := chunks[len(chunks)-1]; last.Type != "done" || !last.Done {
+				t.Errorf("last chunk = %+v, want done chunk", last)
+			}
+		})
+	}
+}
+
+func TestStreamStructuredResponseStreamsText(t *testing.T) {
+	chunks, err := runStructuredStream(t, `{"speaker":"moderator","text":"Welcome to `, `the debate."}`)
+	if err != nil {
+		t.Fatalf("streamStructuredResponse failed: %v", err)
+	}
+
+	// Text is sent before the object is complete
+	if chunks[0].Type != "message" || chunks[0].Text == "" || strings.Contains(chunks[0].Text, "debate") {
+		t.Errorf("first chunk = %+v, want partial text", chunks[0])
+	}
+}
+
+func TestStreamStructuredResponseAddresses(t *testing.T) {
+	chunks, err := runStructuredStream(t,
+		`{"speaker":"aquinas","addresses":["augustine","mlk"],"text":"With respect to both of you, I disagree."}`,
+		`{"speaker":"mlk","addresses":"aquinas","text":"Then let us reason together."}`,
+	)
+	if err != nil {
+		t.Fatalf("streamStructuredResponse failed: %v", err)
+	}
+
+	var addressed [][]string
+	for _, chunk := range chunks {
+		if len(chunk.Addresses) > 0 {
+			addressed = append(addressed, chunk.Addresses)
+		}
+	}
+
+	// Addresses are sent once per turn, on its first chunk
+	if len(addressed) != 2 ||
+		strings.Join(addressed[0], ",") != "augustine,mlk" ||
+		strings.Join(addressed[1], ",") != "aquinas" {
+		t.Errorf("addresses = %v, want [[augustine mlk] [aquinas]]", addressed)
+	}
+}
+
+func TestStreamStructuredResponseCitations(t *testing.T) {
+	chunks, err := runStructuredStream(t,
+		`{"speaker":"aquinas","text":"An unjust law is a perversion of law.",`,
+		`"citations":[{"work":"Summa Theologica","locator":"I-II, q. 95, a. 2","quote":"non lex sed legis corruptio {sic}"},{"locator":"p. 3"}]}`+"\n",
+		`{"speaker":"augustine","citations":[{"work":" De Libero Arbitrio ","locator":"I.5"}],"text":"I said as much."}`+"\n",
+		`{"speaker":"moderator","text":"Thank you."}`,
+	)
+	if err != nil {
+		t.Fatalf("streamStructuredResponse failed: %v", err)
+	}
+
+	var cited []StreamChunk
+	for _, chunk := range chunks {
+		if len(chunk.Citations) > 0 {
+			cited = append(cited, chunk)
+		}
+	}
+	if len(cited) != 2 {
+		t.Fatalf("chunks with citations = %+v, want one per citing turn", cited)
+	}
+
+	// Citations after the text close the message, citations before it ride with the text
+	aquinas, augustine := cited[0], cited[1]
+	if aquinas.PanelistID != "aquinas" || aquinas.Text != "" || len(aquinas.Citations) != 1 ||
+		aquinas.Citations[0].Locator != "I-II, q. 95, a. 2" || aquinas.Citations[0].Quote != "non lex sed legis corruptio {sic}" {
+		t.Errorf("aquinas citations = %+v", aquinas)
+	}
+	if augustine.PanelistID != "augustine" || augustine.Text == "" || augustine.Citations[0].Work != "De Libero Arbitrio" {
+		t.Errorf("augustine citations = %+v", augustine)
+	}
+
+	assertMessages(t, mergeMessages(chunks), []StreamChunk{
+		{PanelistID: "aquinas", Text: "An unjust law is a perversion of law."},
+		{PanelistID: "augustine", Text: "I said as much."},
+		{PanelistID: "moderator", Text: "Thank you."},
+	})
+}
+
+func TestHandleGenerateDebateCitations(t *testing.T) {
+	fake := llm.NewFake(
+		`{"speaker":"moderator","text":"Welcome."}`+"\n",
+		`{"speaker":"augustine","text":"An unjust law is no law at all.","citations":[{"work":"De Libero Arbitrio","locator":"I.5"}]}`+"\n",
+		`{"speaker":"moderator","text":"Thank you."}`,
+	)
+	memStore := useFakes(t, fake)
+
+	body := strings.Replace(testDebateBody, `"topic"`, `"citations": true, "topic"`, 1)
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	HandleGenerateDebate(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	if prompt := fake.Requests()[0].Messages[0].Text; !strings.Contains(prompt, "JSON Lines") || !strings.Contains(prompt, "CITATIONS:") {
+		t.Errorf("prompt does not ask for cited JSON turns:\n%s", prompt)
+	}
+
+	debate := waitForDebate(t, memStore, rec.Header().Get("X-Debate-Id"))
+	if !debate.Citations {
+		t.Error("saved debate is not in citation mode")
+	}
+	if len(debate.Messages) != 3 || len(debate.Messages[1].Citations) != 1 || debate.Messages[1].Citations[0].Work != "De Libero Arbitrio" {
+		t.Fatalf("saved messages = %+v, want augustine's citation", debate.Messages)
+	}
+
+	// Continuations keep the structured mode and replay the citations
+	debateReq := debateRequestFromDocument(debate, "")
+	if !debateReq.Citations || outputMode(debateReq) != OutputModeStructured {
+		t.Errorf("rebuilt request = %+v, want citations in structured mode", debateReq)
+	}
+	if transcript := formatTranscript(debate.Messages, OutputModeStructured); !strings.Contains(transcript, `"citations":[{"work":"De Libero Arbitrio","locator":"I.5"}]`) {
+		t.Errorf("transcript = %s", transcript)
+	}
+}
+
+func TestValidateDebateRequestCitations(t *testing.T) {
+	req := &DebateRequest{
+		Topic:             "Is war ever just?",
+		SelectedPanelists: []Panelist{{ID: "a", Name: "A"}, {ID: "b", Name: "B"}},
+		Citations:         true,
+	}
+	if err := ValidateDebateRequest(req); err != nil || req.OutputMode != OutputModeStructured {
+		t.Errorf("ValidateDebateRequest() error = %v, output mode %q, want structured", err, req.OutputMode)
+	}
+
+	req.OutputMode = OutputModeText
+	if err := ValidateDebateRequest(req); err == nil {
+		t.Error("ValidateDebateRequest() accepted citations in text mode")
+	}
+}
+
+func TestStreamStructuredResponseError(t *testing.T) {
+	streamErr := errors.New("overloaded")
+	client := NewClaudeClientWithModel(llm.NewFakeScripts(llm.Script{
+		Deltas: []string{`{"speaker":"moderator","text":"Welcome`},
+		Err:    streamErr,
+	}))
+
+	var output chunkRecorder
+	err := client.GenerateDebate(context.Background(), &DebateRequest{Topic: "Is war ever just?", OutputMode: OutputModeStructured}, &output)
+	if !errors.Is(err, streamErr) {
+		t.Fatalf("GenerateDebate() error = %v, want %v", err, streamErr)
+	}
+
+	for _, chunk := range output.chunks {
+		if chunk.Type == "done" {
+			t.Error("done chunk sent despite stream error")
+		}
+	}
+}
+
+func TestGenerateDebateStructuredPrompt(t *testing.T) {
+	fake := llm.NewFake(`{"speaker":"moderator","text":"Welcome"}`)
+	client := NewClaudeClientWithModel(fake)
+
+	req := &DebateRequest{
+		Topic:      "Should Christians defy unjust laws?",
+		OutputMode: OutputModeStructured,
+		SelectedPanelists: []Panelist{
+			{ID: "augustine", Name: "Augustine of Hippo"},
+			{ID: "mlk", Name: "Martin Luther King Jr."},
+		},
+	}
+
+	var output chunkRecorder
+	if err := client.GenerateDebate(context.Background(), req, &output); err != nil {
+		t.Fatalf("GenerateDebate() error = %v", err)
+	}
+
+	prompt := fake.Requests()[0].Messages[0].Text
+	if !strings.Contains(prompt, "JSON Lines") || strings.Contains(prompt, "[moderator]:") {
+		t.Errorf("prompt does not ask for JSON turns:\n%s", prompt)
+	}
+
+	assertMessages(t, mergeMessages(output.chunks), []StreamChunk{
+		{PanelistID: "moderator", Text: "Welcome"},
+	})
+}
+
+func TestOutputMode(t *testing.T) {
+	t.Setenv("DEBATE_OUTPUT_MODE", "")
+	if got := outputMode(&DebateRequest{}); got != OutputModeText {
+		t.Errorf("outputMode() = %q, want %q", got, OutputModeText)
+	}
+
+	t.Setenv("DEBATE_OUTPUT_MODE", OutputModeStructured)
+	if got := outputMode(&DebateRequest{}); got != OutputModeStructured {
+		t.Errorf("outputMode() with DEBATE_OUTPUT_MODE = %q, want %q", got, OutputModeStructured)
+	}
+	if got := outputMode(&DebateRequest{OutputMode: OutputModeText}); got != OutputModeText {
+		t.Errorf("outputMode() with request mode = %q, want %q", got, OutputModeText)
+	}
+}
@@ -0,0 +1,276 @@
+package generatedebate
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/language"
+	"github.com/raphink/debate/shared/llm"
+	"github.com/raphink/debate/shared/store"
+)
+
+// translationNamespace derives the IDs of translated debates, so that each
+// debate has at most one translation per language
+var translationNamespace = uuid.MustParse("5b0c8e0e-3f51-4f4e-9a57-2b1d7c6e4a90")
+
+// detailsMaxTokens bounds the translation of the topic and panelist details
+const detailsMaxTokens = 4096
+
+// handleTranslateDebateImpl translates a saved debate into another language
+// and streams the translated messages. The translation is saved as a new
+// debate, linked to the original. GET requests resume the stream with
+// Last-Event-ID.
+func handleTranslateDebateImpl(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method == "GET" {
+		handleResumeDebate(w, r)
+		return
+	}
+
+	if r.Method != "POST" {
+		sendError(w, "Method not allowed", ErrInvalidRequest, false, http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse request body
+	var req TranslateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, "Invalid request body", ErrInvalidRequest, false, http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	if err := ValidateTranslateRequest(&req); err != nil {
+		sendError(w, err.Error(), ErrInvalidRequest, false, http.StatusBadRequest)
+		return
+	}
+
+	debateStore, source, ok := loadDebate(r.Context(), w, req.DebateID)
+	if !ok {
+		return
+	}
+
+	if source.Status == firebase.StatusInProgress {
+		sendError(w, "Debate is still being generated", ErrDebateInProgress, true, http.StatusConflict)
+		return
+	}
+	if sourceLanguage(source) == req.Language {
+		sendError(w, fmt.Sprintf("Debate is already in %s", language.Name(req.Language)), ErrInvalidRequest, false, http.StatusBadRequest)
+		return
+	}
+
+	// A debate being translated is not translated again concurrently
+	id := translationID(source, req.Language)
+	if existing, err := debateStore.GetDebate(r.Context(), id); err == nil && existing.Status == firebase.StatusInProgress {
+		sendError(w, "Debate is already being translated", ErrDebateInProgress, true, http.StatusConflict)
+		return
+	}
+
+	// Create Claude client
+	claudeClient, err := newClaudeClient()
+	if err != nil {
+		log.Printf("Failed to create Claude client: %v", err)
+		sendError(w, "Service configuration error", ErrInternalError, true, http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Translating debate %s into %s as %s", source.ID, req.Language, id)
+
+	// Translate the details first, so the translation is saved with them
+	topic, panelists, err := claudeClient.TranslateDetails(r.Context(), source, req.Language)
+	if err != nil {
+		log.Printf("Failed to translate debate details: %v", err)
+		sendError(w, "Translation is temporarily unavailable. Please try again.", ErrServiceUnavailable, true, http.StatusServiceUnavailable)
+		return
+	}
+
+	accumulator := NewDebateAccumulator(id, topic, panelists)
+	accumulator.Format = source.Format
+	accumulator.Style = source.Style
+	accumulator.Language = req.Language
+	accumulator.Citations = source.Citations
+	accumulator.TranslatedFrom = originalID(source)
+
+	// Claim the translation, as another request may have started it
+	if !claimTranslation(r.Context(), w, debateStore, accumulator.Document(firebase.StatusInProgress, r.Header.Get("User-Agent"))) {
+		return
+	}
+
+	serveGeneration(w, r, debateStore, accumulator, r.Header.Get("User-Agent"), func(ctx context.Context, emitter ChunkEmitter) error {
+		return claudeClient.TranslateMessages(ctx, source, req.Language, emitter)
+	})
+}
+
+// claimTranslation atomically moves the translation stored under the ID of
+// debate to in_progress, creating it from debate if it does not exist yet, so
+// that two requests never generate the same translation at once. It sends an
+// error response if the translation cannot be claimed.
+func claimTranslation(ctx context.Context, w http.ResponseWriter, debateStore store.DebateStore, debate *firebase.DebateDocument) bool {
+	err := debateStore.UpdateDebate(ctx, debate.ID, func(existing *firebase.DebateDocument) error {
+		if existing.Status == firebase.StatusInProgress {
+			return errDebateInProgress
+		}
+		existing.Status = firebase.StatusInProgress
+		return nil
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		err = debateStore.CreateDebate(ctx, debate.ID, debate)
+		if errors.Is(err, store.ErrExists) {
+			err = errDebateInProgress
+		}
+	}
+
+	switch {
+	case errors.Is(err, errDebateInProgress):
+		sendError(w, "Debate is already being translated", ErrDebateInProgress, true, http.StatusConflict)
+		return false
+	case err != nil:
+		log.Printf("Failed to claim translation %s: %v", debate.ID, err)
+		sendError(w, "Failed to save translation", ErrInternalError, true, http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// sourceLanguage returns the language code of doc
+func sourceLanguage(doc *firebase.DebateDocument) string {
+	if doc.Language == "" {
+		return language.Default
+	}
+	return doc.Language
+}
+
+// originalID returns the ID of the debate doc was written as: translations
+// of a translation are linked to the same original
+func originalID(doc *firebase.DebateDocument) string {
+	if doc.TranslatedFrom != "" {
+		return doc.TranslatedFrom
+	}
+	return doc.ID
+}
+
+// translationID returns the ID of the translation of doc into lang
+func translationID(doc *firebase.DebateDocument, lang string) string {
+	return uuid.NewSHA1(translationNamespace, []byte(originalID(doc)+"/"+lang)).String()
+}
+
+// translatedDetails holds the translatable details of a debate
+type translatedDetails struct {
+	Topic     string               `json:"topic"`
+	Panelists []translatedPanelist `json:"panelists"`
+}
+
+// translatedPanelist holds the translatable details of a panelist
+type translatedPanelist struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Tagline  string `json:"tagline"`
+	Bio      string `json:"bio"`
+	Position string `json:"position"`
+}
+
+// TranslateDetails translates the topic and panelist details of source into
+// lang. Details missing from the model's answer are kept untranslated.
+func (c *ClaudeClient) TranslateDetails(ctx context.Context, source *firebase.DebateDocument, lang string) (string, []Panelist, error) {
+	panelists := panelistsFromDocument(source)
+
+	details := translatedDetails{Topic: source.Topic.Text}
+	for _, p := range panelists {
+		details.Panelists = append(details.Panelists, translatedPanelist{
+			ID:       p.ID,
+			Name:     p.Name,
+			Tagline:  p.Tagline,
+			Bio:      p.Bio,
+			Position: p.Position,
+		})
+	}
+	input, _ := json.MarshalIndent(details, "", "  ")
+
+	var prompt strings.Builder
+	prompt.WriteString(fmt.Sprintf("Translate the details of this debate from %s into %s.\n", language.Name(sourceLanguage(source)), language.Name(lang)))
+	prompt.WriteString("- Return ONLY a JSON object with exactly the same structure, with no markdown or other text\n")
+	prompt.WriteString("- Translate the values of \"topic\", \"name\", \"tagline\", \"bio\" and \"position\"\n")
+	prompt.WriteString(fmt.Sprintf("- Use the names by which the panelists are usually known in %s\n", language.Name(lang)))
+	prompt.WriteString("- Keep every \"id\" unchanged\n\n")
+	prompt.Write(input)
+
+	stream := c.model.Stream(ctx, llm.UserPrompt(prompt.String(), detailsMaxTokens))
+	defer stream.Close()
+
+	var answer strings.Builder
+	for stream.Next() {
+		answer.WriteString(stream.Current())
+	}
+	if err := stream.Err(); err != nil {
+		return "", nil, fmt.Errorf("stream error: %w", err)
+	}
+
+	object, err := jsonObject(answer.String())
+	if err != nil {
+		return "", nil, err
+	}
+	var translated translatedDetails
+	if err := json.Unmarshal([]byte(object), &translated); err != nil {
+		return "", nil, fmt.Errorf("invalid translated details: %w", err)
+	}
+
+	topic := cmp.Or(translated.Topic, source.Topic.Text)
+	for _, t := range translated.Panelists {
+		for i := range panelists {
+			if panelists[i].ID == t.ID {
+				panelists[i].Name = cmp.Or(t.Name, panelists[i].Name)
+				panelists[i].Tagline = cmp.Or(t.Tagline, panelists[i].Tagline)
+				panelists[i].Bio = cmp.Or(t.Bio, panelists[i].Bio)
+				panelists[i].Position = cmp.Or(t.Position, panelists[i].Position)
+			}
+		}
+	}
+
+	return topic, panelists, nil
+}
+
+// TranslateMessages streams the messages of source translated into lang,
+// as the chunks of a generated debate
+func (c *ClaudeClient) TranslateMessages(ctx context.Context, source *firebase.DebateDocument, lang string, emitter ChunkEmitter) error {
+	transcript := formatTranscript(source.Messages, OutputModeStructured)
+
+	var prompt strings.Builder
+	prompt.WriteString(fmt.Sprintf("Translate this debate transcript from %s into %s.\n", language.Name(sourceLanguage(source)), language.Name(lang)))
+	prompt.WriteString("- Output one JSON object per line, with the same \"speaker\", \"addresses\" and \"citations\" as the original line and its \"text\" translated\n")
+	prompt.WriteString("- Keep the lines in the same order: exactly one output line per input line\n")
+	prompt.WriteString(fmt.Sprintf("- Copy the lines whose speaker is %q unchanged: they mark phases\n", phaseSpeaker))
+	prompt.WriteString("- Translate faithfully, keeping each speaker's register and tone, without summarizing, adding or omitting anything\n")
+	prompt.WriteString("- NO markdown, code fences or text outside the JSON objects\n\n")
+	prompt.WriteString("Transcript:\n")
+	prompt.WriteString(transcript)
+
+	// Phase markers become phase chunks
+	emitter = &phaseEmitter{next: emitter, format: debateFormat(&DebateRequest{Format: source.Format})}
+
+	// Create streaming request, continued if it hits the token limit
+	stream := llm.StreamWithContinuations(ctx, c.model, llm.UserPrompt(prompt.String(), translationBudget(transcript)), maxContinuations)
+	defer stream.Close()
+
+	return c.streamStructuredResponse(stream, emitter)
+}
+
+// translationBudget returns the max tokens needed to translate transcript,
+// allowing for languages that take more tokens than the original
+func translationBudget(transcript string) int {
+	budget := len([]rune(transcript)) / 2
+	return min(max(budget, minDebateTokens), maxDebateTokens)
+}
@@ -0,0 +1,198 @@
+package generatedebate
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/llm"
+	"github.com/raphink/debate/shared/store"
+)
+
+func TestHandleTranslateDebate(t *testing.T) {
+	fake := llm.NewFakeScripts(
+		llm.Script{Deltas: []string{"```json\n" + `{"topic":"Les chrétiens doivent-ils désobéir aux lois injustes ?","panelists":[` +
+			`{"id":"augustine","name":"Augustin d'Hippone","tagline":"Évêque d'Hippone"}]}` + "\n```"}},
+		llm.Script{Deltas: []string{
+			`{"speaker":"augustine","text":"Une loi injuste n'est pas`,
+			` une loi."}` + "\n",
+			`{"speaker":"moderator","text":"Merci à vous deux."}`,
+		}},
+	)
+	memStore := useFakes(t, fake)
+	saveTestDebate(t, memStore, "d1", firebase.StatusComplete)
+
+	req := httptest.NewRequest(http.MethodPost, "/translate-debate", strings.NewReader(`{"debateId": "d1", "language": "fr"}`))
+	rec := httptest.NewRecorder()
+	HandleTranslateDebate(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	assertMessages(t, mergeMessages(decodeChunks(t, rec.Body.String())), []StreamChunk{
+		{PanelistID: "augustine", Text: "Une loi injuste n'est pas une loi."},
+		{PanelistID: "moderator", Text: "Merci à vous deux."},
+	})
+
+	// The details are translated first, then the transcript
+	requests := fake.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("%d model requests, want 2", len(requests))
+	}
+	if prompt := requests[0].Messages[0].Text; !strings.Contains(prompt, "from English into French") || !strings.Contains(prompt, `"tagline"`) {
+		t.Errorf("details prompt = %q", prompt)
+	}
+	if prompt := requests[1].Messages[0].Text; !strings.Contains(prompt, `{"speaker":"augustine","text":"An unjust law is no law at all."}`) {
+		t.Errorf("transcript prompt = %q", prompt)
+	}
+
+	// The translation is a new debate, linked to the original by language
+	id := rec.Header().Get("X-Debate-Id")
+	if id != translationID(&firebase.DebateDocument{ID: "d1"}, "fr") {
+		t.Errorf("translation ID = %q, want the ID derived from d1 and fr", id)
+	}
+	debate := waitForDebate(t, memStore, id)
+	if debate.TranslatedFrom != "d1" || debate.Language != "fr" {
+		t.Errorf("saved translation of %q in %q, want d1 in fr", debate.TranslatedFrom, debate.Language)
+	}
+	if debate.Topic.Text != "Les chrétiens doivent-ils désobéir aux lois injustes ?" {
+		t.Errorf("saved topic = %q", debate.Topic.Text)
+	}
+	augustine, mlk := debate.Panelists[0], debate.Panelists[1]
+	if augustine.Name != "Augustin d'Hippone" || augustine.Tagline != "Évêque d'Hippone" || augustine.AvatarURL != "augustine.jpg" {
+		t.Errorf("translated panelist = %+v", augustine)
+	}
+	if mlk.Name != "Martin Luther King Jr." {
+		t.Errorf("untranslated panelist name = %q, want the original", mlk.Name)
+	}
+	if len(debate.Messages) != 2 || debate.Messages[0].PanelistName != "Augustin d'Hippone" {
+		t.Errorf("saved messages = %+v", debate.Messages)
+	}
+
+	// The original is left untouched
+	original := waitForDebate(t, memStore, "d1")
+	if original.Language != "" || original.Messages[0].Text != "An unjust law is no law at all." {
+		t.Errorf("original debate changed: %+v", original)
+	}
+}
+
+func TestHandleTranslateDebateErrors(t *testing.T) {
+	memStore := useFakes(t, llm.NewFakeScripts(llm.Script{Err: errors.New("overloaded")}))
+	saveTestDebate(t, memStore, "running", firebase.StatusInProgress)
+	saveTestDebate(t, memStore, "d1", firebase.StatusComplete)
+
+	// A translation is already running
+	translating := &firebase.DebateDocument{ID: "d2"}
+	saveTestDebate(t, memStore, "d2", firebase.StatusComplete)
+	saveTestDebate(t, memStore, translationID(translating, "de"), firebase.StatusInProgress)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "missing language", body: `{"debateId": "d1"}`, want: http.StatusBadRequest},
+		{name: "unknown language", body: `{"debateId": "d1", "language": "tlh"}`, want: http.StatusBadRequest},
+		{name: "same language", body: `{"debateId": "d1", "language": "en"}`, want: http.StatusBadRequest},
+		{name: "unknown debate", body: `{"debateId": "missing", "language": "fr"}`, want: http.StatusNotFound},
+		{name: "debate in progress", body: `{"debateId": "running", "language": "fr"}`, want: http.StatusConflict},
+		{name: "translation in progress", body: `{"debateId": "d2", "language": "de"}`, want: http.StatusConflict},
+		{name: "model unavailable", body: `{"debateId": "d1", "language": "fr"}`, want: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/translate-debate", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			HandleTranslateDebate(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestClaimTranslation(t *testing.T) {
+	memStore := store.NewMemoryStore()
+	saveTestDebate(t, memStore, "done", firebase.StatusComplete)
+
+	// Only one of concurrent requests gets a new or finished translation
+	for _, id := range []string{"new", "done"} {
+		t.Run(id, func(t *testing.T) {
+			var wg sync.WaitGroup
+			codes := make([]int, 10)
+			for i := range codes {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					rec := httptest.NewRecorder()
+					if claimTranslation(context.Background(), rec, memStore, &firebase.DebateDocument{ID: id, Status: firebase.StatusInProgress}) {
+						codes[i] = http.StatusOK
+					} else {
+						codes[i] = rec.Code
+					}
+				}()
+			}
+			wg.Wait()
+
+			claimed := 0
+			for _, code := range codes {
+				switch code {
+				case http.StatusOK:
+					claimed++
+				case http.StatusConflict:
+				default:
+					t.Errorf("claimTranslation() sent status %d, want a conflict", code)
+				}
+			}
+			if claimed != 1 {
+				t.Errorf("%d concurrent claims succeeded, want 1", claimed)
+			}
+			if saved, _ := memStore.GetDebate(context.Background(), id); saved.Status != firebase.StatusInProgress {
+				t.Errorf("stored status = %q, want in_progress", saved.Status)
+			}
+		})
+	}
+}
+
+func TestTranslationID(t *testing.T) {
+	original := &firebase.DebateDocument{ID: "d1"}
+	french := &firebase.DebateDocument{ID: translationID(original, "fr"), Language: "fr", TranslatedFrom: "d1"}
+
+	if translationID(original, "fr") == translationID(original, "de") {
+		t.Error("translations into different languages share an ID")
+	}
+	if translationID(french, "de") != translationID(original, "de") {
+		t.Error("translating a translation does not key it by the original debate")
+	}
+}
+
+func TestTranslateMessagesPhases(t *testing.T) {
+	client := NewClaudeClientWithModel(llm.NewFake(
+		`{"speaker":"phase","text":"opening"}`+"\n",
+		`{"speaker":"moderator","text":"Bienvenue."}`,
+	))
+	source := &firebase.DebateDocument{
+		Format:   FormatOxford,
+		Messages: []firebase.Message{{PanelistID: "moderator", Text: "Welcome.", Phase: "opening"}},
+	}
+
+	var output chunkRecorder
+	if err := client.TranslateMessages(context.Background(), source, "fr", &output); err != nil {
+		t.Fatalf("TranslateMessages() error = %v", err)
+	}
+
+	phases := phaseChunks(output.chunks)
+	if len(phases) != 1 || phases[0].Phase != "opening" {
+		t.Errorf("phase chunks = %+v, want the opening phase", phases)
+	}
+	assertMessages(t, mergeMessages(output.chunks), []StreamChunk{
+		{PanelistID: "moderator", Text: "Bienvenue."},
+	})
+}
@@ -0,0 +1,229 @@
+package generatedebate
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/llm"
+)
+
+// conclusionMaxTokens bounds the follow-up generating a missing conclusion
+const conclusionMaxTokens = 1024
+
+// balanceRatio is how many times more words a panelist may speak than
+// another before the debate is reported as unbalanced
+const balanceRatio = 2
+
+// turnChecker tracks the turns of a debate as it streams. It maps speaker IDs
+// the model misspelled to the panelists they designate, drops the messages of
+// speakers matching no panelist, records the turns
+// for the airtime report, and holds back the done chunk until the debate is
+// known to be concluded.
+type turnChecker struct {
+	next      ChunkEmitter
+	panelists []Panelist
+	keys      map[string]string  // Normalized IDs and names to speaker IDs
+	opening   bool               // The debate must open with the moderator
+	turns     []firebase.Message // Phase markers included
+	unknown   []string           // Speaker IDs matching no panelist
+	done      *StreamChunk
+}
+
+// newTurnChecker creates a checker for the speakers of req
+func newTurnChecker(next ChunkEmitter, req *DebateRequest, opening bool) *turnChecker {
+	c := &turnChecker{
+		next:      next,
+		panelists: req.SelectedPanelists,
+		keys:      make(map[string]string),
+		opening:   opening,
+	}
+	// The audience only speaks through interjections, never through the model
+	for _, id := range []string{"moderator", phaseSpeaker} {
+		c.keys[speakerKey(id)] = id
+	}
+	for _, p := range req.SelectedPanelists {
+		for _, name := range []string{p.ID, p.Name} {
+			if key := speakerKey(name); key != "" && c.keys[key] == "" {
+				c.keys[key] = p.ID
+			}
+		}
+	}
+	return c
+}
+
+// speakerKey normalizes a speaker ID or name for near-miss matching: case,
+// spacing, hyphenation and punctuation are ignored
+func speakerKey(id string) string {
+	var key strings.Builder
+	for _, r := range strings.ToLower(id) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			key.WriteRune(r)
+		}
+	}
+	return key.String()
+}
+
+// resolve returns the speaker ID designated by id, and false if it matches
+// no speaker
+func (c *turnChecker) resolve(id string) (string, bool) {
+	speaker, ok := c.keys[speakerKey(id)]
+	if !ok {
+		if !slices.Contains(c.unknown, id) {
+			log.Printf("Dropping messages of unknown speaker %q", id)
+			c.unknown = append(c.unknown, id)
+		}
+		return id, false
+	}
+	if speaker != id {
+		log.Printf("Mapped speaker ID %q to %q", id, speaker)
+		c.keys[speakerKey(id)] = speaker
+	}
+	return speaker, true
+}
+
+// Emit corrects the speaker of message chunks, drops those of unknown
+// speakers, records turns and holds back the done chunk
+func (c *turnChecker) Emit(chunk StreamChunk) {
+	switch {
+	case chunk.Type == "done":
+		c.done = &chunk
+		return
+	case chunk.Type == "message" && chunk.PanelistID != "":
+		speaker, ok := c.resolve(chunk.PanelistID)
+		if !ok {
+			return
+		}
+		chunk.PanelistID = speaker
+		c.record(chunk)
+	}
+
+	c.next.Emit(chunk)
+}
+
+// record adds the text of a message chunk to the turns of the debate
+func (c *turnChecker) record(chunk StreamChunk) {
+	if n := len(c.turns); n > 0 && c.turns[n-1].PanelistID == chunk.PanelistID {
+		c.turns[n-1].Text += chunk.Text
+		return
+	}
+	c.turns = append(c.turns, firebase.Message{PanelistID: chunk.PanelistID, Text: chunk.Text})
+}
+
+// messages returns the turns recorded so far, in the phases announced by the
+// phase markers
+func (c *turnChecker) messages() []firebase.Message {
+	var messages []firebase.Message
+	phase := ""
+	for _, turn := range c.turns {
+		if turn.PanelistID == phaseSpeaker {
+			phase = strings.TrimSpace(turn.Text)
+			continue
+		}
+		turn.Text = strings.TrimSpace(turn.Text)
+		turn.Phase = phase
+		messages = append(messages, turn)
+	}
+	return messages
+}
+
+// concluded returns whether the debate ends with the moderator
+func (c *turnChecker) concluded() bool {
+	messages := c.messages()
+	return len(messages) > 0 && messages[len(messages)-1].PanelistID == "moderator"
+}
+
+// finish reports the format issues of the debate in an issues chunk, to be
+// stored with it, and forwards the done chunk held back, if the stream completed
+func (c *turnChecker) finish() {
+	if issues := c.issues(); len(issues) > 0 {
+		log.Printf("Debate format issues: %s", strings.Join(issues, "; "))
+		c.next.Emit(StreamChunk{Type: "issues", Issues: issues})
+	}
+	if c.done != nil {
+		c.next.Emit(*c.done)
+		c.done = nil
+	}
+}
+
+// issues reports how the debate departs from the expected format and how
+// unevenly the panelists were given the floor
+func (c *turnChecker) issues() []string {
+	var issues []string
+
+	messages := c.messages()
+	if c.opening && len(messages) > 0 && messages[0].PanelistID != "moderator" {
+		issues = append(issues, fmt.Sprintf("opens with %s instead of the moderator", messages[0].PanelistID))
+	}
+	if !c.concluded() {
+		issues = append(issues, "does not end with the moderator")
+	}
+	for _, id := range c.unknown {
+		issues = append(issues, fmt.Sprintf("dropped messages of unknown speaker %q", id))
+	}
+
+	// Compare the airtime of the generated panelists
+	words := make(map[string]int)
+	for _, msg := range messages {
+		words[msg.PanelistID] += len(strings.Fields(msg.Text))
+	}
+	most, least := "", ""
+	for _, p := range c.panelists {
+		if p.Human {
+			continue
+		}
+		if words[p.ID] == 0 {
+			issues = append(issues, fmt.Sprintf("%s never speaks", p.ID))
+			continue
+		}
+		if most == "" || words[p.ID] > words[most] {
+			most = p.ID
+		}
+		if least == "" || words[p.ID] < words[least] {
+			least = p.ID
+		}
+	}
+	if least != "" && words[most] > balanceRatio*words[least] {
+		issues = append(issues, fmt.Sprintf("%s speaks %d words, %s only %d", most, words[most], least, words[least]))
+	}
+
+	return issues
+}
+
+// concludeDebate asks the model for the moderator's last message of a debate
+// whose response to request ended without it, and streams it to emitter
+func (c *ClaudeClient) concludeDebate(ctx context.Context, request llm.Request, req *DebateRequest, checker *turnChecker, emitter ChunkEmitter) error {
+	mode := outputMode(req)
+
+	moderatorTurn := "[moderator]:"
+	if mode == OutputModeStructured {
+		moderatorTurn = `{"speaker":"moderator"}`
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString("The debate stopped before the moderator's last message.\n")
+	prompt.WriteString(fmt.Sprintf("- Write only that message now, starting with %s, as instructed\n", moderatorTurn))
+	prompt.WriteString("- Do not add any panelist message\n")
+	prompt.WriteString("- Use exactly the same format as before, with no extra text")
+
+	followUp := llm.Request{
+		System: request.System,
+		Messages: append(slices.Clone(request.Messages),
+			llm.Message{Role: llm.RoleAssistant, Text: formatTranscript(checker.messages(), mode)},
+			llm.Message{Role: llm.RoleUser, Text: prompt.String()},
+		),
+		MaxTokens: conclusionMaxTokens,
+	}
+
+	stream := c.model.Stream(ctx, followUp)
+	defer stream.Close()
+
+	if mode == OutputModeStructured {
+		return c.streamStructuredResponse(stream, emitter)
+	}
+	return c.streamResponse(stream, emitter)
+}
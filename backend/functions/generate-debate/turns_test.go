@@ -0,0 +1,247 @@
+package generatedebate
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/llm"
+)
+
+// testPanel is the panel of the turn checker tests
+var testPanel = []Panelist{
+	{ID: "augustine", Name: "Augustine of Hippo"},
+	{ID: "mlk", Name: "Martin Luther King Jr."},
+}
+
+func TestGenerateDebateMapsSpeakers(t *testing.T) {
+	client := NewClaudeClientWithModel(llm.NewFake(
+		"[Moderator]: Welcome.\n",
+		"[Augustine of Hippo]: Peace.\n",
+		"[MLK]: Justice.\n",
+		"[Phase]: closing\n",
+		"[moderator]: Farewell.",
+	))
+
+	var output chunkRecorder
+	if err := client.GenerateDebate(context.Background(), &DebateRequest{Topic: "Is war ever just?", SelectedPanelists: testPanel}, &output); err != nil {
+		t.Fatalf("GenerateDebate() error = %v", err)
+	}
+
+	assertMessages(t, mergeMessages(output.chunks), []StreamChunk{
+		{PanelistID: "moderator", Text: "Welcome."},
+		{PanelistID: "augustine", Text: "Peace."},
+		{PanelistID: "mlk", Text: "Justice."},
+		{PanelistID: "moderator", Text: "Farewell."},
+	})
+	if phases := phaseChunks(output.chunks); len(phases) != 1 || phases[0].Phase != "closing" {
+		t.Errorf("phase chunks = %+v, want the closing phase", phases)
+	}
+}
+
+func TestGenerateDebateDropsAudience(t *testing.T) {
+	client := NewClaudeClientWithModel(llm.NewFake(
+		"[moderator]: Welcome.\n",
+		"[audience]: What about civil disobedience?\n",
+		"[augustine]: Peace.\n",
+		"[moderator]: Farewell.",
+	))
+
+	var output chunkRecorder
+	if err := client.GenerateDebate(context.Background(), &DebateRequest{Topic: "Is war ever just?", SelectedPanelists: testPanel}, &output); err != nil {
+		t.Fatalf("GenerateDebate() error = %v", err)
+	}
+
+	// Only interjections submitted by users are audience messages
+	assertMessages(t, mergeMessages(output.chunks), []StreamChunk{
+		{PanelistID: "moderator", Text: "Welcome."},
+		{PanelistID: "augustine", Text: "Peace."},
+		{PanelistID: "moderator", Text: "Farewell."},
+	})
+}
+
+func TestGenerateDebateConcludes(t *testing.T) {
+	fake := llm.NewFakeScripts(
+		llm.Script{Deltas: []string{"[moderator]: Welcome.\n[augustine]: Peace.\n[mlk]: Justice."}},
+		llm.Script{Deltas: []string{"[moderator]: Thank you both."}},
+	)
+	client := NewClaudeClientWithModel(fake)
+
+	var output chunkRecorder
+	if err := client.GenerateDebate(context.Background(), &DebateRequest{Topic: "Is war ever just?", SelectedPanelists: testPanel}, &output); err != nil {
+		t.Fatalf("GenerateDebate() error = %v", err)
+	}
+
+	assertMessages(t, mergeMessages(output.chunks), []StreamChunk{
+		{PanelistID: "moderator", Text: "Welcome."},
+		{PanelistID: "augustine", Text: "Peace."},
+		{PanelistID: "mlk", Text: "Justice."},
+		{PanelistID: "moderator", Text: "Thank you both."},
+	})
+
+	// The follow-up replays the debate so far and asks for the conclusion
+	requests := fake.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("%d model requests, want the debate and its conclusion", len(requests))
+	}
+	followUp := requests[1].Messages
+	if len(followUp) != 3 || followUp[1].Role != llm.RoleAssistant || !strings.Contains(followUp[1].Text, "[mlk]: Justice.") {
+		t.Errorf("follow-up messages = %+v, want prompt, debate and conclusion request", followUp)
+	}
+	if !strings.Contains(followUp[2].Text, "moderator's last message") {
+		t.Errorf("conclusion prompt = %q", followUp[2].Text)
+	}
+
+	// The stream ends once, after the conclusion
+	var done int
+	for _, chunk := range output.chunks {
+		if chunk.Type == "done" {
+			done++
+		}
+	}
+	if done != 1 || output.chunks[len(output.chunks)-1].Type != "done" {
+		t.Errorf("%d done chunks, want 1 at the end", done)
+	}
+}
+
+func TestGenerateDebateConclusionFailure(t *testing.T) {
+	client := NewClaudeClientWithModel(llm.NewFakeScripts(
+		llm.Script{Deltas: []string{"[moderator]: Welcome.\n[augustine]: Peace."}},
+		llm.Script{Err: errors.New("overloaded")},
+	))
+
+	// The debate is kept as generated
+	var output chunkRecorder
+	if err := client.GenerateDebate(context.Background(), &DebateRequest{Topic: "Is war ever just?", SelectedPanelists: testPanel}, &output); err != nil {
+		t.Fatalf("GenerateDebate() error = %v", err)
+	}
+	assertMessages(t, mergeMessages(output.chunks), []StreamChunk{
+		{PanelistID: "moderator", Text: "Welcome."},
+		{PanelistID: "augustine", Text: "Peace."},
+	})
+	if last := output.chunks[len(output.chunks)-1]; last.Type != "done" {
+		t.Errorf("last chunk = %+v, want done", last)
+	}
+}
+
+func TestGenerateDebateStoresIssues(t *testing.T) {
+	client := NewClaudeClientWithModel(llm.NewFake("[moderator]: Welcome.\n", "[augustine]: Peace.\n", "[moderator]: Farewell."))
+
+	// The issues reach the client and the stored debate, before the stream ends
+	var output chunkRecorder
+	acc := NewDebateAccumulator("debate", "Is war ever just?", testPanel)
+	if err := client.GenerateDebate(context.Background(), &DebateRequest{Topic: "Is war ever just?", SelectedPanelists: testPanel}, &AccumulatingEmitter{next: &output, accumulator: acc}); err != nil {
+		t.Fatalf("GenerateDebate() error = %v", err)
+	}
+
+	n := len(output.chunks)
+	if n < 2 || output.chunks[n-2].Type != "issues" || output.chunks[n-1].Type != "done" {
+		t.Fatalf("chunks = %+v, want issues then done", output.chunks)
+	}
+	if issues := output.chunks[n-2].Issues; len(issues) != 1 || issues[0] != "mlk never speaks" {
+		t.Errorf("issues = %q, want mlk never speaks", issues)
+	}
+	if doc := acc.Document(firebase.StatusComplete, ""); len(doc.FormatIssues) != 1 {
+		t.Errorf("stored issues = %q, want the reported issue", doc.FormatIssues)
+	}
+}
+
+func TestTurnCheckerIssues(t *testing.T) {
+	tests := []struct {
+		name    string
+		opening bool
+		turns   []StreamChunk
+		want    []string
+	}{
+		{
+			name:    "balanced",
+			opening: true,
+			turns: []StreamChunk{
+				{PanelistID: "moderator", Text: "Welcome."},
+				{PanelistID: "augustine", Text: "An unjust law is no law."},
+				{PanelistID: "mlk", Text: "Injustice anywhere threatens justice."},
+				{PanelistID: "moderator", Text: "Thank you."},
+			},
+		},
+		{
+			name:    "opened by a panelist",
+			opening: true,
+			turns: []StreamChunk{
+				{PanelistID: "augustine", Text: "Peace."},
+				{PanelistID: "mlk", Text: "Justice."},
+				{PanelistID: "moderator", Text: "Thank you."},
+			},
+			want: []string{"opens with augustine instead of the moderator"},
+		},
+		{
+			name: "continuation opened by a panelist",
+			turns: []StreamChunk{
+				{PanelistID: "augustine", Text: "Peace."},
+				{PanelistID: "mlk", Text: "Justice."},
+				{PanelistID: "moderator", Text: "Thank you."},
+			},
+		},
+		{
+			name: "unconcluded with an unknown speaker",
+			turns: []StreamChunk{
+				{PanelistID: "augustine", Text: "Peace."},
+				{PanelistID: "aquinas", Text: "Order."},
+				{PanelistID: "mlk", Text: "Justice."},
+			},
+			want: []string{"does not end with the moderator", `dropped messages of unknown speaker "aquinas"`},
+		},
+		{
+			name: "unbalanced",
+			turns: []StreamChunk{
+				{PanelistID: "augustine", Text: "Our heart is restless until it rests in Thee."},
+				{PanelistID: "mlk", Text: "I have a dream."},
+				{PanelistID: "moderator", Text: "Thank you."},
+			},
+			want: []string{"augustine speaks 9 words, mlk only 4"},
+		},
+		{
+			name: "silent panelist",
+			turns: []StreamChunk{
+				{PanelistID: "augustine", Text: "Peace."},
+				{PanelistID: "moderator", Text: "Thank you."},
+			},
+			want: []string{"mlk never speaks"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output chunkRecorder
+			checker := newTurnChecker(&output, &DebateRequest{SelectedPanelists: testPanel}, tt.opening)
+			for _, turn := range tt.turns {
+				turn.Type = "message"
+				checker.Emit(turn)
+			}
+
+			if got := checker.issues(); strings.Join(got, "; ") != strings.Join(tt.want, "; ") {
+				t.Errorf("issues() = %q, want %q", got, tt.want)
+			}
+			for _, chunk := range output.chunks {
+				if chunk.PanelistID == "aquinas" {
+					t.Errorf("forwarded %+v, want unknown speakers dropped", chunk)
+				}
+			}
+		})
+	}
+}
+
+func TestSpeakerKey(t *testing.T) {
+	tests := map[string]string{
+		"martin-luther-king": "martinlutherking",
+		"Martin Luther King": "martinlutherking",
+		"MARTIN_LUTHER_KING": "martinlutherking",
+		"Kierkegaard":        "kierkegaard",
+	}
+	for id, want := range tests {
+		if got := speakerKey(id); got != want {
+			t.Errorf("speakerKey(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
@@ -1,28 +1,122 @@
 package generatedebate
 
+import "github.com/raphink/debate/shared/firebase"
+
 // Panelist represents a debate participant
 type Panelist struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	Tagline   string `json:"tagline"`
-	Bio       string `json:"bio"`
-	AvatarURL string `json:"avatarUrl"`
-	Position  string `json:"position"`
+	ID          string                `json:"id"`
+	Name        string                `json:"name"`
+	Tagline     string                `json:"tagline"`
+	Bio         string                `json:"bio"`
+	AvatarURL   string                `json:"avatarUrl"`
+	Position    string                `json:"position"`
+	Human       bool                  `json:"human,omitempty"`       // Seat taken by the user, who writes this panelist's messages
+	Born        int                   `json:"born,omitempty"`        // Year of birth, negative before Christ
+	Died        int                   `json:"died,omitempty"`        // Year of death, unset for the living
+	Attribution *firebase.Attribution `json:"attribution,omitempty"` // Credits of the portrait, from get-portrait
 }
 
 // DebateRequest represents the incoming request to generate a debate
 type DebateRequest struct {
 	Topic             string     `json:"topic"`
 	SelectedPanelists []Panelist `json:"selectedPanelists"`
+	OutputMode        string     `json:"outputMode,omitempty"`  // "text" (default) or "structured"
+	Interactive       bool       `json:"interactive,omitempty"` // Stop after the opening exchanges to take interjections
+	Format            string     `json:"format,omitempty"`      // "roundtable" (default), "oxford", "socratic" or "cross-examination"
+	Exchanges         int        `json:"exchanges,omitempty"`   // Exchanges between panelists (default depends on the format)
+	TurnLength        string     `json:"turnLength,omitempty"`  // "short", "medium" (default) or "long"
+	Register          string     `json:"register,omitempty"`    // "academic", "popular" or "teen"
+	Tone              string     `json:"tone,omitempty"`        // "combative" or "irenic"
+	Language          string     `json:"language,omitempty"`    // ISO 639-1 code of the debate language (default "en")
+	Judge             bool       `json:"judge,omitempty"`       // Score the panelists once the debate is generated
+	Citations         bool       `json:"citations,omitempty"`   // Panelists cite primary sources (structured output only)
+
+	passages []firebase.Passage // Corpus passages retrieved for the panelists
+}
+
+// ContinueRequest represents the incoming request to extend a saved debate
+type ContinueRequest struct {
+	DebateID   string `json:"debateId"`
+	Exchanges  int    `json:"exchanges,omitempty"`  // Additional exchanges (default 5)
+	OutputMode string `json:"outputMode,omitempty"` // "text" (default) or "structured"
+}
+
+// ReplyRequest represents the message of the human panelist when it is their turn
+type ReplyRequest struct {
+	DebateID   string `json:"debateId"`
+	Text       string `json:"text"`                 // The human panelist's message
+	Exchanges  int    `json:"exchanges,omitempty"`  // Exchanges to generate in response (default 5)
+	OutputMode string `json:"outputMode,omitempty"` // "text" (default) or "structured"
+}
+
+// InterjectRequest represents an audience interjection between the turns of a
+// saved debate. The next exchanges are generated in response to it.
+type InterjectRequest struct {
+	DebateID   string `json:"debateId"`
+	Kind       string `json:"kind"`                 // "audience" (default) or "moderator"
+	Text       string `json:"text"`                 // The question, or what the moderator should ask
+	Exchanges  int    `json:"exchanges,omitempty"`  // Exchanges to generate in response (default 3)
+	OutputMode string `json:"outputMode,omitempty"` // "text" (default) or "structured"
+}
+
+// Interjection kinds
+const (
+	InterjectionAudience  = "audience"  // A question from the audience, stored in the debate
+	InterjectionModerator = "moderator" // A question the moderator asks the panel
+)
+
+// ForkRequest represents the incoming request to fork a saved debate.
+// Messages before AtSequence are copied to the new debate and the rest is
+// generated again with the updated panel.
+type ForkRequest struct {
+	DebateID          string     `json:"debateId"`
+	AtSequence        *int       `json:"atSequence,omitempty"`        // Defaults to after the last message
+	AddPanelists      []Panelist `json:"addPanelists,omitempty"`      // Panelists joining the fork
+	RemovePanelists   []string   `json:"removePanelists,omitempty"`   // IDs of panelists leaving the fork
+	ModeratorQuestion string     `json:"moderatorQuestion,omitempty"` // Question the moderator opens the fork with
+	Exchanges         int        `json:"exchanges,omitempty"`         // Exchanges to generate (default 5)
+	OutputMode        string     `json:"outputMode,omitempty"`        // "text" (default) or "structured"
+}
+
+// TranslateRequest represents the incoming request to translate a saved
+// debate. The translation is stored as a separate debate, linked to the
+// original and keyed by language.
+type TranslateRequest struct {
+	DebateID string `json:"debateId"`
+	Language string `json:"language"` // ISO 639-1 code of the target language
 }
 
+// CheckRequest represents the incoming request to check a saved debate for
+// anachronisms and out-of-character claims
+type CheckRequest struct {
+	DebateID string `json:"debateId"`
+}
+
+// Limits on the number of exchanges added by a continuation
+const (
+	DefaultContinueExchanges  = 5
+	MaxContinueExchanges      = 12
+	DefaultInterjectExchanges = 3
+)
+
+// Output modes for the model response
+const (
+	OutputModeText       = "text"       // [ID]: text protocol
+	OutputModeStructured = "structured" // one JSON object per turn
+)
+
 // StreamChunk represents a single chunk of the streaming response
 type StreamChunk struct {
-	Type       string `json:"type"`            // "message", "error", "done"
-	PanelistID string `json:"panelistId"`      // ID of the speaking panelist
-	Text       string `json:"text"`            // Partial or complete text
-	Done       bool   `json:"done"`            // Whether streaming is complete
-	Error      string `json:"error,omitempty"` // Error message if type="error"
+	Type       string   `json:"type"`                // "message", "phase", "turn", "issues", "error", "done"
+	PanelistID string   `json:"panelistId"`          // ID of the speaking panelist
+	Text       string   `json:"text"`                // Partial or complete text
+	Done       bool     `json:"done"`                // Whether streaming is complete
+	Error      string   `json:"error,omitempty"`     // Error message if type="error"
+	Addresses  []string `json:"addresses,omitempty"` // IDs the speaker responds to (structured mode)
+	Phase      string   `json:"phase,omitempty"`     // Phase ID if type="phase", with its title as text
+	Issues     []string `json:"issues,omitempty"`    // Format issues of the debate if type="issues"
+
+	Citations []firebase.Citation `json:"citations,omitempty"` // Sources of the message (citation mode)
 }
 
 // ErrorResponse represents an error response from the API
@@ -40,4 +134,9 @@ const (
 	ErrRateLimitExceeded  = "RATE_LIMIT_EXCEEDED"
 	ErrInternalError      = "INTERNAL_ERROR"
 	ErrServiceUnavailable = "SERVICE_UNAVAILABLE"
+	ErrStreamNotFound     = "STREAM_NOT_FOUND"
+	ErrDebateNotFound     = "DEBATE_NOT_FOUND"
+	ErrDebateInProgress   = "DEBATE_IN_PROGRESS"
+	ErrNotHumanTurn       = "NOT_HUMAN_TURN"
+	ErrAwaitingHuman      = "AWAITING_HUMAN"
 )
@@ -2,7 +2,14 @@ package generatedebate
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
 	"strings"
+	"unicode/utf8"
+
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/language"
+	"github.com/raphink/debate/shared/sanitize"
 )
 
 // ValidateDebateRequest validates the debate generation request
@@ -11,12 +18,12 @@ func ValidateDebateRequest(req *DebateRequest) error {
 		return errors.New("request body is required")
 	}
 
-	// Validate topic
-	topic := strings.TrimSpace(req.Topic)
-	if len(topic) < 10 {
+	// Validate topic, counting characters rather than bytes
+	length := utf8.RuneCountInString(strings.TrimSpace(req.Topic))
+	if length < 10 {
 		return errors.New("topic must be at least 10 characters")
 	}
-	if len(topic) > 500 {
+	if length > 500 {
 		return errors.New("topic must not exceed 500 characters")
 	}
 
@@ -29,11 +36,236 @@ func ValidateDebateRequest(req *DebateRequest) error {
 	}
 
 	// Validate each panelist
+	humans := 0
 	for _, panelist := range req.SelectedPanelists {
 		if panelist.ID == "" || panelist.Name == "" {
 			return errors.New("all panelists must have id and name")
 		}
+		if err := validateAttribution(panelist.Attribution); err != nil {
+			return err
+		}
+		if panelist.Human {
+			humans++
+		}
+	}
+	if humans > 1 {
+		return errors.New("only one panelist can be human")
+	}
+	if req.Judge && req.Interactive {
+		return errors.New("interactive debates cannot be judged")
+	}
+
+	// Citations are fields of the structured output
+	if req.Citations {
+		if req.OutputMode == OutputModeText {
+			return errors.New("citations require the structured output mode")
+		}
+		req.OutputMode = OutputModeStructured
+	}
+
+	if _, ok := formats[req.Format]; req.Format != "" && !ok {
+		return fmt.Errorf("unknown debate format %q", req.Format)
+	}
+
+	// Validate length, register and tone
+	if req.Exchanges != 0 && (req.Exchanges < MinDebateExchanges || req.Exchanges > MaxDebateExchanges) {
+		return fmt.Errorf("exchanges must be between %d and %d", MinDebateExchanges, MaxDebateExchanges)
+	}
+	if _, ok := turnLengths[req.TurnLength]; req.TurnLength != "" && !ok {
+		return errors.New("turnLength must be short, medium or long")
+	}
+	if _, ok := registers[req.Register]; req.Register != "" && !ok {
+		return errors.New("register must be academic, popular or teen")
+	}
+	if _, ok := tones[req.Tone]; req.Tone != "" && !ok {
+		return errors.New("tone must be combative or irenic")
+	}
+	if err := language.Validate(req.Language); err != nil {
+		return err
+	}
+
+	return validateOutputMode(req.OutputMode)
+}
+
+// validateAttribution sanitizes the portrait attribution of a panelist,
+// whose links must be web URLs
+func validateAttribution(attribution *firebase.Attribution) error {
+	if attribution == nil {
+		return nil
+	}
+
+	attribution.License = sanitize.SanitizeTextField(attribution.License)
+	attribution.Artist = sanitize.SanitizeTextField(attribution.Artist)
+	if len(attribution.License) > 100 || len(attribution.Artist) > 500 {
+		return errors.New("portrait attribution is too long")
+	}
+
+	for _, link := range []string{attribution.LicenseURL, attribution.SourceURL} {
+		if link == "" {
+			continue
+		}
+		u, err := url.Parse(link)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid portrait attribution link %q", link)
+		}
+	}
+	return nil
+}
+
+// ValidateContinueRequest validates a continuation request and applies defaults
+func ValidateContinueRequest(req *ContinueRequest) error {
+	if req == nil {
+		return errors.New("request body is required")
+	}
+
+	if strings.TrimSpace(req.DebateID) == "" {
+		return errors.New("debateId is required")
+	}
+
+	if req.Exchanges == 0 {
+		req.Exchanges = DefaultContinueExchanges
+	}
+	if req.Exchanges < 1 || req.Exchanges > MaxContinueExchanges {
+		return fmt.Errorf("exchanges must be between 1 and %d", MaxContinueExchanges)
+	}
+
+	return validateOutputMode(req.OutputMode)
+}
+
+// ValidateReplyRequest validates the human panelist's reply and applies defaults
+func ValidateReplyRequest(req *ReplyRequest) error {
+	if req == nil {
+		return errors.New("request body is required")
+	}
+
+	if strings.TrimSpace(req.DebateID) == "" {
+		return errors.New("debateId is required")
+	}
+
+	req.Text = sanitize.SanitizeTextField(req.Text)
+	if req.Text == "" {
+		return errors.New("text is required")
+	}
+	if len(req.Text) > 2000 {
+		return errors.New("text must not exceed 2000 characters")
+	}
+
+	if req.Exchanges == 0 {
+		req.Exchanges = DefaultContinueExchanges
+	}
+	if req.Exchanges < 1 || req.Exchanges > MaxContinueExchanges {
+		return fmt.Errorf("exchanges must be between 1 and %d", MaxContinueExchanges)
+	}
+
+	return validateOutputMode(req.OutputMode)
+}
+
+// ValidateInterjectRequest validates an interjection and applies defaults
+func ValidateInterjectRequest(req *InterjectRequest) error {
+	if req == nil {
+		return errors.New("request body is required")
+	}
+
+	if strings.TrimSpace(req.DebateID) == "" {
+		return errors.New("debateId is required")
+	}
+
+	switch req.Kind {
+	case "":
+		req.Kind = InterjectionAudience
+	case InterjectionAudience, InterjectionModerator:
+	default:
+		return errors.New("kind must be audience or moderator")
+	}
+
+	req.Text = sanitize.SanitizeTextField(req.Text)
+	if req.Text == "" {
+		return errors.New("text is required")
+	}
+	if len(req.Text) > 500 {
+		return errors.New("text must not exceed 500 characters")
+	}
+
+	if req.Exchanges == 0 {
+		req.Exchanges = DefaultInterjectExchanges
+	}
+	if req.Exchanges < 1 || req.Exchanges > MaxContinueExchanges {
+		return fmt.Errorf("exchanges must be between 1 and %d", MaxContinueExchanges)
+	}
+
+	return validateOutputMode(req.OutputMode)
+}
+
+// ValidateForkRequest validates a fork request and applies defaults.
+// The resulting panel is validated once the source debate is loaded.
+func ValidateForkRequest(req *ForkRequest) error {
+	if req == nil {
+		return errors.New("request body is required")
+	}
+
+	if strings.TrimSpace(req.DebateID) == "" {
+		return errors.New("debateId is required")
+	}
+
+	if req.AtSequence != nil && *req.AtSequence < 0 {
+		return errors.New("atSequence must be >= 0")
+	}
+
+	for _, panelist := range req.AddPanelists {
+		if err := validateAttribution(panelist.Attribution); err != nil {
+			return err
+		}
+	}
+
+	req.ModeratorQuestion = sanitize.SanitizeTextField(req.ModeratorQuestion)
+	if len(req.ModeratorQuestion) > 500 {
+		return errors.New("moderatorQuestion must not exceed 500 characters")
+	}
+
+	if req.Exchanges == 0 {
+		req.Exchanges = DefaultContinueExchanges
+	}
+	if req.Exchanges < 1 || req.Exchanges > MaxContinueExchanges {
+		return fmt.Errorf("exchanges must be between 1 and %d", MaxContinueExchanges)
+	}
+
+	return validateOutputMode(req.OutputMode)
+}
+
+// ValidateTranslateRequest validates a translation request
+func ValidateTranslateRequest(req *TranslateRequest) error {
+	if req == nil {
+		return errors.New("request body is required")
 	}
 
+	if strings.TrimSpace(req.DebateID) == "" {
+		return errors.New("debateId is required")
+	}
+
+	if req.Language == "" {
+		return errors.New("language is required")
+	}
+	return language.Validate(req.Language)
+}
+
+// ValidateCheckRequest validates a fidelity check request
+func ValidateCheckRequest(req *CheckRequest) error {
+	if req == nil {
+		return errors.New("request body is required")
+	}
+
+	if strings.TrimSpace(req.DebateID) == "" {
+		return errors.New("debateId is required")
+	}
 	return nil
 }
+
+// validateOutputMode checks that mode is a known output mode (or empty for the default)
+func validateOutputMode(mode string) error {
+	switch mode {
+	case "", OutputModeText, OutputModeStructured:
+		return nil
+	default:
+		return errors.New("outputMode must be text or structured")
+	}
+}
@@ -0,0 +1,30 @@
+package generatedebate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateDebateRequestTopicLength(t *testing.T) {
+	tests := []struct {
+		name    string
+		topic   string
+		wantErr bool
+	}{
+		{name: "too short", topic: "Is war?", wantErr: true},
+		{name: "maximum length", topic: strings.Repeat("a", 500)},
+		{name: "too long", topic: strings.Repeat("a", 501), wantErr: true},
+		{name: "maximum length in Greek", topic: strings.Repeat("α", 500)}, // 1000 bytes
+		{name: "too long in Japanese", topic: strings.Repeat("論", 501), wantErr: true},
+		{name: "too short in Japanese", topic: "戦争は正義か", wantErr: true}, // 18 bytes
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &DebateRequest{Topic: tt.topic, SelectedPanelists: testPanel}
+			if err := ValidateDebateRequest(req); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDebateRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package getdebate
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"os"
@@ -10,7 +11,7 @@ import (
 
 	_ "github.com/GoogleCloudPlatform/functions-framework-go/funcframework"
 	"github.com/google/uuid"
-	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/store"
 )
 
 var allowedOrigin string
@@ -22,10 +23,10 @@ func init() {
 	}
 	log.Printf("ALLOWED_ORIGIN set to: %s", allowedOrigin)
 
-	// Initialize Firestore client
+	// Initialize debate store
 	ctx := context.Background()
-	if err := firebase.InitFirestore(ctx); err != nil {
-		log.Printf("Failed to initialize Firestore: %v", err)
+	if _, err := store.Default(ctx); err != nil {
+		log.Printf("Failed to initialize debate store: %v", err)
 	}
 }
 
@@ -69,12 +70,22 @@ func HandleGetDebate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Retrieve debate from Firestore
+	// Retrieve debate from the store
 	ctx := r.Context()
-	debate, err := firebase.GetDebate(ctx, debateID)
+	debateStore, err := store.Default(ctx)
+	if err != nil {
+		log.Printf("Failed to initialize debate store: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": "Failed to load debate",
+		})
+		return
+	}
+
+	debate, err := debateStore.GetDebate(ctx, debateID)
 	if err != nil {
 		// Check if it's a not found error
-		if strings.Contains(err.Error(), "not found") {
+		if errors.Is(err, store.ErrNotFound) {
 			log.Printf("Debate not found: %s", debateID)
 			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(map[string]string{
@@ -1,39 +1,186 @@
 package getportrait
 
 import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
 	"sync"
+	"time"
+
+	"github.com/raphink/debate/shared/firebase"
+)
+
+// Cache backends, selected with PORTRAIT_CACHE
+const (
+	CacheMemory = "memory" // In-process LRU, lost on cold starts (default)
+	CacheDisk   = "disk"   // JSON files in PORTRAIT_CACHE_DIR
+	CacheStore  = "store"  // Firestore "portraits" collection, shared by all instances
+)
+
+// Cache defaults, overridden with PORTRAIT_CACHE_SIZE, PORTRAIT_HIT_TTL and
+// PORTRAIT_MISS_TTL
+const (
+	defaultCacheSize = 1000
+	defaultHitTTL    = 30 * 24 * time.Hour
+	defaultMissTTL   = 24 * time.Hour
 )
 
-// PortraitCache provides thread-safe in-memory caching of portrait URLs
+// Entry is a cached portrait lookup
+type Entry struct {
+	PortraitURL string                `json:"portraitUrl" firestore:"portraitUrl"`               // Empty for panelists without a portrait
+	EntityID    string                `json:"entityId,omitempty" firestore:"entityId,omitempty"` // Wikidata entity the portrait was resolved to
+	Attribution *firebase.Attribution `json:"attribution,omitempty" firestore:"attribution,omitempty"`
+	StoredAt    time.Time             `json:"storedAt" firestore:"storedAt"`
+}
+
+// Cache stores portrait lookups by key
+type Cache interface {
+	// Get returns the entry stored under key, if any
+	Get(ctx context.Context, key string) (Entry, bool, error)
+	// Set creates or replaces the entry stored under key
+	Set(ctx context.Context, key string, entry Entry) error
+}
+
+// Stats counts the lookups of a PortraitCache
+type Stats struct {
+	Hits         int64 `json:"hits"`         // Portraits served from the cache
+	NegativeHits int64 `json:"negativeHits"` // Panelists served as known to have no portrait
+	Misses       int64 `json:"misses"`       // Lookups not in the cache
+	Expired      int64 `json:"expired"`      // Lookups in the cache but past their TTL
+	Errors       int64 `json:"errors"`       // Failed cache reads and writes
+}
+
+// PortraitCache caches portrait lookups in a Cache. Portraits expire after
+// hitTTL; panelists without a portrait are looked up again after missTTL.
 type PortraitCache struct {
-	cache map[string]string
-	mu    sync.RWMutex
+	cache   Cache
+	hitTTL  time.Duration
+	missTTL time.Duration
+	mu      sync.Mutex
+	stats   Stats
+}
+
+// NewPortraitCache creates a portrait cache storing lookups in cache
+func NewPortraitCache(cache Cache, hitTTL, missTTL time.Duration) *PortraitCache {
+	return &PortraitCache{cache: cache, hitTTL: hitTTL, missTTL: missTTL}
 }
 
-// NewPortraitCache creates a new portrait cache
-func NewPortraitCache() *PortraitCache {
-	return &PortraitCache{
-		cache: make(map[string]string),
+// Get returns the cached portrait of a panelist, with an empty URL if the
+// panelist is known to have no portrait
+func (pc *PortraitCache) Get(ctx context.Context, key string) (Entry, bool) {
+	entry, found, err := pc.cache.Get(ctx, key)
+	if err != nil {
+		log.Printf("Failed to read portrait cache for %s: %v", key, err)
+		pc.count(&pc.stats.Errors)
+		return Entry{}, false
+	}
+	if !found {
+		pc.count(&pc.stats.Misses)
+		return Entry{}, false
+	}
+
+	ttl := pc.hitTTL
+	if entry.PortraitURL == "" {
+		ttl = pc.missTTL
+	}
+	if time.Since(entry.StoredAt) > ttl {
+		pc.count(&pc.stats.Expired)
+		return Entry{}, false
 	}
+
+	if entry.PortraitURL == "" {
+		pc.count(&pc.stats.NegativeHits)
+	} else {
+		pc.count(&pc.stats.Hits)
+	}
+	return entry, true
 }
 
-// Get retrieves a portrait URL from cache
-// Returns empty string if not found
-func (pc *PortraitCache) Get(panelistID string) (string, bool) {
-	pc.mu.RLock()
-	defer pc.mu.RUnlock()
+// Set caches the portrait of a panelist, with an empty URL if none was found
+func (pc *PortraitCache) Set(ctx context.Context, key string, entry Entry) {
+	entry.StoredAt = time.Now()
+	if err := pc.cache.Set(ctx, key, entry); err != nil {
+		log.Printf("Failed to write portrait cache for %s: %v", key, err)
+		pc.count(&pc.stats.Errors)
+	}
+}
 
-	url, found := pc.cache[panelistID]
-	return url, found
+// Stats returns the lookup counts since the cache was created
+func (pc *PortraitCache) Stats() Stats {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.stats
 }
 
-// Set stores a portrait URL in cache
-func (pc *PortraitCache) Set(panelistID, portraitURL string) {
+// count increments a counter of the stats
+func (pc *PortraitCache) count(counter *int64) {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
+	*counter++
+}
 
-	pc.cache[panelistID] = portraitURL
+// NewCache opens the cache backend with the given name
+func NewCache(ctx context.Context, backend string) (Cache, error) {
+	switch backend {
+	case "", CacheMemory:
+		return NewLRUCache(envInt("PORTRAIT_CACHE_SIZE", defaultCacheSize)), nil
+	case CacheDisk:
+		dir := os.Getenv("PORTRAIT_CACHE_DIR")
+		if dir == "" {
+			dir = filepath.Join(os.TempDir(), "portraits")
+		}
+		return NewDiskCache(dir)
+	case CacheStore:
+		return NewStoreCache(ctx)
+	default:
+		log.Printf("Unknown PORTRAIT_CACHE %q, using %s", backend, CacheMemory)
+		return NewCache(ctx, CacheMemory)
+	}
 }
 
-// Global cache instance (persists across function invocations in same instance)
-var portraitCache = NewPortraitCache()
+var (
+	portraitCache   *PortraitCache
+	portraitCacheMu sync.Mutex
+)
+
+// defaultPortraitCache returns the process-wide portrait cache (persists
+// across function invocations in the same instance), opened from the
+// environment on first use. It falls back to memory if the backend fails.
+func defaultPortraitCache(ctx context.Context) *PortraitCache {
+	portraitCacheMu.Lock()
+	defer portraitCacheMu.Unlock()
+
+	if portraitCache != nil {
+		return portraitCache
+	}
+
+	backend := os.Getenv("PORTRAIT_CACHE")
+	cache, err := NewCache(ctx, backend)
+	if err != nil {
+		log.Printf("Failed to open %s portrait cache, using %s: %v", backend, CacheMemory, err)
+		cache, _ = NewCache(ctx, CacheMemory)
+	}
+
+	portraitCache = NewPortraitCache(cache,
+		envDuration("PORTRAIT_HIT_TTL", defaultHitTTL),
+		envDuration("PORTRAIT_MISS_TTL", defaultMissTTL))
+	return portraitCache
+}
+
+// envInt returns the positive integer in the environment variable name, or def
+func envInt(name string, def int) int {
+	if n, err := strconv.Atoi(os.Getenv(name)); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
+
+// envDuration returns the duration in the environment variable name, or def
+func envDuration(name string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(name)); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
@@ -0,0 +1,84 @@
+package getportrait
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLRUCacheEvicts(t *testing.T) {
+	ctx := context.Background()
+	cache := NewLRUCache(2)
+	cache.Set(ctx, "augustine", Entry{PortraitURL: "augustine.jpg"})
+	cache.Set(ctx, "aquinas", Entry{PortraitURL: "aquinas.jpg"})
+
+	// Reading augustine makes aquinas the least recently used
+	cache.Get(ctx, "augustine")
+	cache.Set(ctx, "mlk", Entry{PortraitURL: "mlk.jpg"})
+
+	if _, found, _ := cache.Get(ctx, "aquinas"); found {
+		t.Error("aquinas was not evicted")
+	}
+	for _, id := range []string{"augustine", "mlk"} {
+		if _, found, _ := cache.Get(ctx, id); !found {
+			t.Errorf("%s was evicted", id)
+		}
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", cache.Len())
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	ctx := context.Background()
+	cache, err := NewDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDiskCache() error = %v", err)
+	}
+
+	if _, found, err := cache.Get(ctx, "augustine"); found || err != nil {
+		t.Fatalf("Get() on empty cache = %v, %v", found, err)
+	}
+
+	stored := Entry{PortraitURL: "augustine.jpg", StoredAt: time.Now().Truncate(time.Second)}
+	if err := cache.Set(ctx, "augustine", stored); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	entry, found, err := cache.Get(ctx, "augustine")
+	if err != nil || !found {
+		t.Fatalf("Get() = %v, %v", found, err)
+	}
+	if entry.PortraitURL != stored.PortraitURL || !entry.StoredAt.Equal(stored.StoredAt) {
+		t.Errorf("Get() = %+v, want %+v", entry, stored)
+	}
+}
+
+func TestPortraitCacheTTL(t *testing.T) {
+	ctx := context.Background()
+	backend := NewLRUCache(10)
+	cache := NewPortraitCache(backend, 24*time.Hour, time.Hour)
+
+	// Portraits outlive misses of the same age
+	old := time.Now().Add(-2 * time.Hour)
+	backend.Set(ctx, "augustine", Entry{PortraitURL: "augustine.jpg", StoredAt: old})
+	backend.Set(ctx, "unknown", Entry{StoredAt: old})
+	cache.Set(ctx, "nobody", Entry{})
+
+	if entry, found := cache.Get(ctx, "augustine"); !found || entry.PortraitURL != "augustine.jpg" {
+		t.Errorf("Get(augustine) = %+v, %v, want the cached portrait", entry, found)
+	}
+	if _, found := cache.Get(ctx, "unknown"); found {
+		t.Error("expired miss was served")
+	}
+	if entry, found := cache.Get(ctx, "nobody"); !found || entry.PortraitURL != "" {
+		t.Errorf("Get(nobody) = %+v, %v, want a cached miss", entry, found)
+	}
+	if _, found := cache.Get(ctx, "mlk"); found {
+		t.Error("uncached panelist was served")
+	}
+
+	want := Stats{Hits: 1, NegativeHits: 1, Misses: 1, Expired: 1}
+	if got := cache.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
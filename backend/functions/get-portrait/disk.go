@@ -0,0 +1,77 @@
+package getportrait
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// DiskCache stores entries as JSON files in a directory, so they survive
+// restarts of a local server or a container with a mounted volume
+type DiskCache struct {
+	dir string
+}
+
+// NewDiskCache creates a cache in dir, creating the directory if needed
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create portrait cache directory %s: %w", dir, err)
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+// path returns the file of the entry stored under key. Keys are validated
+// panelist IDs followed by a hash, safe to use as file names.
+func (c *DiskCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// Get reads the entry stored under key
+func (c *DiskCache) Get(ctx context.Context, key string) (Entry, bool, error) {
+	data, err := os.ReadFile(c.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Entry{}, false, nil
+	}
+	if err != nil {
+		return Entry{}, false, err
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return Entry{}, false, fmt.Errorf("invalid cache entry %s: %w", key, err)
+	}
+	return entry, true, nil
+}
+
+// Set writes the entry stored under key
+func (c *DiskCache) Set(ctx context.Context, key string, entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(c.dir, key+".json", data)
+}
+
+// writeFileAtomic writes a file in dir through a temporary file renamed into
+// place, so concurrent readers never see a partial file
+func writeFileAtomic(dir, name string, data []byte) error {
+	tmp, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
@@ -2,11 +2,11 @@ package getportrait
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"regexp"
-	"strings"
 )
 
 var (
@@ -14,6 +14,9 @@ var (
 	panelistIDPattern = regexp.MustCompile(`^[a-zA-Z0-9\-]{1,50}$`)
 )
 
+// placeholderPortrait is served for panelists without a portrait
+const placeholderPortrait = "placeholder-avatar.svg"
+
 // HandleGetPortrait is the HTTP handler for the get-portrait Cloud Function
 func HandleGetPortrait(w http.ResponseWriter, r *http.Request) {
 	// Set CORS headers - allow configured origin or localhost for dev
@@ -22,7 +25,7 @@ func HandleGetPortrait(w http.ResponseWriter, r *http.Request) {
 		allowedOrigin = "http://localhost:3000"
 	}
 	w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
-	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
 	// Handle preflight OPTIONS request
@@ -31,72 +34,93 @@ func HandleGetPortrait(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ctx := r.Context()
+	cache := defaultPortraitCache(ctx)
+
+	// GET serves a portrait image, or reports the cache statistics
+	if r.Method == http.MethodGet && r.URL.Query().Has("id") {
+		handleImage(w, r, cache)
+		return
+	}
+	if r.Method == http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(cache.Stats())
+		return
+	}
+
 	// Only accept POST requests
 	if r.Method != http.MethodPost {
-		respondWithError(w, http.StatusMethodNotAllowed, "Only POST method is allowed", ErrInvalidInput, false)
+		respondWithError(w, http.StatusMethodNotAllowed, "Only GET and POST methods are allowed", ErrInvalidInput, false)
 		return
 	}
 
-	// Parse request body
-	var req PortraitRequest
+	// Parse request body, a single panelist or a batch
+	var req struct {
+		PortraitRequest
+		BatchPortraitRequest
+	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		log.Printf("Error decoding request: %v", err)
 		respondWithError(w, http.StatusBadRequest, "Invalid request format", ErrInvalidInput, false)
 		return
 	}
 
-	// Validate input
-	if req.PanelistID == "" || req.PanelistName == "" {
-		respondWithError(w, http.StatusBadRequest, "Panelist ID and name are required", ErrInvalidInput, false)
+	if req.Panelists != nil {
+		handleBatch(w, r, cache, req.Panelists)
 		return
 	}
 
-	if !panelistIDPattern.MatchString(req.PanelistID) {
-		respondWithError(w, http.StatusBadRequest, "Invalid panelist ID format", ErrInvalidInput, false)
+	// Validate input
+	sanitizedName, err := validatePortraitRequest(req.PortraitRequest)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), ErrInvalidInput, false)
 		return
 	}
 
-	if len(req.PanelistName) > 100 {
-		respondWithError(w, http.StatusBadRequest, "Panelist name too long (max 100 characters)", ErrInvalidInput, false)
-		return
-	}
+	respondWithSuccess(w, proxied(r, req.PortraitRequest, lookupPortrait(ctx, cache, req.PortraitRequest, sanitizedName)))
+}
 
-	// Sanitize name
-	sanitizedName := strings.TrimSpace(req.PanelistName)
-	if sanitizedName == "" {
-		respondWithError(w, http.StatusBadRequest, "Panelist name cannot be empty", ErrInvalidInput, false)
+// handleBatch resolves the portraits of several panelists at once
+func handleBatch(w http.ResponseWriter, r *http.Request, cache *PortraitCache, panelists []PortraitRequest) {
+	if len(panelists) == 0 {
+		respondWithError(w, http.StatusBadRequest, "Panelists are required", ErrInvalidInput, false)
 		return
 	}
 
-	// Check cache first
-	if cachedURL, found := portraitCache.Get(req.PanelistID); found {
-		log.Printf("Cache hit for %s", req.PanelistID)
-		respondWithSuccess(w, PortraitResponse{
-			PanelistID:  req.PanelistID,
-			PortraitURL: cachedURL,
-			Cached:      true,
-		})
+	if len(panelists) > maxBatchSize {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Too many panelists (max %d)", maxBatchSize), ErrInvalidInput, false)
 		return
 	}
 
-	// Fetch from Wikimedia
-	wiki := NewWikimediaAPI()
-	portraitURL := wiki.FetchPortraitURL(sanitizedName)
+	names := make([]string, len(panelists))
+	for i, panelist := range panelists {
+		name, err := validatePortraitRequest(panelist)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Panelist %d: %s", i+1, err), ErrInvalidInput, false)
+			return
+		}
+		names[i] = name
+	}
 
-	// Use placeholder if not found
-	if portraitURL == "" {
-		portraitURL = "placeholder-avatar.svg"
+	portraits := lookupPortraits(r.Context(), cache, panelists, names)
+	for _, panelist := range panelists {
+		portraits[panelist.PanelistID] = proxied(r, panelist, portraits[panelist.PanelistID])
 	}
 
-	// Cache the result
-	portraitCache.Set(req.PanelistID, portraitURL)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(BatchPortraitResponse{Portraits: portraits})
+}
 
-	// Return response
-	respondWithSuccess(w, PortraitResponse{
-		PanelistID:  req.PanelistID,
-		PortraitURL: portraitURL,
-		Cached:      false,
-	})
+// proxied points the response to the image proxy if the request asks for it
+// and a portrait was found
+func proxied(r *http.Request, req PortraitRequest, resp PortraitResponse) PortraitResponse {
+	if !req.Proxy || resp.PortraitURL == placeholderPortrait || resp.SourceURL != "" {
+		return resp
+	}
+	resp.SourceURL = resp.PortraitURL
+	resp.PortraitURL = proxiedURL(r, req, resp.SourceURL)
+	return resp
 }
 
 func respondWithError(w http.ResponseWriter, status int, message, code string, retryable bool) {
@@ -0,0 +1,151 @@
+package getportrait
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// useFakeWikimedia routes lookups to a fake Wikimedia server, with an empty
+// portrait cache
+func useFakeWikimedia(t *testing.T, fake *fakeWikimedia) {
+	wiki := newFakeWikimediaAPI(t, fake)
+	oldAPI, oldCache := newWikimediaAPI, portraitCache
+	newWikimediaAPI = func() *WikimediaAPI { return wiki }
+	portraitCache = NewPortraitCache(NewLRUCache(10), time.Hour, time.Hour)
+	t.Cleanup(func() {
+		newWikimediaAPI, portraitCache = oldAPI, oldCache
+	})
+}
+
+// postPortraits sends a request body to the handler
+func postPortraits(t *testing.T, body any) *httptest.ResponseRecorder {
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	HandleGetPortrait(w, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data)))
+	return w
+}
+
+// testWikimedia knows Augustine of Hippo and nobody else
+func testWikimedia() *fakeWikimedia {
+	return &fakeWikimedia{
+		titles: map[string]string{
+			"Augustine of Hippo": `{"query": {"pages": [{"title": "Augustine of Hippo", "pageprops": {"wikibase_item": "Q8018"}}]}}`,
+			"Nobody":             `{"query": {"pages": [{"title": "Nobody", "missing": true}]}}`,
+			augustineFile:        augustineInfo,
+		},
+		entities: map[string]string{"Q8018": `{"entities": {` + augustineEntity + `}}`},
+	}
+}
+
+func TestHandleGetPortraitBatch(t *testing.T) {
+	fake := testWikimedia()
+	useFakeWikimedia(t, fake)
+
+	w := postPortraits(t, BatchPortraitRequest{Panelists: []PortraitRequest{
+		{PanelistID: "augustine", PanelistName: "Augustine of Hippo"},
+		{PanelistID: "nobody", PanelistName: "Nobody"},
+		{PanelistID: "augustine", PanelistName: "Augustine of Hippo"},
+	}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+
+	var resp BatchPortraitResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Portraits["augustine"]; got.EntityID != "Q8018" || got.PortraitURL == placeholderPortrait {
+		t.Errorf("augustine = %+v, want the resolved portrait", got)
+	} else if got.Attribution == nil || got.Attribution.License != "Public domain" {
+		t.Errorf("augustine attribution = %+v, want public domain", got.Attribution)
+	}
+	if got := resp.Portraits["nobody"]; got.PortraitURL != placeholderPortrait {
+		t.Errorf("nobody = %+v, want the placeholder", got)
+	}
+	if len(resp.Portraits) != 2 {
+		t.Errorf("%d portraits, want 2", len(resp.Portraits))
+	}
+	if n := fake.titled.Load(); n != 2 {
+		t.Errorf("%d Wikimedia lookups, want one per panelist", n)
+	}
+}
+
+func TestHandleGetPortraitBatchInvalid(t *testing.T) {
+	useFakeWikimedia(t, testWikimedia())
+
+	tests := map[string][]PortraitRequest{
+		"empty":      {},
+		"invalid id": {{PanelistID: "augustine", PanelistName: "Augustine"}, {PanelistID: "../etc", PanelistName: "Nobody"}},
+		"no name":    {{PanelistID: "augustine"}},
+		"too many":   make([]PortraitRequest, maxBatchSize+1),
+	}
+	for name, panelists := range tests {
+		t.Run(name, func(t *testing.T) {
+			if w := postPortraits(t, BatchPortraitRequest{Panelists: panelists}); w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", w.Code)
+			}
+		})
+	}
+}
+
+func TestLookupPortraitCoalesces(t *testing.T) {
+	fake := testWikimedia()
+	fake.hold = make(chan struct{})
+	useFakeWikimedia(t, fake)
+
+	req := PortraitRequest{PanelistID: "augustine", PanelistName: "Augustine of Hippo"}
+	responses := make([]PortraitResponse, 3)
+	var wg sync.WaitGroup
+	for i := range responses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i] = lookupPortrait(context.Background(), portraitCache, req, req.PanelistName)
+		}()
+	}
+
+	// Let every lookup join the first before Wikimedia answers
+	time.Sleep(50 * time.Millisecond)
+	close(fake.hold)
+	wg.Wait()
+
+	if n := fake.titled.Load(); n != 1 {
+		t.Errorf("%d Wikimedia lookups, want 1", n)
+	}
+	for _, resp := range responses {
+		if resp.EntityID != "Q8018" || resp.Cached {
+			t.Errorf("response = %+v, want a shared resolution", resp)
+		}
+	}
+}
+
+func TestLookupPortraitKeysOnFigure(t *testing.T) {
+	fake := testWikimedia()
+	fake.titles["Augustine of Canterbury"] = `{"query": {"pages": [{"title": "Augustine of Canterbury", "missing": true}]}}`
+	useFakeWikimedia(t, fake)
+
+	// Two panels may use the same ID for different figures
+	hippo := PortraitRequest{PanelistID: "augustine", PanelistName: "Augustine of Hippo"}
+	canterbury := PortraitRequest{PanelistID: "augustine", PanelistName: "Augustine of Canterbury"}
+	if resp := lookupPortrait(context.Background(), portraitCache, hippo, hippo.PanelistName); resp.EntityID != "Q8018" {
+		t.Fatalf("Hippo = %+v, want Q8018", resp)
+	}
+	if resp := lookupPortrait(context.Background(), portraitCache, canterbury, canterbury.PanelistName); resp.Cached || resp.EntityID != "" {
+		t.Errorf("Canterbury = %+v, want its own lookup", resp)
+	}
+
+	// Spacing and case do not make another figure
+	again := PortraitRequest{PanelistID: "augustine", PanelistName: " augustine  of HIPPO"}
+	if resp := lookupPortrait(context.Background(), portraitCache, again, again.PanelistName); !resp.Cached || resp.EntityID != "Q8018" {
+		t.Errorf("Hippo again = %+v, want the cached portrait", resp)
+	}
+}
@@ -0,0 +1,409 @@
+package getportrait
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // Registers the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/image/draw"
+)
+
+// Image proxy settings
+const (
+	defaultImageSize   = 256
+	defaultImageFormat = "jpeg"
+	maxImageBytes      = 10 << 20   // Largest source image downloaded
+	maxImagePixels     = 20_000_000 // Largest source image decoded
+	jpegQuality        = 85
+	// imageMaxAge is how long browsers and CDNs may keep served images
+	imageMaxAge = 30 * 24 * time.Hour
+	// defaultImageCacheMB bounds the thumbnails cached on disk, overridden
+	// with PORTRAIT_IMAGE_CACHE_MB
+	defaultImageCacheMB = 64
+)
+
+var (
+	// imageSizes are the thumbnail sizes served, in pixels. Requested sizes
+	// are rounded up so each portrait has few variants on disk.
+	imageSizes = []int{64, 128, 256, 512}
+
+	// imageFormats maps the formats served to their content types
+	imageFormats = map[string]string{
+		"jpeg": "image/jpeg",
+		"png":  "image/png",
+	}
+
+	// imageHosts are the hosts images are proxied from, including redirects
+	imageHosts = map[string]bool{
+		"upload.wikimedia.org":  true,
+		"commons.wikimedia.org": true,
+	}
+
+	imageClient = &http.Client{
+		Timeout: 10 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			if !imageHosts[req.URL.Hostname()] {
+				return fmt.Errorf("redirect to disallowed host %s", req.URL.Hostname())
+			}
+			return nil
+		},
+	}
+)
+
+// thumbPattern matches the URLs of Wikimedia thumbnails, whose width is part
+// of the file name, e.g. .../thumb/a/ab/Name.jpg/300px-Name.jpg
+var thumbPattern = regexp.MustCompile(`^(https://upload\.wikimedia\.org/.+/thumb/.+/)\d+px-([^/]+)$`)
+
+// errImageSource is returned for sources that are not proxied
+var errImageSource = errors.New("portrait source not allowed")
+
+// imageSize returns the served size for a requested size, the default if
+// empty or invalid
+func imageSize(requested string) int {
+	n, err := strconv.Atoi(requested)
+	if err != nil || n <= 0 {
+		return defaultImageSize
+	}
+	for _, size := range imageSizes {
+		if n <= size {
+			return size
+		}
+	}
+	return imageSizes[len(imageSizes)-1]
+}
+
+// imageDir returns the directory caching proxied images, overridden with
+// PORTRAIT_IMAGE_DIR. On Cloud Functions and Cloud Run the default temporary
+// directory is held in memory, so the cache counts against the instance's
+// memory up to PORTRAIT_IMAGE_CACHE_MB.
+func imageDir() string {
+	if dir := os.Getenv("PORTRAIT_IMAGE_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "portrait-images")
+}
+
+// sourceVersion identifies the source image of a portrait in proxied URLs,
+// so that a portrait resolved to another image gets a new URL
+func sourceVersion(source string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(source)))[:12]
+}
+
+// imageName returns the name of the thumbnail of source in size and format,
+// used both as its cache file name and as its ETag
+func imageName(source string, size int, format string) string {
+	return fmt.Sprintf("%x-%d.%s", sha256.Sum256([]byte(source)), size, format)
+}
+
+// proxiedURL returns the URL serving a panelist's portrait, resolved to
+// source, through this function. It names the panelist so that instances
+// which have not resolved the portrait yet can.
+func proxiedURL(r *http.Request, req PortraitRequest, source string) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	q := url.Values{}
+	q.Set("id", req.PanelistID)
+	q.Set("size", strconv.Itoa(imageSize(strconv.Itoa(req.Size))))
+	q.Set("name", req.PanelistName)
+	if req.Tagline != "" {
+		q.Set("tagline", req.Tagline)
+	}
+	q.Set("v", sourceVersion(source))
+	return fmt.Sprintf("%s://%s%s?%s", scheme, r.Host, r.URL.Path, q.Encode())
+}
+
+// portraitImage returns a square thumbnail of the source image, encoded in
+// format. Thumbnails are cached on disk by source, size and format, and
+// concurrent requests for the same one share a download.
+func portraitImage(ctx context.Context, source string, size int, format string) ([]byte, error) {
+	name := imageName(source, size, format)
+
+	dir := imageDir()
+	if data, err := os.ReadFile(filepath.Join(dir, name)); err == nil {
+		// Mark the thumbnail as recently used, so it is evicted last
+		now := time.Now()
+		os.Chtimes(filepath.Join(dir, name), now, now)
+		return data, nil
+	}
+
+	result, err, _ := lookups.Do("image:"+name, func() (any, error) {
+		// Wikimedia refuses thumbnails wider than the original: fall back
+		// to the resolved thumbnail
+		ctx := context.WithoutCancel(ctx)
+		img, err := fetchImage(ctx, sourceAtSize(source, size))
+		if err != nil && sourceAtSize(source, size) != source {
+			log.Printf("Failed to fetch portrait at %dpx, using %s: %v", size, source, err)
+			img, err = fetchImage(ctx, source)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := encodeImage(squareThumbnail(img, size), format)
+		if err != nil {
+			return nil, err
+		}
+
+		// A failed write only costs a download next time
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			log.Printf("Failed to create image cache directory %s: %v", dir, err)
+		} else if err := writeFileAtomic(dir, name, data); err != nil {
+			log.Printf("Failed to cache portrait image %s: %v", name, err)
+		} else {
+			pruneImages(dir, int64(envInt("PORTRAIT_IMAGE_CACHE_MB", defaultImageCacheMB))<<20)
+		}
+		return data, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]byte), nil
+}
+
+// pruneMu serializes pruning of the image cache directory
+var pruneMu sync.Mutex
+
+// pruneImages removes the least recently used thumbnails in dir until they
+// take at most limit bytes
+func pruneImages(dir string, limit int64) {
+	pruneMu.Lock()
+	defer pruneMu.Unlock()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Printf("Failed to list image cache directory %s: %v", dir, err)
+		return
+	}
+
+	var files []fs.FileInfo
+	var total int64
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasSuffix(entry.Name(), ".tmp") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, info)
+		total += info.Size()
+	}
+	if total <= limit {
+		return
+	}
+
+	// Oldest first: reads refresh the modification time
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+	for _, info := range files {
+		if total <= limit {
+			break
+		}
+		if err := os.Remove(filepath.Join(dir, info.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Failed to evict portrait image %s: %v", info.Name(), err)
+			continue
+		}
+		total -= info.Size()
+	}
+}
+
+// sourceAtSize returns the URL of the source image at least size pixels wide,
+// rather than the thumbnail the portrait was resolved to, so that larger
+// sizes are not upscaled. Other sources are returned as is.
+func sourceAtSize(source string, size int) string {
+	if strings.HasPrefix(source, commonsFileURL) {
+		u, err := url.Parse(source)
+		if err != nil {
+			return source
+		}
+		q := u.Query()
+		q.Set("width", strconv.Itoa(size))
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return thumbPattern.ReplaceAllString(source, "${1}"+strconv.Itoa(size)+"px-${2}")
+}
+
+// fetchImage downloads and decodes an image from an allowed host
+func fetchImage(ctx context.Context, source string) (image.Image, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !imageHosts[req.URL.Hostname()] {
+		return nil, fmt.Errorf("%w: %s", errImageSource, req.URL.Hostname())
+	}
+	// Proper User-Agent header is required by Wikimedia
+	req.Header.Set("User-Agent", "DebateApp/1.0 (https://github.com/raphink/debate; debate@example.com)")
+
+	resp, err := imageClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("image source returned status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageBytes {
+		return nil, fmt.Errorf("image larger than %d bytes", maxImageBytes)
+	}
+
+	// Check the dimensions before decoding the pixels
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported image: %w", err)
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, fmt.Errorf("image too large (%dx%d)", config.Width, config.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// squareThumbnail crops the largest square out of img and scales it to size.
+// Portraits frame the face in the upper part of tall images, so the crop is
+// centred horizontally but kept near the top rather than the middle.
+func squareThumbnail(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/5
+	crop := image.Rect(x0, y0, x0+side, y0+side)
+
+	thumb := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(thumb, thumb.Bounds(), img, crop, draw.Src, nil)
+	return thumb
+}
+
+// encodeImage encodes img in format. JPEG has no transparency, so
+// transparent areas are flattened onto white.
+func encodeImage(img image.Image, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg":
+		flat := image.NewRGBA(img.Bounds())
+		draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+		if err := jpeg.Encode(&buf, flat, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+	case "png":
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	return buf.Bytes(), nil
+}
+
+// handleImage serves the square thumbnail of a panelist's portrait, resolved
+// from the name and tagline of the request unless cached
+func handleImage(w http.ResponseWriter, r *http.Request, cache *PortraitCache) {
+	ctx := r.Context()
+	q := r.URL.Query()
+
+	id := q.Get("id")
+	if !panelistIDPattern.MatchString(id) {
+		respondWithError(w, http.StatusBadRequest, "Invalid panelist ID format", ErrInvalidInput, false)
+		return
+	}
+
+	format := q.Get("format")
+	if format == "" {
+		format = defaultImageFormat
+	}
+	contentType, ok := imageFormats[format]
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Unsupported image format (jpeg or png)", ErrInvalidInput, false)
+		return
+	}
+
+	req := PortraitRequest{PanelistID: id, PanelistName: q.Get("name"), Tagline: q.Get("tagline")}
+	sanitizedName, err := validatePortraitRequest(req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), ErrInvalidInput, false)
+		return
+	}
+
+	var source string
+	if resp := lookupPortrait(ctx, cache, req, sanitizedName); resp.PortraitURL != placeholderPortrait {
+		source = resp.PortraitURL
+	}
+	if source == "" {
+		respondWithError(w, http.StatusNotFound, "No portrait found for this panelist", ErrNotFound, false)
+		return
+	}
+
+	// The ETag is known from the request, so revalidation needs no download
+	size := imageSize(q.Get("size"))
+	etag := `"` + imageName(source, size, format) + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		setImageCaching(w, q.Get("v") == sourceVersion(source), etag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	data, err := portraitImage(ctx, source, size, format)
+	if err != nil {
+		log.Printf("Failed to proxy portrait of %s from %s: %v", id, source, err)
+		if errors.Is(err, errImageSource) {
+			respondWithError(w, http.StatusNotFound, "Portrait cannot be proxied", ErrNotFound, false)
+			return
+		}
+		respondWithError(w, http.StatusBadGateway, "Failed to fetch portrait image", ErrWikimediaError, true)
+		return
+	}
+
+	setImageCaching(w, q.Get("v") == sourceVersion(source), etag)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// setImageCaching sets the caching headers of a served image. Only URLs
+// naming the source served may be cached for long; others are revalidated,
+// as the panelist's portrait may change.
+func setImageCaching(w http.ResponseWriter, versioned bool, etag string) {
+	if versioned {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(imageMaxAge.Seconds())))
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	w.Header().Set("ETag", etag)
+}
@@ -0,0 +1,222 @@
+package getportrait
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tallPortrait is a 100x300 image, red in its top third and blue below
+func tallPortrait() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 300))
+	for y := 0; y < 300; y++ {
+		c := color.RGBA{B: 255, A: 255}
+		if y < 100 {
+			c = color.RGBA{R: 255, A: 255}
+		}
+		for x := 0; x < 100; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// useImageSource serves tallPortrait from an allowed test host, caching
+// thumbnails in a temporary directory, and returns its URL and hit count
+func useImageSource(t *testing.T) (string, *atomic.Int32) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		png.Encode(w, tallPortrait())
+	}))
+	t.Cleanup(server.Close)
+
+	u, _ := url.Parse(server.URL)
+	host := u.Hostname()
+	imageHosts[host] = true
+	t.Cleanup(func() { delete(imageHosts, host) })
+	t.Setenv("PORTRAIT_IMAGE_DIR", t.TempDir())
+
+	return server.URL + "/portrait.png", &hits
+}
+
+func TestHandleImage(t *testing.T) {
+	useFakeWikimedia(t, testWikimedia())
+	source, hits := useImageSource(t)
+	augustine := PortraitRequest{PanelistID: "augustine", PanelistName: "Augustine of Hippo"}
+	portraitCache.Set(context.Background(), portraitKey(augustine, augustine.PanelistName), Entry{PortraitURL: source})
+
+	unversioned := "/?id=augustine&name=Augustine+of+Hippo&size=100&format=png"
+	versioned := unversioned + "&v=" + sourceVersion(source)
+	get := func(target string, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		HandleGetPortrait(w, r)
+		return w
+	}
+
+	w := get(versioned, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "image/png" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if cc := w.Header().Get("Cache-Control"); !strings.Contains(cc, "max-age=") {
+		t.Errorf("Cache-Control = %q, want a max age", cc)
+	}
+	img, err := png.Decode(bytes.NewReader(w.Body.Bytes()))
+	if err != nil {
+		t.Fatalf("invalid PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 128 || b.Dy() != 128 {
+		t.Errorf("size = %v, want 128x128", b.Size())
+	}
+
+	// Repeated requests are served from disk
+	etag := w.Header().Get("ETag")
+	if get(versioned, nil).Code != http.StatusOK || hits.Load() != 1 {
+		t.Errorf("%d downloads, want 1", hits.Load())
+	}
+
+	// Revalidation needs neither the image nor its cached thumbnail
+	t.Setenv("PORTRAIT_IMAGE_DIR", t.TempDir())
+	w = get(versioned, http.Header{"If-None-Match": {etag}})
+	if w.Code != http.StatusNotModified || hits.Load() != 1 {
+		t.Errorf("revalidation status = %d after %d downloads, want 304 after 1", w.Code, hits.Load())
+	}
+
+	// URLs that do not name the source may be served another image later
+	if cc := get(unversioned, nil).Header().Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("unversioned Cache-Control = %q, want no-cache", cc)
+	}
+}
+
+func TestHandleImageErrors(t *testing.T) {
+	useFakeWikimedia(t, testWikimedia())
+	useImageSource(t)
+	portraitCache.Set(context.Background(), portraitKey(PortraitRequest{PanelistID: "elsewhere"}, "Elsewhere"), Entry{PortraitURL: "https://example.com/portrait.png"})
+	portraitCache.Set(context.Background(), portraitKey(PortraitRequest{PanelistID: "nobody"}, "Nobody"), Entry{})
+
+	tests := map[string]int{
+		"id=../etc&name=Etc":                      http.StatusBadRequest,
+		"id=augustine&name=Augustine&format=webp": http.StatusBadRequest,
+		"id=augustine":                            http.StatusBadRequest,
+		"id=unknown&name=Unknown":                 http.StatusNotFound,
+		"id=nobody&name=Nobody":                   http.StatusNotFound,
+		"id=elsewhere&name=Elsewhere":             http.StatusNotFound,
+	}
+	for query, want := range tests {
+		w := httptest.NewRecorder()
+		HandleGetPortrait(w, httptest.NewRequest(http.MethodGet, "/?"+query, nil))
+		if w.Code != want {
+			t.Errorf("GET ?%s status = %d, want %d", query, w.Code, want)
+		}
+	}
+}
+
+func TestPruneImages(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Now()
+	for i, name := range []string{"oldest.png", "used.png", "newest.png"} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, make([]byte, 100), 0o644)
+		os.Chtimes(path, base.Add(time.Duration(i)*time.Minute), base.Add(time.Duration(i)*time.Minute))
+	}
+
+	// A read makes a thumbnail the most recently used
+	os.Chtimes(filepath.Join(dir, "used.png"), base.Add(time.Hour), base.Add(time.Hour))
+
+	pruneImages(dir, 200)
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if strings.Join(names, ",") != "newest.png,used.png" {
+		t.Errorf("kept %v, want [newest.png used.png]", names)
+	}
+
+	pruneImages(dir, 0)
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("%d images left with no room, want none", len(entries))
+	}
+}
+
+func TestProxiedPortrait(t *testing.T) {
+	useFakeWikimedia(t, testWikimedia())
+
+	w := postPortraits(t, PortraitRequest{PanelistID: "augustine", PanelistName: "Augustine of Hippo", Proxy: true, Size: 64})
+	var resp PortraitResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(resp.PortraitURL)
+	if err != nil || u.Query().Get("id") != "augustine" || u.Query().Get("size") != "64" || u.Query().Get("name") != "Augustine of Hippo" {
+		t.Errorf("PortraitURL = %q, want the proxy", resp.PortraitURL)
+	}
+	if v := u.Query().Get("v"); v != sourceVersion(resp.SourceURL) {
+		t.Errorf("source version = %q, want %q", v, sourceVersion(resp.SourceURL))
+	}
+	if !strings.HasPrefix(resp.SourceURL, commonsFileURL) {
+		t.Errorf("SourceURL = %q, want the Commons image", resp.SourceURL)
+	}
+}
+
+func TestSquareThumbnail(t *testing.T) {
+	thumb := squareThumbnail(tallPortrait(), 64)
+	if b := thumb.Bounds(); b.Dx() != 64 || b.Dy() != 64 {
+		t.Fatalf("size = %v, want 64x64", b.Size())
+	}
+
+	// The crop keeps the top of tall portraits, where the face is
+	if r, _, _, _ := thumb.At(32, 5).RGBA(); r < 0x8000 {
+		t.Errorf("top of thumbnail = %v, want the top of the portrait", thumb.At(32, 5))
+	}
+}
+
+func TestImageSize(t *testing.T) {
+	tests := map[string]int{
+		"":     defaultImageSize,
+		"abc":  defaultImageSize,
+		"-1":   defaultImageSize,
+		"10":   64,
+		"128":  128,
+		"200":  256,
+		"4096": 512,
+	}
+	for requested, want := range tests {
+		if got := imageSize(requested); got != want {
+			t.Errorf("imageSize(%q) = %d, want %d", requested, got, want)
+		}
+	}
+}
+
+func TestSourceAtSize(t *testing.T) {
+	tests := map[string]string{
+		commonsFileURL + "Augustine.jpg?width=300":                                                    commonsFileURL + "Augustine.jpg?width=512",
+		"https://upload.wikimedia.org/wikipedia/commons/thumb/a/ab/Augustine.jpg/300px-Augustine.jpg": "https://upload.wikimedia.org/wikipedia/commons/thumb/a/ab/Augustine.jpg/512px-Augustine.jpg",
+		"https://upload.wikimedia.org/wikipedia/commons/a/ab/Augustine.jpg":                           "https://upload.wikimedia.org/wikipedia/commons/a/ab/Augustine.jpg",
+	}
+	for source, want := range tests {
+		if got := sourceAtSize(source, 512); got != want {
+			t.Errorf("sourceAtSize(%q) = %q, want %q", source, got, want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package getportrait
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// maxBatchSize is the maximum number of panelists in a batch request
+	maxBatchSize = 50
+	// defaultLookupConcurrency is the number of Wikimedia lookups a batch
+	// runs in parallel, overridden with PORTRAIT_CONCURRENCY
+	defaultLookupConcurrency = 4
+)
+
+var (
+	// newWikimediaAPI creates the Wikimedia client, replaced in tests
+	newWikimediaAPI = NewWikimediaAPI
+
+	// lookups coalesces concurrent Wikimedia lookups of the same panelist
+	lookups singleflight.Group
+)
+
+// validatePortraitRequest checks a portrait request and returns the
+// sanitized panelist name
+func validatePortraitRequest(req PortraitRequest) (string, error) {
+	if req.PanelistID == "" || req.PanelistName == "" {
+		return "", errors.New("Panelist ID and name are required")
+	}
+
+	if !panelistIDPattern.MatchString(req.PanelistID) {
+		return "", errors.New("Invalid panelist ID format")
+	}
+
+	if len(req.PanelistName) > 100 {
+		return "", errors.New("Panelist name too long (max 100 characters)")
+	}
+
+	if len(req.Tagline) > 300 {
+		return "", errors.New("Tagline too long (max 300 characters)")
+	}
+
+	// Sanitize name
+	sanitizedName := strings.TrimSpace(req.PanelistName)
+	if sanitizedName == "" {
+		return "", errors.New("Panelist name cannot be empty")
+	}
+	return sanitizedName, nil
+}
+
+// portraitKey returns the key caching and coalescing the lookups of a
+// panelist. Panelist IDs are only unique within a panel, so the key also
+// covers the name and lifespan the portrait is resolved from.
+func portraitKey(req PortraitRequest, name string) string {
+	lifespan := ParseLifespan(req.Tagline)
+	figure := fmt.Sprintf("%s|%d|%d", strings.ToLower(strings.Join(strings.Fields(name), " ")), lifespan.Born, lifespan.Died)
+	sum := sha256.Sum256([]byte(figure))
+	return fmt.Sprintf("%s-%x", req.PanelistID, sum[:8])
+}
+
+// lookupPortrait returns the portrait of a panelist from the cache, or
+// resolves and caches it. Concurrent lookups of the same panelist share one
+// resolution. Wikimedia failures yield what was resolved, or the placeholder,
+// without caching it.
+func lookupPortrait(ctx context.Context, cache *PortraitCache, req PortraitRequest, name string) PortraitResponse {
+	key := portraitKey(req, name)
+	if cached, found := cache.Get(ctx, key); found {
+		log.Printf("Cache hit for %s", key)
+		return PortraitResponse{
+			PanelistID:  req.PanelistID,
+			PortraitURL: portraitOrPlaceholder(cached.PortraitURL),
+			EntityID:    cached.EntityID,
+			Cached:      true,
+			Attribution: cached.Attribution,
+		}
+	}
+
+	// The lookup outlives the request that started it, as other requests
+	// may be waiting on it
+	ctx = context.WithoutCancel(ctx)
+	result, _, shared := lookups.Do(key, func() (any, error) {
+		portrait, err := newWikimediaAPI().ResolvePortrait(ctx, name, ParseLifespan(req.Tagline))
+		if err == nil {
+			cache.Set(ctx, key, Entry{
+				PortraitURL: portrait.URL,
+				EntityID:    portrait.EntityID,
+				Attribution: portrait.Attribution,
+			})
+		}
+		return portrait, err
+	})
+	if shared {
+		log.Printf("Coalesced portrait lookup for %s", key)
+	}
+
+	// A failed lookup may still have found the portrait
+	portrait, _ := result.(Portrait)
+	return PortraitResponse{
+		PanelistID:  req.PanelistID,
+		PortraitURL: portraitOrPlaceholder(portrait.URL),
+		EntityID:    portrait.EntityID,
+		Cached:      false,
+		Attribution: portrait.Attribution,
+	}
+}
+
+// lookupPortraits looks up the portraits of validated requests with bounded
+// parallelism, keyed by panelist ID
+func lookupPortraits(ctx context.Context, cache *PortraitCache, reqs []PortraitRequest, names []string) map[string]PortraitResponse {
+	portraits := make(map[string]PortraitResponse, len(reqs))
+	var mu sync.Mutex
+
+	var g errgroup.Group
+	g.SetLimit(envInt("PORTRAIT_CONCURRENCY", defaultLookupConcurrency))
+
+	seen := make(map[string]bool, len(reqs))
+	for i, req := range reqs {
+		// Duplicates in a batch are looked up once
+		if seen[req.PanelistID] {
+			continue
+		}
+		seen[req.PanelistID] = true
+
+		name := names[i]
+		g.Go(func() error {
+			portrait := lookupPortrait(ctx, cache, req, name)
+			mu.Lock()
+			defer mu.Unlock()
+			portraits[req.PanelistID] = portrait
+			return nil
+		})
+	}
+	g.Wait()
+
+	return portraits
+}
+
+// portraitOrPlaceholder returns portraitURL, or the placeholder if it is empty
+func portraitOrPlaceholder(portraitURL string) string {
+	if portraitURL == "" {
+		return placeholderPortrait
+	}
+	return portraitURL
+}
@@ -0,0 +1,72 @@
+package getportrait
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// LRUCache keeps a bounded number of entries in memory, evicting the least
+// recently used first
+type LRUCache struct {
+	size    int
+	order   *list.List // Front is the most recently used
+	entries map[string]*list.Element
+	mu      sync.Mutex
+}
+
+// lruItem is an element of the LRU order
+type lruItem struct {
+	key   string
+	entry Entry
+}
+
+// NewLRUCache creates an in-memory cache holding up to size entries
+func NewLRUCache(size int) *LRUCache {
+	return &LRUCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Get returns the entry stored under key, marking it as recently used
+func (c *LRUCache) Get(ctx context.Context, key string) (Entry, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, found := c.entries[key]
+	if !found {
+		return Entry{}, false, nil
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruItem).entry, true, nil
+}
+
+// Set stores entry under key, evicting the least recently used entry if the
+// cache is full
+func (c *LRUCache) Set(ctx context.Context, key string, entry Entry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, found := c.entries[key]; found {
+		elem.Value.(*lruItem).entry = entry
+		c.order.MoveToFront(elem)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(&lruItem{key: key, entry: entry})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruItem).key)
+	}
+	return nil
+}
+
+// Len returns the number of entries in the cache
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
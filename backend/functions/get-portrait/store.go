@@ -0,0 +1,53 @@
+package getportrait
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/raphink/debate/shared/firebase"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// portraitsCollection is the Firestore collection holding cached portraits
+const portraitsCollection = "portraits"
+
+// StoreCache stores entries in the Firestore "portraits" collection, shared
+// by every instance of the function
+type StoreCache struct {
+	client *firestore.Client
+}
+
+// NewStoreCache creates a cache backed by the shared Firestore client,
+// initializing the client if needed
+func NewStoreCache(ctx context.Context) (*StoreCache, error) {
+	if firebase.GetClient() == nil {
+		if err := firebase.InitFirestore(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return &StoreCache{client: firebase.GetClient()}, nil
+}
+
+// Get reads the entry stored under key
+func (c *StoreCache) Get(ctx context.Context, key string) (Entry, bool, error) {
+	doc, err := c.client.Collection(portraitsCollection).Doc(key).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return Entry{}, false, nil
+	}
+	if err != nil {
+		return Entry{}, false, err
+	}
+
+	var entry Entry
+	if err := doc.DataTo(&entry); err != nil {
+		return Entry{}, false, err
+	}
+	return entry, true, nil
+}
+
+// Set writes the entry stored under key
+func (c *StoreCache) Set(ctx context.Context, key string, entry Entry) error {
+	_, err := c.client.Collection(portraitsCollection).Doc(key).Set(ctx, entry)
+	return err
+}
@@ -1,16 +1,37 @@
 package getportrait
 
+import "github.com/raphink/debate/shared/firebase"
+
 // PortraitRequest represents a request to fetch a panelist's portrait
 type PortraitRequest struct {
 	PanelistID   string `json:"panelistId"`
 	PanelistName string `json:"panelistName"`
+	Tagline      string `json:"tagline,omitempty"` // Lifespan hints to disambiguate the name, e.g. "Bishop of Hippo (354-430)"
+	Proxy        bool   `json:"proxy,omitempty"`   // Serve the portrait through this function rather than Wikimedia
+	Size         int    `json:"size,omitempty"`    // Size of proxied portraits, in pixels
+}
+
+// BatchPortraitRequest represents a request to fetch the portraits of
+// several panelists at once
+type BatchPortraitRequest struct {
+	Panelists []PortraitRequest `json:"panelists"`
+}
+
+// BatchPortraitResponse maps panelist IDs to their portraits
+type BatchPortraitResponse struct {
+	Portraits map[string]PortraitResponse `json:"portraits"`
 }
 
 // PortraitResponse represents the response with portrait URL
 type PortraitResponse struct {
 	PanelistID  string `json:"panelistId"`
 	PortraitURL string `json:"portraitUrl"`
+	SourceURL   string `json:"sourceUrl,omitempty"` // Wikimedia URL of proxied portraits
+	EntityID    string `json:"entityId,omitempty"`  // Wikidata entity the portrait was resolved to
 	Cached      bool   `json:"cached"`
+
+	// License and credits to display with the portrait, unknown if nil
+	Attribution *firebase.Attribution `json:"attribution,omitempty"`
 }
 
 // ErrorResponse represents an error response
@@ -23,6 +44,7 @@ type ErrorResponse struct {
 // Error codes
 const (
 	ErrInvalidInput   = "INVALID_INPUT"
+	ErrNotFound       = "NOT_FOUND"
 	ErrWikimediaError = "WIKIMEDIA_ERROR"
 	ErrInternalError  = "INTERNAL_ERROR"
 )
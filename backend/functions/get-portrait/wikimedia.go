@@ -1,16 +1,43 @@
 package getportrait
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"html"
 	"net/http"
 	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/sanitize"
+)
+
+// Wikimedia API endpoints
+const (
+	wikipediaAPIURL = "https://en.wikipedia.org/w/api.php"
+	wikidataAPIURL  = "https://www.wikidata.org/w/api.php"
+	commonsFileURL  = "https://commons.wikimedia.org/wiki/Special:FilePath/"
+)
+
+const (
+	// portraitWidth is the width of the requested thumbnails, in pixels
+	portraitWidth = 300
+	// searchLimit is the number of search results considered as candidates
+	searchLimit = 5
+	// humanEntity is the Wikidata class of human beings
+	humanEntity = "Q5"
 )
 
 // WikimediaAPI client for fetching portrait images
 type WikimediaAPI struct {
-	client *http.Client
+	client       *http.Client
+	wikipediaURL string
+	wikidataURL  string
 }
 
 // NewWikimediaAPI creates a new Wikimedia API client
@@ -19,94 +46,428 @@ func NewWikimediaAPI() *WikimediaAPI {
 		client: &http.Client{
 			Timeout: 5 * time.Second,
 		},
+		wikipediaURL: wikipediaAPIURL,
+		wikidataURL:  wikidataAPIURL,
 	}
 }
 
-// WikimediaImageResponse represents the API response structure
-type WikimediaImageResponse struct {
-	Query struct {
-		Pages map[string]struct {
-			PageID    int    `json:"pageid"`
-			Title     string `json:"title"`
-			Thumbnail struct {
-				Source string `json:"source"`
-				Width  int    `json:"width"`
-				Height int    `json:"height"`
-			} `json:"thumbnail,omitempty"`
-			Original struct {
-				Source string `json:"source"`
-				Width  int    `json:"width"`
-				Height int    `json:"height"`
-			} `json:"original,omitempty"`
-		} `json:"pages"`
-	} `json:"query"`
-}
-
-// FetchPortraitURL fetches a portrait image URL from Wikimedia Commons for a given person
-// Returns empty string if no suitable image is found
-func (w *WikimediaAPI) FetchPortraitURL(personName string) string {
-	// Construct Wikipedia API URL
-	// We'll use the pageimages API which provides the main image for a page
-	baseURL := "https://en.wikipedia.org/w/api.php"
+// Portrait is the result of resolving a panelist on Wikimedia
+type Portrait struct {
+	URL         string                // Empty if no suitable image was found
+	EntityID    string                // Wikidata entity of the panelist, empty if unresolved
+	Attribution *firebase.Attribution // License and credits of the image
+}
 
-	params := url.Values{}
-	params.Add("action", "query")
-	params.Add("titles", personName)
-	params.Add("prop", "pageimages")
-	params.Add("pithumbsize", "300") // Request 300px thumbnail
-	params.Add("format", "json")
-	params.Add("formatversion", "2")
+// Lifespan holds the birth and death years of a panelist, negative for BC
+// and zero when unknown
+type Lifespan struct {
+	Born int
+	Died int
+}
+
+// Known reports whether any year is known
+func (l Lifespan) Known() bool {
+	return l.Born != 0 || l.Died != 0
+}
+
+// lifespanPattern matches year ranges such as "354–430", "c. 427 – 347 BC"
+// or "4 BC - AD 30"
+var lifespanPattern = regexp.MustCompile(`(?i)(?:\b(AD|CE)\s*)?\b(\d{1,4})\s*(BCE?|AD|CE)?\s*[-–—]\s*(?:(AD|CE)\s*)?(\d{1,4})\s*(BCE?|AD|CE)?\b`)
+
+// ParseLifespan extracts the birth and death years from a panelist tagline
+func ParseLifespan(tagline string) Lifespan {
+	m := lifespanPattern.FindStringSubmatch(tagline)
+	if m == nil {
+		return Lifespan{}
+	}
 
-	apiURL := baseURL + "?" + params.Encode()
+	born, _ := strconv.Atoi(m[2])
+	died, _ := strconv.Atoi(m[5])
+	bornEra := strings.ToUpper(m[1] + m[3])
+	diedEra := strings.ToUpper(m[4] + m[6])
 
-	// Create request with proper User-Agent header (required by Wikipedia)
-	req, err := http.NewRequest("GET", apiURL, nil)
+	// A trailing BC applies to both years unless the first has its own era
+	if strings.HasPrefix(diedEra, "BC") {
+		died = -died
+		if bornEra == "" {
+			born = -born
+		}
+	}
+	if strings.HasPrefix(bornEra, "BC") {
+		born = -born
+	}
+	if died < born {
+		return Lifespan{}
+	}
+	return Lifespan{Born: born, Died: died}
+}
+
+// page is a Wikipedia page returned by the query API
+type page struct {
+	Title     string `json:"title"`
+	Index     int    `json:"index"` // Rank in search results
+	Missing   bool   `json:"missing"`
+	PageImage string `json:"pageimage"` // File name of the thumbnail
+	PageProps struct {
+		WikibaseItem   string  `json:"wikibase_item"`
+		Disambiguation *string `json:"disambiguation"`
+	} `json:"pageprops"`
+	Thumbnail *struct {
+		Source string `json:"source"`
+	} `json:"thumbnail,omitempty"`
+}
+
+// entity is a Wikidata entity reduced to the claims used to pick a portrait
+type entity struct {
+	ID     string
+	Image  string // P18 file name on Commons
+	Human  bool   // P31 includes Q5
+	Born   int    // P569 year
+	Died   int    // P570 year
+	Exists bool
+}
+
+// claim is a Wikidata statement, its value depending on the property
+type claim struct {
+	Mainsnak struct {
+		Datavalue struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"datavalue"`
+	} `json:"mainsnak"`
+}
+
+// ResolvePortrait finds the portrait of a panelist. It tries the Wikipedia
+// page titled after the panelist (following redirects), then searches
+// Wikipedia with the lifespan hints, and picks the candidate whose Wikidata
+// entity best matches, preferring its P18 image over the page thumbnail.
+// An error means Wikimedia could not be queried, as opposed to a panelist
+// without a portrait.
+func (w *WikimediaAPI) ResolvePortrait(ctx context.Context, personName string, hints Lifespan) (Portrait, error) {
+	candidates, err := w.titlePages(ctx, personName)
 	if err != nil {
-		fmt.Printf("[WIKIMEDIA] Error creating request for %s: %v\n", personName, err)
-		return ""
+		return Portrait{}, err
 	}
-	req.Header.Set("User-Agent", "DebateApp/1.0 (https://github.com/raphink/debate; debate@example.com)")
 
-	// Make the request
-	resp, err := w.client.Do(req)
+	entities, err := w.entities(ctx, candidates)
 	if err != nil {
-		fmt.Printf("[WIKIMEDIA] Error fetching portrait for %s: %v\n", personName, err)
-		return ""
+		return Portrait{}, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("[WIKIMEDIA] Non-200 status for %s: %d\n", personName, resp.StatusCode)
-		return ""
+	// The exact title is trusted unless it contradicts the hints
+	best := pickCandidate(candidates, entities, hints)
+	if best == nil || (hints.Known() && !entities[best.PageProps.WikibaseItem].matches(hints)) {
+		results, err := w.searchPages(ctx, personName, hints)
+		if err != nil {
+			return Portrait{}, err
+		}
+		found, err := w.entities(ctx, results)
+		if err != nil {
+			return Portrait{}, err
+		}
+		for id, e := range found {
+			entities[id] = e
+		}
+		candidates = append(candidates, results...)
+		best = pickCandidate(candidates, entities, hints)
+	}
+
+	if best == nil {
+		fmt.Printf("[WIKIMEDIA] No page found for %s\n", personName)
+		return Portrait{}, nil
 	}
 
-	// Parse response - using simpler structure
+	portrait := Portrait{EntityID: best.PageProps.WikibaseItem}
+	var file string
+	if e := entities[portrait.EntityID]; e.Image != "" {
+		portrait.URL = commonsImageURL(e.Image)
+		file = e.Image
+	} else if best.Thumbnail != nil {
+		portrait.URL = best.Thumbnail.Source
+		file = best.PageImage
+	}
+
+	if portrait.URL == "" {
+		fmt.Printf("[WIKIMEDIA] No portrait found for %s (%s)\n", personName, best.Title)
+		return portrait, nil
+	}
+	fmt.Printf("[WIKIMEDIA] Found portrait for %s (%s, %s): %s\n", personName, best.Title, portrait.EntityID, portrait.URL)
+
+	// The portrait is returned with the error, usable but not to be cached
+	if file != "" {
+		portrait.Attribution, err = w.attribution(ctx, file)
+	}
+	return portrait, err
+}
+
+// attribution fetches the license and credits of an image file from its
+// description page metadata. It returns nil if the file has none.
+func (w *WikimediaAPI) attribution(ctx context.Context, file string) (*firebase.Attribution, error) {
+	params := url.Values{}
+	params.Set("action", "query")
+	params.Set("titles", "File:"+file)
+	params.Set("prop", "imageinfo")
+	params.Set("iiprop", "url|extmetadata")
+	params.Set("iiextmetadatafilter", "LicenseShortName|LicenseUrl|Artist")
+	params.Set("format", "json")
+	params.Set("formatversion", "2")
+
+	type field struct {
+		Value string `json:"value"`
+	}
 	var result struct {
 		Query struct {
 			Pages []struct {
-				PageID    int    `json:"pageid"`
-				Title     string `json:"title"`
-				Thumbnail *struct {
-					Source string `json:"source"`
-					Width  int    `json:"width"`
-					Height int    `json:"height"`
-				} `json:"thumbnail,omitempty"`
+				ImageInfo []struct {
+					DescriptionURL string `json:"descriptionurl"`
+					ExtMetadata    struct {
+						LicenseShortName field `json:"LicenseShortName"`
+						LicenseURL       field `json:"LicenseUrl"`
+						Artist           field `json:"Artist"`
+					} `json:"extmetadata"`
+				} `json:"imageinfo"`
 			} `json:"pages"`
 		} `json:"query"`
 	}
+	if err := w.get(ctx, w.wikipediaURL, params, &result); err != nil {
+		return nil, err
+	}
+
+	if len(result.Query.Pages) == 0 || len(result.Query.Pages[0].ImageInfo) == 0 {
+		fmt.Printf("[WIKIMEDIA] No image info for %s\n", file)
+		return nil, nil
+	}
+	info := result.Query.Pages[0].ImageInfo[0]
+	return &firebase.Attribution{
+		License:    metadataText(info.ExtMetadata.LicenseShortName.Value),
+		LicenseURL: info.ExtMetadata.LicenseURL.Value,
+		// Artists are HTML, often a link to their user or Wikipedia page
+		Artist:    metadataText(info.ExtMetadata.Artist.Value),
+		SourceURL: info.DescriptionURL,
+	}, nil
+}
+
+// metadataText converts an HTML metadata value to plain text
+func metadataText(value string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(sanitize.StripHTML(value))), " ")
+}
+
+// pickCandidate returns the first page matching the hints, else the first
+// page about a human, else the first page. Disambiguation pages are skipped.
+func pickCandidate(pages []page, entities map[string]entity, hints Lifespan) *page {
+	var human, first *page
+	for i := range pages {
+		p := &pages[i]
+		if p.Missing || p.PageProps.Disambiguation != nil {
+			continue
+		}
+		e := entities[p.PageProps.WikibaseItem]
+		if hints.Known() && e.matches(hints) {
+			return p
+		}
+		if human == nil && e.Human {
+			human = p
+		}
+		if first == nil {
+			first = p
+		}
+	}
+	if human != nil {
+		return human
+	}
+	return first
+}
+
+// matches reports whether the entity's dates agree with the known hints,
+// within a year to allow for calendar and dating differences
+func (e entity) matches(hints Lifespan) bool {
+	if !e.Exists || (e.Born == 0 && e.Died == 0) {
+		return false
+	}
+	near := func(got, want int) bool {
+		return want == 0 || got == 0 || got-want <= 1 && want-got <= 1
+	}
+	return near(e.Born, hints.Born) && near(e.Died, hints.Died)
+}
+
+// titlePages returns the page titled personName, following redirects
+func (w *WikimediaAPI) titlePages(ctx context.Context, personName string) ([]page, error) {
+	params := pageParams()
+	params.Set("titles", personName)
+	params.Set("redirects", "1")
+	return w.queryPages(ctx, params)
+}
+
+// searchPages searches Wikipedia for personName and the lifespan hints
+func (w *WikimediaAPI) searchPages(ctx context.Context, personName string, hints Lifespan) ([]page, error) {
+	query := personName
+	for _, year := range []int{hints.Born, hints.Died} {
+		if year < 0 {
+			year = -year
+		}
+		if year != 0 {
+			query += " " + strconv.Itoa(year)
+		}
+	}
+
+	params := pageParams()
+	params.Set("generator", "search")
+	params.Set("gsrsearch", query)
+	params.Set("gsrlimit", strconv.Itoa(searchLimit))
+	params.Set("redirects", "1")
+
+	pages, err := w.queryPages(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generator results come unordered, ranked by index
+	sort.SliceStable(pages, func(i, j int) bool { return pages[i].Index < pages[j].Index })
+	return pages, nil
+}
+
+// pageParams returns the query parameters fetching the Wikidata item,
+// disambiguation flag and thumbnail of pages
+func pageParams() url.Values {
+	params := url.Values{}
+	params.Set("action", "query")
+	params.Set("prop", "pageprops|pageimages")
+	params.Set("ppprop", "wikibase_item|disambiguation")
+	params.Set("piprop", "thumbnail|name")
+	params.Set("pithumbsize", strconv.Itoa(portraitWidth))
+	params.Set("format", "json")
+	params.Set("formatversion", "2")
+	return params
+}
+
+// queryPages runs a Wikipedia query and returns its pages
+func (w *WikimediaAPI) queryPages(ctx context.Context, params url.Values) ([]page, error) {
+	var result struct {
+		Query struct {
+			Pages []page `json:"pages"`
+		} `json:"query"`
+	}
+	if err := w.get(ctx, w.wikipediaURL, params, &result); err != nil {
+		return nil, err
+	}
+	return result.Query.Pages, nil
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		fmt.Printf("[WIKIMEDIA] Error decoding response for %s: %v\n", personName, err)
-		return ""
+// entities fetches the Wikidata entities of pages, by ID
+func (w *WikimediaAPI) entities(ctx context.Context, pages []page) (map[string]entity, error) {
+	var ids []string
+	for _, p := range pages {
+		if id := p.PageProps.WikibaseItem; id != "" {
+			ids = append(ids, id)
+		}
 	}
+	entities := make(map[string]entity)
+	if len(ids) == 0 {
+		return entities, nil
+	}
+
+	params := url.Values{}
+	params.Set("action", "wbgetentities")
+	params.Set("ids", strings.Join(ids, "|"))
+	params.Set("props", "claims")
+	params.Set("format", "json")
 
-	// Extract image URL from first page
-	if len(result.Query.Pages) > 0 && result.Query.Pages[0].Thumbnail != nil {
-		imageURL := result.Query.Pages[0].Thumbnail.Source
-		fmt.Printf("[WIKIMEDIA] Found portrait for %s: %s\n", personName, imageURL)
-		return imageURL
+	var result struct {
+		Entities map[string]struct {
+			ID        string  `json:"id"`
+			Missing   *string `json:"missing"`
+			Redirects *struct {
+				From string `json:"from"`
+			} `json:"redirects"`
+			Claims map[string][]claim `json:"claims"`
+		} `json:"entities"`
+	}
+	if err := w.get(ctx, w.wikidataURL, params, &result); err != nil {
+		return nil, err
 	}
 
-	fmt.Printf("[WIKIMEDIA] No portrait found for %s\n", personName)
-	return ""
+	for key, raw := range result.Entities {
+		if raw.Missing != nil {
+			continue
+		}
+		e := entity{ID: raw.ID, Exists: true}
+		for _, claim := range raw.Claims["P18"] {
+			if json.Unmarshal(claim.Mainsnak.Datavalue.Value, &e.Image) == nil {
+				break
+			}
+		}
+		for _, claim := range raw.Claims["P31"] {
+			var class struct {
+				ID string `json:"id"`
+			}
+			if json.Unmarshal(claim.Mainsnak.Datavalue.Value, &class) == nil && class.ID == humanEntity {
+				e.Human = true
+			}
+		}
+		e.Born = claimYear(raw.Claims["P569"])
+		e.Died = claimYear(raw.Claims["P570"])
+
+		entities[key] = e
+		// Entities merged into another are returned under their target
+		if raw.Redirects != nil {
+			entities[raw.Redirects.From] = e
+		}
+	}
+	return entities, nil
+}
+
+// claimYear returns the year of the first time value of Wikidata claims,
+// such as "+0354-11-13T00:00:00Z" or "-0427-00-00T00:00:00Z"
+func claimYear(claims []claim) int {
+	for _, c := range claims {
+		var value struct {
+			Time string `json:"time"`
+		}
+		if json.Unmarshal(c.Mainsnak.Datavalue.Value, &value) != nil {
+			continue
+		}
+
+		sign, t := 1, strings.TrimPrefix(value.Time, "+")
+		if strings.HasPrefix(t, "-") {
+			sign, t = -1, t[1:]
+		}
+		year, _, _ := strings.Cut(t, "-")
+		if n, err := strconv.Atoi(year); err == nil && n != 0 {
+			return sign * n
+		}
+	}
+	return 0
+}
+
+// commonsImageURL returns the URL of a thumbnail of a Commons file
+func commonsImageURL(file string) string {
+	name := strings.ReplaceAll(file, " ", "_")
+	return commonsFileURL + url.PathEscape(name) + "?width=" + strconv.Itoa(portraitWidth)
+}
+
+// get queries a Wikimedia API and decodes its JSON response into v
+func (w *WikimediaAPI) get(ctx context.Context, baseURL string, params url.Values, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	// Proper User-Agent header is required by Wikimedia
+	req.Header.Set("User-Agent", "DebateApp/1.0 (https://github.com/raphink/debate; debate@example.com)")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		fmt.Printf("[WIKIMEDIA] Error querying %s: %v\n", baseURL, err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		fmt.Printf("[WIKIMEDIA] Non-200 status from %s: %d\n", baseURL, resp.StatusCode)
+		return fmt.Errorf("wikimedia returned status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		fmt.Printf("[WIKIMEDIA] Error decoding response from %s: %v\n", baseURL, err)
+		return err
+	}
+	return nil
 }
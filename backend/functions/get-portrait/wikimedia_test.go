@@ -0,0 +1,235 @@
+package getportrait
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/raphink/debate/shared/firebase"
+)
+
+// fakeWikimedia serves canned Wikipedia and Wikidata responses, keyed by the
+// title, search query or entity IDs of the request
+type fakeWikimedia struct {
+	titles   map[string]string
+	searches map[string]string
+	entities map[string]string
+	fail     bool
+	failInfo bool          // Fail image info queries only
+	titled   atomic.Int32  // Number of title lookups
+	hold     chan struct{} // If set, title lookups wait until it is closed
+}
+
+func (f *fakeWikimedia) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	imageInfo := r.URL.Query().Get("prop") == "imageinfo"
+	if r.URL.Path == "/wikipedia" && r.URL.Query().Has("titles") && !imageInfo {
+		f.titled.Add(1)
+		if f.hold != nil {
+			<-f.hold
+		}
+	}
+	if f.fail || f.failInfo && imageInfo {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	q := r.URL.Query()
+	var body string
+	switch {
+	case r.URL.Path == "/wikidata":
+		body = f.entities[q.Get("ids")]
+	case q.Get("generator") == "search":
+		body = f.searches[q.Get("gsrsearch")]
+	default:
+		body = f.titles[q.Get("titles")]
+	}
+	if body == "" {
+		body = `{}`
+	}
+	w.Write([]byte(body))
+}
+
+// newFakeWikimediaAPI returns a client querying a fake Wikimedia server
+func newFakeWikimediaAPI(t *testing.T, fake *fakeWikimedia) *WikimediaAPI {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	wiki := NewWikimediaAPI()
+	wiki.wikipediaURL = server.URL + "/wikipedia"
+	wiki.wikidataURL = server.URL + "/wikidata"
+	return wiki
+}
+
+const (
+	augustineEntity = `"Q8018": {"id": "Q8018", "claims": {
+		"P31": [{"mainsnak": {"datavalue": {"value": {"id": "Q5"}}}}],
+		"P18": [{"mainsnak": {"datavalue": {"value": "Saint Augustine by Philippe de Champaigne.jpg"}}}],
+		"P569": [{"mainsnak": {"datavalue": {"value": {"time": "+0354-11-13T00:00:00Z"}}}}],
+		"P570": [{"mainsnak": {"datavalue": {"value": {"time": "+0430-08-28T00:00:00Z"}}}}]
+	}}`
+	augustineFile = "File:Saint Augustine by Philippe de Champaigne.jpg"
+	augustineInfo = `{"query": {"pages": [{"imageinfo": [{
+		"descriptionurl": "https://commons.wikimedia.org/wiki/File:Saint_Augustine_by_Philippe_de_Champaigne.jpg",
+		"extmetadata": {"LicenseShortName": {"value": "Public domain"}, "Artist": {"value": "Philippe de Champaigne"}}
+	}]}]}}`
+	canterburyEntity = `"Q164294": {"id": "Q164294", "claims": {
+		"P31": [{"mainsnak": {"datavalue": {"value": {"id": "Q5"}}}}],
+		"P569": [{"mainsnak": {"datavalue": {"value": {"time": "+0534-00-00T00:00:00Z"}}}}],
+		"P570": [{"mainsnak": {"datavalue": {"value": {"time": "+0604-05-26T00:00:00Z"}}}}]
+	}}`
+)
+
+func TestResolvePortraitFollowsRedirect(t *testing.T) {
+	wiki := newFakeWikimediaAPI(t, &fakeWikimedia{
+		titles: map[string]string{
+			"Saint Augustine": `{"query": {
+				"redirects": [{"from": "Saint Augustine", "to": "Augustine of Hippo"}],
+				"pages": [{"title": "Augustine of Hippo", "pageprops": {"wikibase_item": "Q8018"}}]
+			}}`,
+			augustineFile: augustineInfo,
+		},
+		entities: map[string]string{"Q8018": `{"entities": {` + augustineEntity + `}}`},
+	})
+
+	portrait, err := wiki.ResolvePortrait(context.Background(), "Saint Augustine", Lifespan{})
+	if err != nil {
+		t.Fatalf("ResolvePortrait() error = %v", err)
+	}
+	if want := commonsFileURL + "Saint_Augustine_by_Philippe_de_Champaigne.jpg?width=300"; portrait.URL != want {
+		t.Errorf("URL = %q, want %q", portrait.URL, want)
+	}
+	if portrait.EntityID != "Q8018" {
+		t.Errorf("EntityID = %q, want Q8018", portrait.EntityID)
+	}
+}
+
+func TestResolvePortraitAttribution(t *testing.T) {
+	fake := &fakeWikimedia{
+		titles: map[string]string{
+			"Augustine of Canterbury": `{"query": {"pages": [
+				{"title": "Augustine of Canterbury", "pageimage": "Canterbury.jpg", "pageprops": {"wikibase_item": "Q164294"},
+				 "thumbnail": {"source": "https://upload.wikimedia.org/canterbury.jpg"}}
+			]}}`,
+			"File:Canterbury.jpg": `{"query": {"pages": [{"imageinfo": [{
+				"descriptionurl": "https://commons.wikimedia.org/wiki/File:Canterbury.jpg",
+				"extmetadata": {
+					"LicenseShortName": {"value": "CC BY-SA 4.0"},
+					"LicenseUrl": {"value": "https://creativecommons.org/licenses/by-sa/4.0"},
+					"Artist": {"value": "<a href=\"//commons.wikimedia.org/wiki/User:Jean\">Jean</a> &amp; Marie\n"}
+				}
+			}]}]}}`,
+		},
+		entities: map[string]string{"Q164294": `{"entities": {` + canterburyEntity + `}}`},
+	}
+	wiki := newFakeWikimediaAPI(t, fake)
+
+	portrait, err := wiki.ResolvePortrait(context.Background(), "Augustine of Canterbury", Lifespan{})
+	if err != nil {
+		t.Fatalf("ResolvePortrait() error = %v", err)
+	}
+	want := firebase.Attribution{
+		License:    "CC BY-SA 4.0",
+		LicenseURL: "https://creativecommons.org/licenses/by-sa/4.0",
+		Artist:     "Jean & Marie",
+		SourceURL:  "https://commons.wikimedia.org/wiki/File:Canterbury.jpg",
+	}
+	if portrait.Attribution == nil || *portrait.Attribution != want {
+		t.Errorf("Attribution = %+v, want %+v", portrait.Attribution, want)
+	}
+
+	// The portrait survives a failure to credit it
+	fake.failInfo = true
+	portrait, err = wiki.ResolvePortrait(context.Background(), "Augustine of Canterbury", Lifespan{})
+	if err == nil || portrait.URL == "" || portrait.Attribution != nil {
+		t.Errorf("ResolvePortrait() = %+v, %v, want the uncredited portrait and an error", portrait, err)
+	}
+}
+
+func TestResolvePortraitDisambiguates(t *testing.T) {
+	wiki := newFakeWikimediaAPI(t, &fakeWikimedia{
+		titles: map[string]string{
+			"Augustine": `{"query": {"pages": [
+				{"title": "Augustine", "pageprops": {"wikibase_item": "Q1", "disambiguation": ""}}
+			]}}`,
+		},
+		searches: map[string]string{
+			"Augustine 354 430": `{"query": {"pages": [
+				{"title": "Augustine of Hippo", "index": 2, "pageprops": {"wikibase_item": "Q8018"}},
+				{"title": "Augustine of Canterbury", "index": 1, "pageprops": {"wikibase_item": "Q164294"},
+				 "thumbnail": {"source": "https://upload.wikimedia.org/canterbury.jpg"}}
+			]}}`,
+		},
+		entities: map[string]string{
+			"Q164294|Q8018": `{"entities": {` + augustineEntity + `,` + canterburyEntity + `}}`,
+		},
+	})
+
+	// The lifespan picks the lower-ranked result
+	portrait, err := wiki.ResolvePortrait(context.Background(), "Augustine", ParseLifespan("Bishop of Hippo (354–430)"))
+	if err != nil {
+		t.Fatalf("ResolvePortrait() error = %v", err)
+	}
+	if portrait.EntityID != "Q8018" {
+		t.Errorf("EntityID = %q, want Augustine of Hippo", portrait.EntityID)
+	}
+}
+
+func TestResolvePortraitThumbnailFallback(t *testing.T) {
+	wiki := newFakeWikimediaAPI(t, &fakeWikimedia{
+		titles: map[string]string{
+			"Augustine of Canterbury": `{"query": {"pages": [
+				{"title": "Augustine of Canterbury", "pageprops": {"wikibase_item": "Q164294"},
+				 "thumbnail": {"source": "https://upload.wikimedia.org/canterbury.jpg"}}
+			]}}`,
+		},
+		entities: map[string]string{"Q164294": `{"entities": {` + canterburyEntity + `}}`},
+	})
+
+	portrait, err := wiki.ResolvePortrait(context.Background(), "Augustine of Canterbury", Lifespan{})
+	if err != nil {
+		t.Fatalf("ResolvePortrait() error = %v", err)
+	}
+	if portrait.URL != "https://upload.wikimedia.org/canterbury.jpg" {
+		t.Errorf("URL = %q, want the page thumbnail", portrait.URL)
+	}
+}
+
+func TestResolvePortraitNotFound(t *testing.T) {
+	wiki := newFakeWikimediaAPI(t, &fakeWikimedia{
+		titles: map[string]string{
+			"Nobody": `{"query": {"pages": [{"title": "Nobody", "missing": true}]}}`,
+		},
+	})
+
+	portrait, err := wiki.ResolvePortrait(context.Background(), "Nobody", Lifespan{})
+	if err != nil || portrait != (Portrait{}) {
+		t.Errorf("ResolvePortrait() = %+v, %v, want no portrait", portrait, err)
+	}
+}
+
+func TestResolvePortraitError(t *testing.T) {
+	wiki := newFakeWikimediaAPI(t, &fakeWikimedia{fail: true})
+
+	if _, err := wiki.ResolvePortrait(context.Background(), "Augustine", Lifespan{}); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("ResolvePortrait() error = %v, want the status", err)
+	}
+}
+
+func TestParseLifespan(t *testing.T) {
+	tests := map[string]Lifespan{
+		"Bishop of Hippo (354–430)":            {Born: 354, Died: 430},
+		"Reformer, 1509 - 1564":                {Born: 1509, Died: 1564},
+		"Athenian philosopher (c. 427–347 BC)": {Born: -427, Died: -347},
+		"Poet (43 BC – AD 17)":                 {Born: -43, Died: 17},
+		"Civil rights leader":                  {},
+		"Died before birth (1900–1800)":        {},
+	}
+	for tagline, want := range tests {
+		if got := ParseLifespan(tagline); got != want {
+			t.Errorf("ParseLifespan(%q) = %+v, want %+v", tagline, got, want)
+		}
+	}
+}
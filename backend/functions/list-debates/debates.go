@@ -0,0 +1,96 @@
+package listdebates
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/store"
+)
+
+// autocompleteWindow is the number of recent debates scanned for autocomplete matches
+const autocompleteWindow = 50
+
+// autocompleteLimit is the maximum number of autocomplete results
+const autocompleteLimit = 10
+
+// listedStatus is the status of the debates listed and searched: debates
+// still being generated, failed or paused are left out
+const listedStatus = firebase.StatusComplete
+
+// queryDebates fetches debates from the store with pagination
+func queryDebates(ctx context.Context, debateStore store.DebateStore, limit, offset int) ([]DebateSummary, int, error) {
+	docs, err := debateStore.ListDebates(ctx, store.ListOptions{Limit: limit, Offset: offset, Status: listedStatus})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list debates: %w", err)
+	}
+
+	debates := make([]DebateSummary, 0, len(docs))
+	for i := range docs {
+		debates = append(debates, toSummary(&docs[i]))
+	}
+
+	// Get total count
+	total, err := debateStore.CountDebates(ctx, listedStatus)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get total count: %w", err)
+	}
+
+	return debates, total, nil
+}
+
+// autocompleteDebates scans recent debates and filters using normalized token matching
+// Returns up to 10 matching debates ordered by match weight (DESC), then startedAt (DESC)
+func autocompleteDebates(ctx context.Context, debateStore store.DebateStore, query string) ([]DebateSummary, error) {
+	// Normalize and tokenize the query
+	queryTokens := NormalizeAndTokenize(query)
+
+	// If query has no significant tokens (all words <3 chars), return empty results
+	if len(queryTokens) == 0 {
+		return []DebateSummary{}, nil
+	}
+
+	// Count matching tokens (bag-of-words) between query and topic
+	match := func(debate *firebase.DebateDocument) int {
+		return CountMatchingTokens(queryTokens, NormalizeAndTokenize(debate.Topic.Text))
+	}
+
+	docs, err := debateStore.SearchDebates(ctx, match, store.SearchOptions{
+		Window: autocompleteWindow,
+		Limit:  autocompleteLimit,
+		Status: listedStatus,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search debates: %w", err)
+	}
+
+	results := make([]DebateSummary, 0, len(docs))
+	for i := range docs {
+		results = append(results, toSummary(&docs[i]))
+	}
+
+	return results, nil
+}
+
+// toSummary converts a stored debate into its list view summary
+func toSummary(doc *firebase.DebateDocument) DebateSummary {
+	debate := DebateSummary{
+		ID:            doc.ID,
+		Topic:         doc.Topic.Text,
+		PanelistCount: len(doc.Panelists),
+		Status:        doc.Status,
+		StartedAt:     doc.StartedAt,
+	}
+
+	for _, p := range doc.Panelists {
+		debate.Panelists = append(debate.Panelists, PanelistInfo{
+			ID:        p.ID,
+			Name:      p.Name,
+			AvatarURL: p.AvatarURL,
+			Tagline:   p.Tagline,
+			Bio:       p.Biography,
+		})
+	}
+
+	return debate
+}
@@ -0,0 +1,48 @@
+package listdebates
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/raphink/debate/shared/firebase"
+	"github.com/raphink/debate/shared/store"
+)
+
+func TestOnlyCompleteDebatesListed(t *testing.T) {
+	ctx := context.Background()
+	memStore := store.NewMemoryStore()
+
+	base := time.Now().UTC()
+	statuses := map[string]string{
+		"done":      firebase.StatusComplete,
+		"running":   firebase.StatusInProgress,
+		"failed":    firebase.StatusFailed,
+		"abandoned": firebase.StatusAborted,
+		"paused":    firebase.StatusAwaitingHuman,
+	}
+	for id, status := range statuses {
+		memStore.SaveDebate(ctx, id, &firebase.DebateDocument{
+			ID:        id,
+			Topic:     firebase.Topic{Text: "Should Christians disobey unjust laws?"},
+			Status:    status,
+			StartedAt: base,
+		})
+	}
+
+	debates, total, err := queryDebates(ctx, memStore, 20, 0)
+	if err != nil {
+		t.Fatalf("queryDebates() error = %v", err)
+	}
+	if len(debates) != 1 || debates[0].ID != "done" || total != 1 {
+		t.Errorf("queryDebates() = %+v with total %d, want only the complete debate", debates, total)
+	}
+
+	debates, err = autocompleteDebates(ctx, memStore, "unjust laws")
+	if err != nil {
+		t.Fatalf("autocompleteDebates() error = %v", err)
+	}
+	if len(debates) != 1 || debates[0].ID != "done" {
+		t.Errorf("autocompleteDebates() = %+v, want only the complete debate", debates)
+	}
+}
@@ -12,8 +12,8 @@ import (
 	"strings"
 
 	_ "github.com/GoogleCloudPlatform/functions-framework-go/funcframework"
-	"github.com/raphink/debate/shared/firebase"
 	"github.com/raphink/debate/shared/sanitize"
+	"github.com/raphink/debate/shared/store"
 )
 
 var allowedOrigin string
@@ -25,10 +25,10 @@ func init() {
 	}
 	log.Printf("ALLOWED_ORIGIN set to: %s", allowedOrigin)
 
-	// Initialize Firestore client
+	// Initialize debate store
 	ctx := context.Background()
-	if err := firebase.InitFirestore(ctx); err != nil {
-		log.Printf("Failed to initialize Firestore: %v", err)
+	if _, err := store.Default(ctx); err != nil {
+		log.Printf("Failed to initialize debate store: %v", err)
 	}
 }
 
@@ -61,16 +61,13 @@ func ListDebatesHandler(w http.ResponseWriter, r *http.Request) {
 	limitStr := r.URL.Query().Get("limit")
 	offsetStr := r.URL.Query().Get("offset")
 
-	// Initialize Firestore client if needed
+	// Initialize debate store if needed
 	ctx := r.Context()
-	client := firebase.GetClient()
-	if client == nil {
-		if err := firebase.InitFirestore(ctx); err != nil {
-			log.Printf("Failed to initialize Firestore: %v", err)
-			sendError(w, "Failed to initialize database connection", http.StatusInternalServerError)
-			return
-		}
-		client = firebase.GetClient()
+	debateStore, err := store.Default(ctx)
+	if err != nil {
+		log.Printf("Failed to initialize debate store: %v", err)
+		sendError(w, "Failed to initialize database connection", http.StatusInternalServerError)
+		return
 	}
 
 	// Autocomplete mode: if q parameter is provided
@@ -98,7 +95,7 @@ func ListDebatesHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Query autocomplete debates
-		debates, err := autocompleteDebates(ctx, client, sanitizedQuery)
+		debates, err := autocompleteDebates(ctx, debateStore, sanitizedQuery)
 		if err != nil {
 			// Log only sanitized query and a hash of the original query for correlation
 			queryHash := sha256.Sum256([]byte(queryParam))
@@ -127,7 +124,6 @@ func ListDebatesHandler(w http.ResponseWriter, r *http.Request) {
 	offset := 0 // default
 
 	if limitStr != "" {
-		var err error
 		limit, err = strconv.Atoi(limitStr)
 		if err != nil || limit < 1 || limit > 100 {
 			sendError(w, "Invalid limit: must be between 1 and 100", http.StatusBadRequest)
@@ -136,7 +132,6 @@ func ListDebatesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if offsetStr != "" {
-		var err error
 		offset, err = strconv.Atoi(offsetStr)
 		if err != nil || offset < 0 {
 			sendError(w, "Invalid offset: must be >= 0", http.StatusBadRequest)
@@ -145,10 +140,10 @@ func ListDebatesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Query debates
-	debates, total, err := queryDebates(ctx, client, limit, offset)
+	debates, total, err := queryDebates(ctx, debateStore, limit, offset)
 	if err != nil {
 		log.Printf("Failed to query debates: %v", err)
-		sendError(w, "Failed to query debates from Firestore", http.StatusInternalServerError)
+		sendError(w, "Failed to query debates", http.StatusInternalServerError)
 		return
 	}
 
@@ -1,37 +1,45 @@
 package listdebates
 
 import (
-	"regexp"
 	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
 )
 
-// minTokenLength is the minimum length for tokens to be considered significant
+// minTokenLength is the minimum length in characters for tokens to be considered significant
 const minTokenLength = 3
 
-// punctuationRegex matches all punctuation and special characters except spaces and alphanumeric
-var punctuationRegex = regexp.MustCompile(`[^a-z0-9\s]+`)
-
-// NormalizeAndTokenize converts text to lowercase, splits compound words (hyphens/slashes),
-// removes punctuation, and returns array of significant tokens (≥3 characters)
+// NormalizeAndTokenize converts text to lowercase, strips accents, splits on
+// anything that is not a letter or digit (spaces, punctuation, hyphens and
+// slashes), and returns array of significant tokens (≥3 characters).
+// Letters of all scripts are kept, so "Éthique" and "ethique" match.
 func NormalizeAndTokenize(text string) []string {
-	// Convert to lowercase
-	normalized := strings.ToLower(text)
-
-	// Replace hyphens and slashes with spaces to split compound words
-	// "climate-change" → "climate change", "AI/ML" → "AI ML"
-	normalized = strings.ReplaceAll(normalized, "-", " ")
-	normalized = strings.ReplaceAll(normalized, "/", " ")
+	// Decompose accented letters, so that their marks can be dropped
+	// "é" → "e" + "◌́"
+	decomposed := norm.NFD.String(strings.ToLower(text))
 
-	// Remove all punctuation
-	normalized = punctuationRegex.ReplaceAllString(normalized, " ")
+	var normalized strings.Builder
+	for _, r := range decomposed {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// Drop combining marks
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			normalized.WriteRune(r)
+		default:
+			// Punctuation and compound word separators split tokens
+			// "climate-change" → "climate change", "AI/ML" → "AI ML"
+			normalized.WriteRune(' ')
+		}
+	}
 
 	// Split on whitespace
-	words := strings.Fields(normalized)
+	words := strings.Fields(norm.NFC.String(normalized.String()))
 
 	// Filter to keep only tokens ≥ minTokenLength
 	var tokens []string
 	for _, word := range words {
-		if len(word) >= minTokenLength {
+		if len([]rune(word)) >= minTokenLength {
 			tokens = append(tokens, word)
 		}
 	}
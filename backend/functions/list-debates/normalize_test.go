@@ -66,6 +66,26 @@ func TestNormalizeAndTokenize(t *testing.T) {
 			input:    "AI in 2024 and beyond",
 			expected: []string{"2024", "and", "beyond"},
 		},
+		{
+			name:     "french accents folded",
+			input:    "L'éthique de la guerre peut-elle être juste ?",
+			expected: []string{"ethique", "guerre", "peut", "elle", "etre", "juste"},
+		},
+		{
+			name:     "german umlauts and eszett",
+			input:    "Müssen Christen ungerechten Gesetzen gehorchen? Größe",
+			expected: []string{"mussen", "christen", "ungerechten", "gesetzen", "gehorchen", "große"},
+		},
+		{
+			name:     "short words counted in characters",
+			input:    "Où été",
+			expected: []string{"ete"},
+		},
+		{
+			name:     "non-latin scripts kept",
+			input:    "Что такое справедливость?",
+			expected: []string{"что", "такое", "справедливость"},
+		},
 	}
 
 	for _, tt := range tests {
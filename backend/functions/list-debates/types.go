@@ -19,6 +19,7 @@ type DebateSummary struct {
 	Topic         string         `json:"topic"`
 	Panelists     []PanelistInfo `json:"panelists"`
 	PanelistCount int            `json:"panelistCount"`
+	Status        string         `json:"status"`
 	StartedAt     time.Time      `json:"startedAt"`
 }
 
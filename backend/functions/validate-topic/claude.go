@@ -4,37 +4,37 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
 	"strings"
 
 	"github.com/anthropics/anthropic-sdk-go"
-	"github.com/anthropics/anthropic-sdk-go/option"
-	"github.com/anthropics/anthropic-sdk-go/packages/ssestream"
+	"github.com/raphink/debate/shared/language"
+	"github.com/raphink/debate/shared/llm"
+	"github.com/raphink/debate/shared/sse"
 )
 
-// ClaudeClient handles communication with the Anthropic Claude API
+// ClaudeClient validates topics with a language model (Claude by default)
 type ClaudeClient struct {
-	client anthropic.Client
+	model llm.LanguageModel
 }
 
-// NewClaudeClient creates a new Claude API client
+// NewClaudeClient creates a client for the language model configured in the environment
 func NewClaudeClient() (*ClaudeClient, error) {
-	apiKey := os.Getenv("ANTHROPIC_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("ANTHROPIC_API_KEY environment variable not set")
+	model, err := llm.NewFromEnv(string(anthropic.ModelClaudeHaiku4_5))
+	if err != nil {
+		return nil, err
 	}
 
-	return &ClaudeClient{
-		client: anthropic.NewClient(
-			option.WithAPIKey(apiKey),
-		),
-	}, nil
+	return NewClaudeClientWithModel(model), nil
 }
 
-// ValidateTopicAndSuggestPanelists validates topic and streams panelist suggestions
-func (c *ClaudeClient) ValidateTopicAndSuggestPanelists(ctx context.Context, topic string, suggestedNames []string, writer io.Writer) error {
+// NewClaudeClientWithModel creates a client using the given language model
+func NewClaudeClientWithModel(model llm.LanguageModel) *ClaudeClient {
+	return &ClaudeClient{model: model}
+}
+
+// ValidateTopicAndSuggestPanelists validates topic and streams panelist
+// suggestions, written in the language with the given code
+func (c *ClaudeClient) ValidateTopicAndSuggestPanelists(ctx context.Context, topic string, suggestedNames []string, lang string, events *sse.Writer) error {
 	// Build user-suggested names section
 	namesSection := ""
 	if len(suggestedNames) > 0 {
@@ -59,6 +59,17 @@ For user-suggested panelists who qualify, infer their likely position based on t
 `
 	}
 
+	// Build the language section for debates not held in English
+	languageSection := ""
+	if lang != "" && lang != language.Default {
+		name := language.Name(lang)
+		languageSection = fmt.Sprintf(`
+
+Language: the debate will be held in %[1]s and the topic may be written in %[1]s.
+Write the rejection message and the panelists' name, tagline, bio and position in %[1]s, using the names by which they are usually known in %[1]s.
+Keep the id in lowercase ASCII kebab-case.`, name)
+	}
+
 	// Build the combined prompt for Claude
 	prompt := fmt.Sprintf(`You are an expert in theology and philosophy. Your task is to evaluate if a topic is suitable for a theological or philosophical debate, and if so, suggest panelists.
 
@@ -80,7 +91,7 @@ Return ONLY this JSON structure:
 
 If the topic IS relevant:
 Return 8-20 panelist objects in this format, one per line:
-{"type":"panelist","data":{"id":"unique-kebab-case-id","name":"Full Name","tagline":"One-line with era (max 60 chars)","bio":"Brief bio (max 300 chars)","avatarUrl":"placeholder-avatar.svg","position":"Their position (max 100 chars)"}}
+{"type":"panelist","data":{"id":"unique-kebab-case-id","name":"Full Name","tagline":"One-line with era (max 60 chars)","bio":"Brief bio (max 300 chars)","avatarUrl":"placeholder-avatar.svg","position":"Their position (max 100 chars)","born":1225,"died":1274}}
 
 Requirements for panelists:
 - Different theological/philosophical positions on this topic
@@ -88,48 +99,35 @@ Requirements for panelists:
 - Different traditions (Catholic, Protestant, Orthodox, Jewish, Islamic, secular, etc.)
 - Mix of perspectives (theist/atheist, conservative/progressive, different schools of thought)
 - Only include historical/contemporary figures with known, documented views on related topics
+- born and died are the years of birth and death, negative for years before Christ; omit died for living figures
 
-Format: Each panelist on its own line as shown above. No other text.`, topic, namesSection)
+Format: Each panelist on its own line as shown above. No other text.%s`, topic, namesSection, languageSection)
 
 	// Create streaming request
-	stream := c.client.Messages.NewStreaming(ctx, anthropic.MessageNewParams{
-		Model:     anthropic.ModelClaudeHaiku4_5,
-		MaxTokens: 4096,
-		Messages: []anthropic.MessageParam{
-			anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
-		},
-	})
+	stream := c.model.Stream(ctx, llm.UserPrompt(prompt, 4096))
+	defer stream.Close()
 
 	// Stream the response
-	return c.streamPanelistResponse(stream, writer)
+	return c.streamPanelistResponse(stream, events)
 }
 
 // streamPanelistResponse processes the stream and emits panelists or rejection incrementally
-func (c *ClaudeClient) streamPanelistResponse(stream *ssestream.Stream[anthropic.MessageStreamEventUnion], writer io.Writer) error {
-	flusher, _ := writer.(http.Flusher)
-
+func (c *ClaudeClient) streamPanelistResponse(stream llm.Stream, events *sse.Writer) error {
 	sendChunk := func(chunkType, data string) {
-		chunk := map[string]string{
+		chunk, _ := json.Marshal(map[string]string{
 			"type": chunkType,
 			"data": data,
-		}
-		json.NewEncoder(writer).Encode(chunk)
-		if flusher != nil {
-			flusher.Flush()
-		}
+		})
+		events.Send(chunkType, chunk)
 	}
 
 	var lineBuffer strings.Builder
 	var fullBuffer strings.Builder
+	emitted := false // Whether any line was emitted incrementally
 
 	// Process stream incrementally, emitting complete lines as they arrive
 	for stream.Next() {
-		event := stream.Current()
-		if event.Delta.Text == "" {
-			continue
-		}
-
-		text := event.Delta.Text
+		text := stream.Current()
 		fullBuffer.WriteString(text)
 
 		// Process character by character to detect complete lines
@@ -165,6 +163,7 @@ func (c *ClaudeClient) streamPanelistResponse(stream *ssestream.Stream[anthropic
 						"message":    chunk.Message,
 					})
 					sendChunk("validation", string(rejectionData))
+					emitted = true
 					// Continue processing in case there's more
 				} else if chunk.Type == "panelist" {
 					// Parse and send panelist immediately
@@ -177,23 +176,26 @@ func (c *ClaudeClient) streamPanelistResponse(stream *ssestream.Stream[anthropic
 					if panelist.Name == "" || panelist.ID == "" {
 						continue
 					}
-					if len(panelist.Tagline) > 60 {
-						panelist.Tagline = panelist.Tagline[:57] + "..."
-					}
-					if len(panelist.Bio) > 300 {
-						panelist.Bio = panelist.Bio[:297] + "..."
-					}
-					if len(panelist.Position) > 100 {
-						panelist.Position = panelist.Position[:97] + "..."
+					panelist.Tagline = truncate(panelist.Tagline, 60)
+					panelist.Bio = truncate(panelist.Bio, 300)
+					panelist.Position = truncate(panelist.Position, 100)
+					if panelist.Died != 0 && panelist.Died < panelist.Born {
+						panelist.Born, panelist.Died = 0, 0 // Inconsistent lifespan
 					}
 
 					panelistData, _ := json.Marshal(panelist)
 					sendChunk("panelist", string(panelistData))
+					emitted = true
 				}
 			}
 		}
 	}
 
+	// Check for stream errors
+	if err := stream.Err(); err != nil {
+		return fmt.Errorf("stream error: %w", err)
+	}
+
 	// After stream completes, check if we accumulated text but didn't emit anything via line parsing
 	// This handles the case where Claude returns the old format instead of line-delimited
 	fullText := fullBuffer.String()
@@ -210,7 +212,7 @@ func (c *ClaudeClient) streamPanelistResponse(stream *ssestream.Stream[anthropic
 		}
 	}
 
-	if fullText != "" {
+	if fullText != "" && !emitted {
 		// Try to parse as old format (single JSON object with isRelevant, message, panelists array)
 		var oldFormat struct {
 			IsRelevant bool       `json:"isRelevant"`
@@ -252,3 +254,13 @@ func (c *ClaudeClient) streamPanelistResponse(stream *ssestream.Stream[anthropic
 
 	return nil
 }
+
+// truncate shortens text to at most max characters, ending with an ellipsis.
+// It counts runes so that accented text is never cut mid-character.
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-3]) + "..."
+}
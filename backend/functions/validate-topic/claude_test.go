@@ -1,11 +1,52 @@
 package validatetopic
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"os"
+	"strings"
 	"testing"
+
+	"github.com/raphink/debate/shared/llm"
+	"github.com/raphink/debate/shared/sse"
 )
 
+// streamChunk is a chunk written by streamPanelistResponse
+type streamChunk struct {
+	Type string `json:"type"`
+	Data string `json:"data"`
+}
+
+// decodeChunks parses the server-sent events written by streamPanelistResponse
+// and checks that their IDs increase
+func decodeChunks(t *testing.T, output string) []streamChunk {
+	t.Helper()
+
+	events, err := sse.ReadEvents(strings.NewReader(output))
+	if err != nil {
+		t.Fatalf("invalid event stream: %v", err)
+	}
+
+	var chunks []streamChunk
+	for i, event := range events {
+		if event.ID != i+1 {
+			t.Errorf("event %d has ID %d, want %d", i, event.ID, i+1)
+		}
+
+		var chunk streamChunk
+		if err := json.Unmarshal(event.Data, &chunk); err != nil {
+			t.Fatalf("invalid chunk %q: %v", event.Data, err)
+		}
+		if event.Event != chunk.Type {
+			t.Errorf("event name %q for chunk type %q", event.Event, chunk.Type)
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
 func TestNewClaudeClient(t *testing.T) {
 	// Save original env var
 	originalKey := os.Getenv("ANTHROPIC_API_KEY")
@@ -16,6 +57,7 @@ func TestNewClaudeClient(t *testing.T) {
 			os.Unsetenv("ANTHROPIC_API_KEY")
 		}
 	}()
+	t.Setenv("LLM_PROVIDER", "")
 
 	t.Run("missing API key", func(t *testing.T) {
 		os.Unsetenv("ANTHROPIC_API_KEY")
@@ -31,127 +73,218 @@ func TestNewClaudeClient(t *testing.T) {
 		if err != nil {
 			t.Errorf("NewClaudeClient() unexpected error: %v", err)
 		}
-		if client == nil {
-			t.Error("NewClaudeClient() returned nil client")
-		}
-		if client.apiKey != "test-key" {
-			t.Errorf("NewClaudeClient() apiKey = %v, want test-key", client.apiKey)
-		}
-		if client.model == "" {
-			t.Error("NewClaudeClient() model is empty")
+		if client == nil || client.model == nil {
+			t.Error("NewClaudeClient() returned client without model")
 		}
 	})
 }
 
-func TestParseValidationResponse(t *testing.T) {
-	client := &ClaudeClient{
-		apiKey: "test-key",
-		model:  "test-model",
-	}
-
+func TestStreamPanelistResponse(t *testing.T) {
 	tests := []struct {
-		name         string
-		response     string
-		wantRelevant bool
-		wantMessage  string
-		wantErr      bool
+		name          string
+		deltas        []string
+		wantTypes     []string
+		wantPanelists []string
+		wantRelevant  *bool
 	}{
 		{
-			name:         "valid JSON response",
-			response:     `{"isRelevant": true, "message": "This is a theological topic."}`,
-			wantRelevant: true,
-			wantMessage:  "This is a theological topic.",
-			wantErr:      false,
+			name: "panelists split across deltas",
+			deltas: []string{
+				`{"type":"panelist","data":{"id":"augustine","name":"Augus`,
+				`tine of Hippo","tagline":"Bishop (354-430)","bio":"Church father","avatarUrl":"placeholder-avatar.svg","position":"Just war"}}` + "\n",
+				`{"type":"panelist","data":{"id":"mlk","name":"Martin Luther King Jr.","tagline":"Pastor","bio":"Civil rights leader","avatarUrl":"placeholder-avatar.svg","position":"Nonviolence"}}` + "\n",
+			},
+			wantTypes:     []string{"panelist", "panelist", "done"},
+			wantPanelists: []string{"Augustine of Hippo", "Martin Luther King Jr."},
 		},
 		{
-			name:         "valid JSON with extra text",
-			response:     `Some preamble {"isRelevant": false, "message": "Not relevant."} some postamble`,
-			wantRelevant: false,
-			wantMessage:  "Not relevant.",
-			wantErr:      false,
+			name:          "rejection",
+			deltas:        []string{`{"type":"rejection","message":"Not a philosophical topic"}` + "\n"},
+			wantTypes:     []string{"validation", "done"},
+			wantPanelists: nil,
+			wantRelevant:  boolPtr(false),
 		},
 		{
-			name:     "no JSON in response",
-			response: "This is not a JSON response",
-			wantErr:  true,
+			name: "panelists without id are skipped",
+			deltas: []string{
+				`{"type":"panelist","data":{"name":"Anonymous"}}` + "\n",
+				`{"type":"panelist","data":{"id":"mlk","name":"Martin Luther King Jr."}}` + "\n",
+			},
+			wantTypes:     []string{"panelist", "done"},
+			wantPanelists: []string{"Martin Luther King Jr."},
 		},
 		{
-			name:     "invalid JSON",
-			response: `{"isRelevant": true, "message":`,
-			wantErr:  true,
-		},
-		{
-			name:         "message truncation",
-			response:     `{"isRelevant": true, "message": "` + string(make([]byte, 250)) + `"}`,
-			wantRelevant: true,
-			wantMessage:  "",
-			wantErr:      false,
+			name: "old single object format in markdown",
+			deltas: []string{
+				"```json\n",
+				`{"isRelevant": true, "message": "Good topic", "panelists": [{"id":"aquinas","name":"Thomas Aquinas"}]}`,
+				"\n```",
+			},
+			wantTypes:     []string{"validation", "panelist", "done"},
+			wantPanelists: []string{"Thomas Aquinas"},
+			wantRelevant:  boolPtr(true),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRelevant, gotMessage, err := client.parseValidationResponse(tt.response)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("parseValidationResponse() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if err != nil {
-				return
+			client := NewClaudeClientWithModel(llm.NewFake(tt.deltas...))
+			stream := client.model.Stream(context.Background(), llm.Request{})
+
+			var output bytes.Buffer
+			if err := client.streamPanelistResponse(stream, sse.NewWriter(&output)); err != nil {
+				t.Fatalf("streamPanelistResponse() error = %v", err)
 			}
-			if gotRelevant != tt.wantRelevant {
-				t.Errorf("parseValidationResponse() gotRelevant = %v, want %v", gotRelevant, tt.wantRelevant)
+
+			chunks := decodeChunks(t, output.String())
+			var gotTypes, gotPanelists []string
+			for _, chunk := range chunks {
+				gotTypes = append(gotTypes, chunk.Type)
+
+				switch chunk.Type {
+				case "panelist":
+					var p Panelist
+					json.Unmarshal([]byte(chunk.Data), &p)
+					gotPanelists = append(gotPanelists, p.Name)
+				case "validation":
+					var v struct {
+						IsRelevant bool `json:"isRelevant"`
+					}
+					json.Unmarshal([]byte(chunk.Data), &v)
+					if tt.wantRelevant != nil && v.IsRelevant != *tt.wantRelevant {
+						t.Errorf("validation isRelevant = %v, want %v", v.IsRelevant, *tt.wantRelevant)
+					}
+				}
 			}
-			if tt.wantMessage != "" && gotMessage != tt.wantMessage {
-				t.Errorf("parseValidationResponse() gotMessage = %v, want %v", gotMessage, tt.wantMessage)
+
+			if strings.Join(gotTypes, ",") != strings.Join(tt.wantTypes, ",") {
+				t.Errorf("chunk types = %v, want %v", gotTypes, tt.wantTypes)
 			}
-			if len(gotMessage) > 200 {
-				t.Errorf("parseValidationResponse() message length = %d, want <= 200", len(gotMessage))
+			if strings.Join(gotPanelists, ",") != strings.Join(tt.wantPanelists, ",") {
+				t.Errorf("panelists = %v, want %v", gotPanelists, tt.wantPanelists)
 			}
 		})
 	}
 }
 
-func TestValidateTopicRelevance(t *testing.T) {
-	// Skip this test if ANTHROPIC_API_KEY is not set (integration test)
-	if os.Getenv("ANTHROPIC_API_KEY") == "" {
-		t.Skip("Skipping integration test: ANTHROPIC_API_KEY not set")
+func TestStreamPanelistResponseTruncatesFields(t *testing.T) {
+	line := `{"type":"panelist","data":{"id":"long","name":"Long Winded","tagline":"` + strings.Repeat("t", 80) +
+		`","bio":"` + strings.Repeat("b", 400) + `","position":"` + strings.Repeat("p", 150) + `"}}` + "\n"
+	client := NewClaudeClientWithModel(llm.NewFake(line))
+
+	var output bytes.Buffer
+	if err := client.streamPanelistResponse(client.model.Stream(context.Background(), llm.Request{}), sse.NewWriter(&output)); err != nil {
+		t.Fatalf("streamPanelistResponse() error = %v", err)
 	}
 
-	client, err := NewClaudeClient()
+	var p Panelist
+	json.Unmarshal([]byte(decodeChunks(t, output.String())[0].Data), &p)
+	if len(p.Tagline) != 60 || len(p.Bio) != 300 || len(p.Position) != 100 {
+		t.Errorf("field lengths = %d/%d/%d, want 60/300/100", len(p.Tagline), len(p.Bio), len(p.Position))
+	}
+}
+
+func TestStreamPanelistResponseLifespan(t *testing.T) {
+	client := NewClaudeClientWithModel(llm.NewFake(
+		`{"type":"panelist","data":{"id":"augustine","name":"Augustine of Hippo","born":354,"died":430}}`+"\n",
+		`{"type":"panelist","data":{"id":"plato","name":"Plato","born":-428,"died":-348}}`+"\n",
+		`{"type":"panelist","data":{"id":"muddled","name":"Muddled","born":1900,"died":1850}}`+"\n",
+	))
+
+	var output bytes.Buffer
+	if err := client.streamPanelistResponse(client.model.Stream(context.Background(), llm.Request{}), sse.NewWriter(&output)); err != nil {
+		t.Fatalf("streamPanelistResponse() error = %v", err)
+	}
+
+	want := map[string][2]int{"augustine": {354, 430}, "plato": {-428, -348}, "muddled": {0, 0}}
+	for _, chunk := range decodeChunks(t, output.String()) {
+		if chunk.Type != "panelist" {
+			continue
+		}
+		var p Panelist
+		json.Unmarshal([]byte(chunk.Data), &p)
+		if got := [2]int{p.Born, p.Died}; got != want[p.ID] {
+			t.Errorf("%s lifespan = %v, want %v", p.ID, got, want[p.ID])
+		}
+	}
+}
+
+func TestValidateTopicAndSuggestPanelists(t *testing.T) {
+	fake := llm.NewFake(`{"type":"rejection","message":"Not relevant"}` + "\n")
+	client := NewClaudeClientWithModel(fake)
+
+	var output bytes.Buffer
+	err := client.ValidateTopicAndSuggestPanelists(context.Background(), "What is the best pizza topping?", []string{"Gordon Ramsay"}, "", sse.NewWriter(&output))
 	if err != nil {
-		t.Fatalf("Failed to create Claude client: %v", err)
+		t.Fatalf("ValidateTopicAndSuggestPanelists() error = %v", err)
+	}
+
+	prompt := fake.Requests()[0].Messages[0].Text
+	for _, want := range []string{`Topic: "What is the best pizza topping?"`, "- Gordon Ramsay"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt does not contain %q", want)
+		}
+	}
+}
+
+func TestValidateTopicAndSuggestPanelistsLanguage(t *testing.T) {
+	fake := llm.NewFake(`{"type":"panelist","data":{"id":"augustin","name":"Augustin d'Hippone"}}` + "\n")
+	client := NewClaudeClientWithModel(fake)
+
+	var output bytes.Buffer
+	err := client.ValidateTopicAndSuggestPanelists(context.Background(), "La guerre peut-elle être juste ?", nil, "fr", sse.NewWriter(&output))
+	if err != nil {
+		t.Fatalf("ValidateTopicAndSuggestPanelists() error = %v", err)
+	}
+
+	prompt := fake.Requests()[0].Messages[0].Text
+	for _, want := range []string{"the debate will be held in French", "tagline, bio and position in French", "ASCII kebab-case"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt does not contain %q", want)
+		}
+	}
+
+	// English prompts have no language section
+	fake = llm.NewFake()
+	client = NewClaudeClientWithModel(fake)
+	if err := client.ValidateTopicAndSuggestPanelists(context.Background(), "Is war ever just?", nil, "en", sse.NewWriter(&output)); err != nil {
+		t.Fatalf("ValidateTopicAndSuggestPanelists() error = %v", err)
+	}
+	if prompt := fake.Requests()[0].Messages[0].Text; strings.Contains(prompt, "Language:") {
+		t.Error("English prompt has a language section")
 	}
+}
 
+func TestTruncate(t *testing.T) {
 	tests := []struct {
-		name  string
-		topic string
+		text string
+		max  int
+		want string
 	}{
-		{
-			name:  "theological topic",
-			topic: "Should Christians defy authorities when the law is unfair?",
-		},
-		{
-			name:  "philosophical topic",
-			topic: "What is the nature of consciousness?",
-		},
+		{text: "short", max: 10, want: "short"},
+		{text: "exactly10!", max: 10, want: "exactly10!"},
+		{text: "far too long", max: 10, want: "far too..."},
+		{text: "Évêque d'Hippone", max: 10, want: "Évêque ..."},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			ctx := context.Background()
-			isRelevant, message, err := client.ValidateTopicRelevance(ctx, tt.topic)
-			if err != nil {
-				t.Errorf("ValidateTopicRelevance() error = %v", err)
-				return
-			}
-			if message == "" {
-				t.Error("ValidateTopicRelevance() returned empty message")
-			}
-			if len(message) > 200 {
-				t.Errorf("ValidateTopicRelevance() message length = %d, want <= 200", len(message))
-			}
-			t.Logf("Topic: %s\nRelevant: %v\nMessage: %s", tt.topic, isRelevant, message)
-		})
+		if got := truncate(tt.text, tt.max); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.text, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTopicStreamError(t *testing.T) {
+	streamErr := errors.New("overloaded")
+	client := NewClaudeClientWithModel(llm.NewFakeScripts(llm.Script{Err: streamErr}))
+
+	var output bytes.Buffer
+	err := client.ValidateTopicAndSuggestPanelists(context.Background(), "Is war ever just?", nil, "", sse.NewWriter(&output))
+	if !errors.Is(err, streamErr) {
+		t.Errorf("ValidateTopicAndSuggestPanelists() error = %v, want %v", err, streamErr)
 	}
 }
+
+func boolPtr(b bool) *bool {
+	return &b
+}
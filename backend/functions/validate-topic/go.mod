@@ -1,8 +1,11 @@
 module github.com/raphink/debate/functions/validate-topic
 
-go 1.24
+go 1.24.0
 
-require github.com/anthropics/anthropic-sdk-go v1.19.0
+require (
+	github.com/anthropics/anthropic-sdk-go v1.19.0
+	github.com/raphink/debate/shared v0.0.0-00010101000000-000000000000
+)
 
 require (
 	github.com/tidwall/gjson v1.18.0 // indirect
@@ -10,3 +13,5 @@ require (
 	github.com/tidwall/pretty v1.2.1 // indirect
 	github.com/tidwall/sjson v1.2.5 // indirect
 )
+
+replace github.com/raphink/debate/shared => ../../shared
@@ -5,9 +5,14 @@ import (
 	"log"
 	"net/http"
 	"os"
+
+	"github.com/raphink/debate/shared/sse"
 )
 
-// handleValidateTopicImpl is the HTTP handler for the validate-topic Cloud Function
+// newClaudeClient creates the client used by the handler (replaced in tests)
+var newClaudeClient = NewClaudeClient
+
+// HandleValidateTopic is the HTTP handler for the validate-topic Cloud Function
 func HandleValidateTopic(w http.ResponseWriter, r *http.Request) {
 	// Set CORS headers - allow configured origin or localhost for dev
 	allowedOrigin := os.Getenv("ALLOWED_ORIGIN")
@@ -18,10 +23,8 @@ func HandleValidateTopic(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
-	// Set SSE headers for streaming
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
+	// Errors are sent as JSON; the event stream overrides the content type
+	w.Header().Set("Content-Type", "application/json")
 
 	// Handle preflight OPTIONS request
 	if r.Method == "OPTIONS" {
@@ -58,6 +61,11 @@ func HandleValidateTopic(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(MapErrorToResponse(err))
 		return
 	}
+	if err := ValidateLanguage(req.Language); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(MapErrorToResponse(err))
+		return
+	}
 
 	// Sanitize the topic
 	sanitizedTopic := SanitizeTopic(req.Topic)
@@ -75,7 +83,7 @@ func HandleValidateTopic(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create Claude client
-	claudeClient, err := NewClaudeClient()
+	claudeClient, err := newClaudeClient()
 	if err != nil {
 		log.Printf("Error creating Claude client: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -87,15 +95,19 @@ func HandleValidateTopic(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Set SSE headers for streaming
+	sse.SetHeaders(w)
+
 	// Validate topic and stream panelist suggestions from Claude
-	if err := claudeClient.ValidateTopicAndSuggestPanelists(r.Context(), sanitizedTopic, suggestedNames, w); err != nil {
+	events := sse.NewWriter(w)
+	if err := claudeClient.ValidateTopicAndSuggestPanelists(r.Context(), sanitizedTopic, suggestedNames, req.Language, events); err != nil {
 		log.Printf("Error validating topic with Claude: %v", err)
 		// Send error chunk
-		errorChunk := map[string]string{
+		errorChunk, _ := json.Marshal(map[string]string{
 			"type":  "error",
 			"error": "The AI service is temporarily unavailable. Please try again in a few moments.",
-		}
-		json.NewEncoder(w).Encode(errorChunk)
+		})
+		events.Send("error", errorChunk)
 		return
 	}
 }
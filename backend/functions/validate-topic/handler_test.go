@@ -0,0 +1,81 @@
+package validatetopic
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/raphink/debate/shared/llm"
+)
+
+// useFakeModel replaces the language model for the duration of a test
+func useFakeModel(t *testing.T, model llm.LanguageModel) {
+	t.Helper()
+
+	previous := newClaudeClient
+	newClaudeClient = func() (*ClaudeClient, error) {
+		return NewClaudeClientWithModel(model), nil
+	}
+	t.Cleanup(func() { newClaudeClient = previous })
+}
+
+func TestHandleValidateTopic(t *testing.T) {
+	fake := llm.NewFake(
+		`{"type":"panelist","data":{"id":"augustine","name":"Augustine of Hippo"}}` + "\n",
+	)
+	useFakeModel(t, fake)
+
+	body := `{"topic": "Should Christians defy unjust laws?", "suggestedNames": ["<b>Thomas Aquinas</b>"]}`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	HandleValidateTopic(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	chunks := decodeChunks(t, rec.Body.String())
+	if len(chunks) != 2 || chunks[0].Type != "panelist" || chunks[1].Type != "done" {
+		t.Errorf("chunks = %+v, want one panelist and done", chunks)
+	}
+
+	prompt := fake.Requests()[0].Messages[0].Text
+	if !strings.Contains(prompt, "- Thomas Aquinas\n") {
+		t.Error("prompt does not contain the sanitized suggested name")
+	}
+}
+
+func TestHandleValidateTopicInvalidTopic(t *testing.T) {
+	useFakeModel(t, llm.NewFake())
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"topic": "short"}`))
+	rec := httptest.NewRecorder()
+	HandleValidateTopic(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+}
+
+func TestHandleValidateTopicUnsupportedLanguage(t *testing.T) {
+	useFakeModel(t, llm.NewFake())
+
+	body := `{"topic": "Should Christians defy unjust laws?", "language": "tlh"}`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	HandleValidateTopic(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), ErrInvalidLanguage) {
+		t.Errorf("body = %s, want %s", rec.Body.String(), ErrInvalidLanguage)
+	}
+}
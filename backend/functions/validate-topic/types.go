@@ -4,6 +4,7 @@ package validatetopic
 type TopicValidationRequest struct {
 	Topic          string   `json:"topic"`
 	SuggestedNames []string `json:"suggestedNames,omitempty"` // Optional: user-suggested panelist names (max 5)
+	Language       string   `json:"language,omitempty"`       // Optional: ISO 639-1 code of the debate language (default "en")
 }
 
 // Panelist represents a suggested debate participant
@@ -14,6 +15,8 @@ type Panelist struct {
 	Bio       string `json:"bio"`
 	AvatarURL string `json:"avatarUrl"`
 	Position  string `json:"position"`
+	Born      int    `json:"born,omitempty"` // Year of birth, negative before Christ
+	Died      int    `json:"died,omitempty"` // Year of death, unset for the living
 }
 
 // TopicValidationResponse represents the response after validating a topic
@@ -37,6 +40,7 @@ type ErrorResponse struct {
 const (
 	ErrInvalidTopicLength  = "INVALID_TOPIC_LENGTH"
 	ErrInvalidTopicContent = "INVALID_TOPIC_CONTENT"
+	ErrInvalidLanguage     = "INVALID_LANGUAGE"
 	ErrRateLimitExceeded   = "RATE_LIMIT_EXCEEDED"
 	ErrInternalError       = "INTERNAL_ERROR"
 	ErrServiceUnavailable  = "SERVICE_UNAVAILABLE"
@@ -3,7 +3,11 @@ package validatetopic
 import (
 	"errors"
 	"fmt"
+	"regexp"
 	"strings"
+	"unicode/utf8"
+
+	"github.com/raphink/debate/shared/language"
 )
 
 const (
@@ -11,10 +15,14 @@ const (
 	MaxTopicLength = 500
 )
 
+// htmlTagPattern matches HTML tags
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
 var (
 	ErrTopicTooShort = errors.New("topic must be at least 10 characters long")
 	ErrTopicTooLong  = errors.New("topic must not exceed 500 characters")
 	ErrTopicInvalid  = errors.New("topic contains invalid characters or HTML content")
+	ErrLanguage      = errors.New("language is not supported")
 )
 
 // ValidateTopicInput validates the topic input from the request
@@ -22,11 +30,12 @@ func ValidateTopicInput(topic string) error {
 	// Trim whitespace
 	topic = strings.TrimSpace(topic)
 
-	// Check length
-	if len(topic) < MinTopicLength {
+	// Check length, in characters rather than bytes
+	length := utf8.RuneCountInString(topic)
+	if length < MinTopicLength {
 		return ErrTopicTooShort
 	}
-	if len(topic) > MaxTopicLength {
+	if length > MaxTopicLength {
 		return ErrTopicTooLong
 	}
 
@@ -44,12 +53,21 @@ func ValidateTopicInput(topic string) error {
 	return nil
 }
 
+// ValidateLanguage checks that the requested debate language is supported
+func ValidateLanguage(code string) error {
+	if err := language.Validate(code); err != nil {
+		return fmt.Errorf("%w: %v", ErrLanguage, err)
+	}
+	return nil
+}
+
 // SanitizeTopic removes potentially dangerous characters from the topic
 func SanitizeTopic(topic string) string {
 	// Trim whitespace
 	topic = strings.TrimSpace(topic)
 
 	// Remove any HTML tags (just in case they slipped through)
+	topic = htmlTagPattern.ReplaceAllString(topic, "")
 	topic = strings.ReplaceAll(topic, "<", "")
 	topic = strings.ReplaceAll(topic, ">", "")
 
@@ -74,6 +92,12 @@ func MapErrorToResponse(err error) ErrorResponse {
 			Code:      ErrInvalidTopicLength,
 			Retryable: true,
 		}
+	case errors.Is(err, ErrLanguage):
+		return ErrorResponse{
+			Error:     fmt.Sprintf("Language must be one of %s", strings.Join(language.Codes(), ", ")),
+			Code:      ErrInvalidLanguage,
+			Retryable: false,
+		}
 	case errors.Is(err, ErrTopicInvalid):
 		return ErrorResponse{
 			Error:     "Topic contains invalid characters or HTML content",
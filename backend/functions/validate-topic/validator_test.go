@@ -11,143 +11,154 @@ func TestValidateTopicInput(t *testing.T) {
 		name    string
 		topic   string
 		wantErr error
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-
-}	}		})			}				t.Errorf("MapErrorToResponse() Error message is empty")			if got.Error == "" {			}				t.Errorf("MapErrorToResponse() Retryable = %v, want true", got.Retryable)			if !got.Retryable {			}				t.Errorf("MapErrorToResponse() Code = %v, want %v", got.Code, tt.wantCode)			if got.Code != tt.wantCode {			got := MapErrorToResponse(tt.err)		t.Run(tt.name, func(t *testing.T) {	for _, tt := range tests {	}		},			wantCode: ErrInternalError,			err:      errors.New("some other error"),			name:     "unknown error",		{		},			wantCode: ErrInvalidTopicContent,			err:      ErrTopicInvalid,			name:     "topic invalid",		{		},			wantCode: ErrInvalidTopicLength,			err:      ErrTopicTooLong,			name:     "topic too long",		{		},			wantCode: ErrInvalidTopicLength,			err:      ErrTopicTooShort,			name:     "topic too short",		{	}{		wantCode string		err      error		name     string	tests := []struct {func TestMapErrorToResponse(t *testing.T) {}	}		})			}				t.Errorf("SanitizeTopic() = %v, want %v", got, tt.want)			if got != tt.want {			got := SanitizeTopic(tt.topic)		t.Run(tt.name, func(t *testing.T) {	for _, tt := range tests {	}		},			want:  "Should Christians defy authorities when the law is unfair?",			topic: "Should Christians defy authorities when the law is unfair?",			name:  "clean topic unchanged",		{		},			want:  "alert('xss')Should Christians defy authorities?",			topic: "<script>alert('xss')</script>Should Christians defy authorities?<img src=x>",			name:  "remove multiple HTML tags",		{		},			want:  "Should Christians defy authorities?",			topic: "Should   Christians    defy   authorities?",			name:  "normalize whitespace",		{		},			want:  "Should Christians defy authorities?",			topic: "  Should Christians defy authorities?  ",			name:  "trim whitespace",		{		},			want:  "Should Christians defy authorities?",			topic: "Should Christians <b>defy</b> authorities?",			name:  "remove HTML tags",		{	}{		want  string		topic string		name  string	tests := []struct {func TestSanitizeTopic(t *testing.T) {}	}		})			}				t.Errorf("ValidateTopicInput() error = %v, wantErr %v", err, tt.wantErr)			if !errors.Is(err, tt.wantErr) {			err := ValidateTopicInput(tt.topic)		t.Run(tt.name, func(t *testing.T) {	for _, tt := range tests {	}		},			wantErr: nil,			topic:   strings.Repeat("a", 500), // Exactly 500 characters			name:    "maximum valid length",		{		},			wantErr: nil,			topic:   "0123456789", // Exactly 10 characters			name:    "minimum valid length",		{		},			wantErr: ErrTopicInvalid,			topic:   "Should Christians &lt;script&gt; defy authorities?",			name:    "topic with HTML entities",		{		},			wantErr: ErrTopicInvalid,			topic:   "Should Christians <script>alert('xss')</script> defy authorities?",			name:    "topic with HTML tags",		{		},			wantErr: ErrTopicTooLong,			topic:   strings.Repeat("a", 501),			name:    "topic too long",		{		},			wantErr: ErrTopicTooShort,			topic:   "Short",			name:    "topic too short",		{		},			wantErr: nil,			topic:   "  Should Christians defy authorities?  ",			name:    "topic with extra whitespace",		{		},			wantErr: nil,			topic:   "Should Christians defy authorities when the law is unfair?",			name:    "valid topic",		{	}{
\ No newline at end of file
+	}{
+		{
+			name:    "valid topic",
+			topic:   "Should Christians defy authorities when the law is unfair?",
+			wantErr: nil,
+		},
+		{
+			name:    "topic with extra whitespace",
+			topic:   "  Should Christians defy authorities?  ",
+			wantErr: nil,
+		},
+		{
+			name:    "topic too short",
+			topic:   "Short",
+			wantErr: ErrTopicTooShort,
+		},
+		{
+			name:    "topic too long",
+			topic:   strings.Repeat("a", 501),
+			wantErr: ErrTopicTooLong,
+		},
+		{
+			name:    "topic with HTML tags",
+			topic:   "Should Christians <script>alert('xss')</script> defy authorities?",
+			wantErr: ErrTopicInvalid,
+		},
+		{
+			name:    "topic with HTML entities",
+			topic:   "Should Christians &lt;script&gt; defy authorities?",
+			wantErr: ErrTopicInvalid,
+		},
+		{
+			name:    "minimum valid length",
+			topic:   "0123456789", // Exactly 10 characters
+			wantErr: nil,
+		},
+		{
+			name:    "maximum valid length",
+			topic:   strings.Repeat("a", 500), // Exactly 500 characters
+			wantErr: nil,
+		},
+		{
+			name:    "maximum valid length in Greek",
+			topic:   strings.Repeat("α", 500), // 500 characters, 1000 bytes
+			wantErr: nil,
+		},
+		{
+			name:    "topic too long in Japanese",
+			topic:   strings.Repeat("論", 501),
+			wantErr: ErrTopicTooLong,
+		},
+		{
+			name:    "topic too short in Japanese",
+			topic:   "戦争は正義か", // 6 characters, 18 bytes
+			wantErr: ErrTopicTooShort,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTopicInput(tt.topic)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateTopicInput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSanitizeTopic(t *testing.T) {
+	tests := []struct {
+		name  string
+		topic string
+		want  string
+	}{
+		{
+			name:  "remove HTML tags",
+			topic: "Should Christians <b>defy</b> authorities?",
+			want:  "Should Christians defy authorities?",
+		},
+		{
+			name:  "trim whitespace",
+			topic: "  Should Christians defy authorities?  ",
+			want:  "Should Christians defy authorities?",
+		},
+		{
+			name:  "normalize whitespace",
+			topic: "Should   Christians    defy   authorities?",
+			want:  "Should Christians defy authorities?",
+		},
+		{
+			name:  "remove multiple HTML tags",
+			topic: "<script>alert('xss')</script>Should Christians defy authorities?<img src=x>",
+			want:  "alert('xss')Should Christians defy authorities?",
+		},
+		{
+			name:  "clean topic unchanged",
+			topic: "Should Christians defy authorities when the law is unfair?",
+			want:  "Should Christians defy authorities when the law is unfair?",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SanitizeTopic(tt.topic)
+			if got != tt.want {
+				t.Errorf("SanitizeTopic() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapErrorToResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode string
+	}{
+		{
+			name:     "topic too short",
+			err:      ErrTopicTooShort,
+			wantCode: ErrInvalidTopicLength,
+		},
+		{
+			name:     "topic too long",
+			err:      ErrTopicTooLong,
+			wantCode: ErrInvalidTopicLength,
+		},
+		{
+			name:     "topic invalid",
+			err:      ErrTopicInvalid,
+			wantCode: ErrInvalidTopicContent,
+		},
+		{
+			name:     "unknown error",
+			err:      errors.New("some other error"),
+			wantCode: ErrInternalError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MapErrorToResponse(tt.err)
+			if got.Code != tt.wantCode {
+				t.Errorf("MapErrorToResponse() Code = %v, want %v", got.Code, tt.wantCode)
+			}
+			if !got.Retryable {
+				t.Errorf("MapErrorToResponse() Retryable = %v, want true", got.Retryable)
+			}
+			if got.Error == "" {
+				t.Errorf("MapErrorToResponse() Error message is empty")
+			}
+		})
+	}
+}
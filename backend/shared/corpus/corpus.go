@@ -0,0 +1,202 @@
+// Package corpus indexes public-domain primary texts in a local SQLite
+// full-text index and retrieves the passages relevant to a debate
+package corpus
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+	_ "modernc.org/sqlite" // Pure Go driver, works with CGO_ENABLED=0
+)
+
+// schema creates the passage index if it does not exist yet. Passages are
+// searched on their text only, with English stemming and without accents;
+// authors are looked up by the keys of their name and aliases.
+const schema = `
+CREATE VIRTUAL TABLE IF NOT EXISTS passages USING fts5(
+	id UNINDEXED,
+	author UNINDEXED,
+	work UNINDEXED,
+	locator UNINDEXED,
+	text,
+	tokenize = 'porter unicode61 remove_diacritics 2'
+);
+CREATE TABLE IF NOT EXISTS authors (
+	key    TEXT PRIMARY KEY,
+	author TEXT NOT NULL
+);
+`
+
+// minTermLength is the minimum length for query terms to be searched
+const minTermLength = 3
+
+// ErrNotConfigured is returned by Default when CORPUS_INDEX is not set
+var ErrNotConfigured = errors.New("no corpus index configured")
+
+// Passage is an excerpt of a work in the corpus
+type Passage struct {
+	ID      string // Source file and position, e.g. "confessions.txt#12"
+	Author  string
+	Work    string
+	Locator string // Book, chapter or section, e.g. "Book VIII, Chapter 12"
+	Text    string
+}
+
+// Index is a full-text index of the corpus, stored in a SQLite database file
+type Index struct {
+	db *sql.DB
+}
+
+// Open opens (or creates) the index at path
+func Open(ctx context.Context, path string) (*Index, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open corpus index %s: %w", path, err)
+	}
+
+	// SQLite allows a single writer; serialize access through one connection
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create corpus index schema: %w", err)
+	}
+
+	return &Index{db: db}, nil
+}
+
+// Close closes the index
+func (i *Index) Close() error {
+	return i.db.Close()
+}
+
+// Retrieve returns up to limit passages by the author known by one of
+// names, ranked by relevance to query. It returns no passages if no author
+// of the corpus matches names.
+func (i *Index) Retrieve(ctx context.Context, names []string, query string, limit int) ([]Passage, error) {
+	author, err := i.author(ctx, names)
+	if err != nil || author == "" {
+		return nil, err
+	}
+
+	match := matchQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+
+	rows, err := i.db.QueryContext(ctx,
+		`SELECT id, author, work, locator, text FROM passages
+		 WHERE passages MATCH ? AND author = ?
+		 ORDER BY rank LIMIT ?`,
+		match, author, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search corpus: %w", err)
+	}
+	defer rows.Close()
+
+	var passages []Passage
+	for rows.Next() {
+		var p Passage
+		if err := rows.Scan(&p.ID, &p.Author, &p.Work, &p.Locator, &p.Text); err != nil {
+			return nil, fmt.Errorf("failed to read passage: %w", err)
+		}
+		passages = append(passages, p)
+	}
+	return passages, rows.Err()
+}
+
+// author returns the corpus author known by one of names, or "" if none is
+func (i *Index) author(ctx context.Context, names []string) (string, error) {
+	for _, name := range names {
+		var author string
+		err := i.db.QueryRowContext(ctx, `SELECT author FROM authors WHERE key = ?`, Key(name)).Scan(&author)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to look up author: %w", err)
+		}
+		return author, nil
+	}
+	return "", nil
+}
+
+// Key normalizes a name or ID into the kebab-case key authors are looked up
+// by: "Thomas Aquinas", "thomas-aquinas" and "Thómas  Aquinas" share a key
+func Key(name string) string {
+	var key strings.Builder
+	dash := false
+	for _, r := range norm.NFD.String(strings.ToLower(name)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// Drop accents
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if dash && key.Len() > 0 {
+				key.WriteByte('-')
+			}
+			dash = false
+			key.WriteRune(r)
+		default:
+			dash = true
+		}
+	}
+	return key.String()
+}
+
+// matchQuery builds a full-text query matching any significant term of text
+func matchQuery(text string) string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, term := range strings.Split(Key(text), "-") {
+		if len([]rune(term)) >= minTermLength && !seen[term] {
+			seen[term] = true
+			terms = append(terms, `"`+term+`"`)
+		}
+	}
+	return strings.Join(terms, " OR ")
+}
+
+var (
+	defaultIndex *Index
+	defaultMu    sync.Mutex
+)
+
+// Default returns the process-wide index, opened from the CORPUS_INDEX
+// environment variable on first use
+func Default(ctx context.Context) (*Index, error) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	if defaultIndex != nil {
+		return defaultIndex, nil
+	}
+
+	path := os.Getenv("CORPUS_INDEX")
+	if path == "" {
+		return nil, ErrNotConfigured
+	}
+
+	index, err := Open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultIndex = index
+	log.Printf("Corpus index opened: %s", path)
+	return defaultIndex, nil
+}
+
+// SetDefault replaces the process-wide index (used by tests and local servers)
+func SetDefault(index *Index) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultIndex = index
+}
@@ -0,0 +1,173 @@
+package corpus
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const confessions = `Author: Augustine of Hippo
+Aliases: Augustine, Saint Augustine
+Work: Confessions
+
+# Book I, Chapter 1
+
+Thou hast made us for Thyself, and our heart is restless until it rests in Thee.
+
+# Book VIII, Chapter 12
+
+I heard from a neighbouring house a voice, as of boy or girl, chanting,
+"Take up and read; take up and read."
+`
+
+const summa = `{"author": "Thomas Aquinas", "aliases": ["Aquinas"], "work": "Summa Theologica", "locator": "II-II, Q. 40, Art. 1", "text": "In order for a war to be just, three things are necessary: the authority of the sovereign, a just cause, and a rightful intention."}
+
+{"author": "Thomas Aquinas", "work": "Summa Theologica", "locator": "I-II, Q. 96, Art. 4", "text": "Laws framed by man are either just or unjust. Unjust laws are acts of violence rather than laws."}
+`
+
+// testIndex returns an index loaded with a plain-text and a JSONL work
+func testIndex(t *testing.T) *Index {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "confessions.txt"), []byte(confessions), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "aquinas"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "aquinas", "summa.jsonl"), []byte(summa), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := Open(context.Background(), filepath.Join(t.TempDir(), "corpus.db"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { index.Close() })
+
+	count, err := index.Load(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if count != 4 {
+		t.Errorf("Load() indexed %d passages, want 4", count)
+	}
+	return index
+}
+
+func TestRetrieve(t *testing.T) {
+	index := testIndex(t)
+
+	tests := []struct {
+		name    string
+		names   []string
+		query   string
+		wantIDs []string
+	}{
+		{name: "by name", names: []string{"Thomas Aquinas"}, query: "Is war ever justified?", wantIDs: []string{"aquinas/summa.jsonl#1"}},
+		{name: "by panelist ID", names: []string{"Saint Thomas", "thomas-aquinas"}, query: "unjust laws", wantIDs: []string{"aquinas/summa.jsonl#3"}},
+		{name: "by alias", names: []string{"Saint Augustine"}, query: "a restless heart", wantIDs: []string{"confessions.txt#2"}},
+		{name: "accents and stemming", names: []string{"Augustíne"}, query: "Reading chants", wantIDs: []string{"confessions.txt#4"}},
+		{name: "other author's passages", names: []string{"Augustine"}, query: "just war", wantIDs: nil},
+		{name: "unknown author", names: []string{"Martin Luther King Jr."}, query: "unjust laws", wantIDs: nil},
+		{name: "no significant term", names: []string{"Aquinas"}, query: "Is it so?", wantIDs: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			passages, err := index.Retrieve(context.Background(), tt.names, tt.query, 3)
+			if err != nil {
+				t.Fatalf("Retrieve() error = %v", err)
+			}
+
+			var ids []string
+			for _, p := range passages {
+				ids = append(ids, p.ID)
+			}
+			if len(ids) != len(tt.wantIDs) {
+				t.Fatalf("Retrieve() = %v, want %v", ids, tt.wantIDs)
+			}
+			for i := range ids {
+				if ids[i] != tt.wantIDs[i] {
+					t.Errorf("Retrieve() = %v, want %v", ids, tt.wantIDs)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadPassage(t *testing.T) {
+	index := testIndex(t)
+
+	passages, err := index.Retrieve(context.Background(), []string{"Augustine"}, "take up and read", 1)
+	if err != nil || len(passages) != 1 {
+		t.Fatalf("Retrieve() = %v, %v", passages, err)
+	}
+
+	p := passages[0]
+	if p.Author != "Augustine of Hippo" || p.Work != "Confessions" || p.Locator != "Book VIII, Chapter 12" {
+		t.Errorf("passage = %+v", p)
+	}
+	if want := `I heard from a neighbouring house a voice, as of boy or girl, chanting, "Take up and read; take up and read."`; p.Text != want {
+		t.Errorf("passage text = %q, want %q", p.Text, want)
+	}
+}
+
+func TestLoadReplaces(t *testing.T) {
+	index := testIndex(t)
+
+	count, err := index.Load(context.Background(), t.TempDir())
+	if err != nil || count != 0 {
+		t.Fatalf("Load() = %d, %v", count, err)
+	}
+	passages, err := index.Retrieve(context.Background(), []string{"Aquinas"}, "just war", 3)
+	if err != nil || len(passages) != 0 {
+		t.Errorf("Retrieve() after reload = %v, %v, want no passages", passages, err)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{name: "text without header", file: "bad.txt", content: "Just some text.\n\nAnd more."},
+		{name: "invalid JSONL", file: "bad.jsonl", content: `{"author": "Aquinas"`},
+		{name: "JSONL without text", file: "bad.jsonl", content: `{"author": "Aquinas", "work": "Summa"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, tt.file), []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			index, err := Open(context.Background(), filepath.Join(t.TempDir(), "corpus.db"))
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer index.Close()
+
+			if _, err := index.Load(context.Background(), dir); err == nil {
+				t.Error("Load() accepted an invalid corpus file")
+			}
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := map[string]string{
+		"Thomas Aquinas":         "thomas-aquinas",
+		"thomas-aquinas":         "thomas-aquinas",
+		"  Thómas  Aquinas ":     "thomas-aquinas",
+		"Martin Luther King Jr.": "martin-luther-king-jr",
+	}
+	for name, want := range tests {
+		if got := Key(name); got != want {
+			t.Errorf("Key(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
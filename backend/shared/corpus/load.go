@@ -0,0 +1,184 @@
+package corpus
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// work is a set of passages read from a corpus file, with the names its
+// author is known by
+type work struct {
+	author   string
+	aliases  []string
+	passages []Passage
+}
+
+// record is a line of a JSONL corpus file
+type record struct {
+	Author  string   `json:"author"`
+	Aliases []string `json:"aliases,omitempty"`
+	Work    string   `json:"work"`
+	Locator string   `json:"locator,omitempty"`
+	Text    string   `json:"text"`
+}
+
+// Load replaces the contents of the index with the corpus files in dir and
+// returns the number of passages indexed. Two formats are read:
+//
+//   - .jsonl files, with one passage per line as an object with "author",
+//     optional "aliases", "work", optional "locator" and "text"
+//   - .txt files, starting with "Author:", optional "Aliases:" (comma
+//     separated) and "Work:" header lines and a blank line. Each following
+//     paragraph is a passage, located by the last "# " heading before it.
+func (i *Index) Load(ctx context.Context, dir string) (int, error) {
+	var works []work
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		name, _ := filepath.Rel(dir, path)
+		switch filepath.Ext(path) {
+		case ".jsonl":
+			read, err := readJSONL(path, name)
+			works = append(works, read...)
+			return err
+		case ".txt":
+			read, err := readText(path, name)
+			works = append(works, read)
+			return err
+		default:
+			return nil
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM passages; DELETE FROM authors;`); err != nil {
+		return 0, fmt.Errorf("failed to clear corpus index: %w", err)
+	}
+
+	count := 0
+	for _, w := range works {
+		for _, name := range append([]string{w.author}, w.aliases...) {
+			if _, err := tx.ExecContext(ctx, `INSERT OR REPLACE INTO authors (key, author) VALUES (?, ?)`, Key(name), w.author); err != nil {
+				return 0, fmt.Errorf("failed to index author %s: %w", name, err)
+			}
+		}
+		for _, p := range w.passages {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO passages (id, author, work, locator, text) VALUES (?, ?, ?, ?, ?)`,
+				p.ID, p.Author, p.Work, p.Locator, p.Text); err != nil {
+				return 0, fmt.Errorf("failed to index passage %s: %w", p.ID, err)
+			}
+			count++
+		}
+	}
+
+	return count, tx.Commit()
+}
+
+// readJSONL reads the passages of a JSONL corpus file, one work per line
+func readJSONL(path, name string) ([]work, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var works []work
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+		if r.Author == "" || r.Work == "" || strings.TrimSpace(r.Text) == "" {
+			return nil, fmt.Errorf("%s:%d: author, work and text are required", name, line)
+		}
+
+		works = append(works, work{
+			author:  r.Author,
+			aliases: r.Aliases,
+			passages: []Passage{{
+				ID:      fmt.Sprintf("%s#%d", name, line),
+				Author:  r.Author,
+				Work:    r.Work,
+				Locator: r.Locator,
+				Text:    strings.TrimSpace(r.Text),
+			}},
+		})
+	}
+	return works, scanner.Err()
+}
+
+// readText reads the passages of a plain-text corpus file
+func readText(path, name string) (work, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return work{}, err
+	}
+
+	header, body, _ := strings.Cut(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n\n")
+
+	var w work
+	var title string
+	for _, line := range strings.Split(header, "\n") {
+		key, value, _ := strings.Cut(line, ":")
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "author":
+			w.author = value
+		case "aliases":
+			for _, alias := range strings.Split(value, ",") {
+				if alias = strings.TrimSpace(alias); alias != "" {
+					w.aliases = append(w.aliases, alias)
+				}
+			}
+		case "work":
+			title = value
+		}
+	}
+	if w.author == "" || title == "" {
+		return work{}, fmt.Errorf("%s: the header must give the Author and Work", name)
+	}
+
+	locator := ""
+	for n, paragraph := range strings.Split(body, "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if heading, ok := strings.CutPrefix(paragraph, "# "); ok {
+			locator = strings.TrimSpace(heading)
+			continue
+		}
+		if paragraph == "" {
+			continue
+		}
+
+		w.passages = append(w.passages, Passage{
+			ID:      fmt.Sprintf("%s#%d", name, n+1),
+			Author:  w.author,
+			Work:    title,
+			Locator: locator,
+			Text:    strings.Join(strings.Fields(paragraph), " "),
+		})
+	}
+	return w, nil
+}
@@ -1,7 +1,6 @@
 package firebase
 
 import (
-	"context"
 	"time"
 )
 
@@ -15,26 +14,115 @@ type Topic struct {
 
 // Panelist represents a debate participant
 type Panelist struct {
-	ID        string `firestore:"id" json:"id"`
-	Name      string `firestore:"name" json:"name"`
-	Tagline   string `firestore:"tagline" json:"tagline"`
-	Biography string `firestore:"biography" json:"biography"`
-	AvatarURL string `firestore:"avatarUrl" json:"avatarUrl"`
-	Position  string `firestore:"position,omitempty" json:"position,omitempty"`
+	ID          string       `firestore:"id" json:"id"`
+	Name        string       `firestore:"name" json:"name"`
+	Tagline     string       `firestore:"tagline" json:"tagline"`
+	Biography   string       `firestore:"biography" json:"biography"`
+	AvatarURL   string       `firestore:"avatarUrl" json:"avatarUrl"`
+	Position    string       `firestore:"position,omitempty" json:"position,omitempty"`
+	Human       bool         `firestore:"human,omitempty" json:"human,omitempty"`             // Seat taken by the user
+	Born        int          `firestore:"born,omitempty" json:"born,omitempty"`               // Year of birth, negative before Christ
+	Died        int          `firestore:"died,omitempty" json:"died,omitempty"`               // Year of death, unset for the living
+	Attribution *Attribution `firestore:"attribution,omitempty" json:"attribution,omitempty"` // Credits of the portrait
+}
+
+// Attribution credits a Wikimedia image, as its license requires when
+// published
+type Attribution struct {
+	License    string `firestore:"license" json:"license"`                           // Short license name, e.g. "CC BY-SA 4.0"
+	LicenseURL string `firestore:"licenseUrl,omitempty" json:"licenseUrl,omitempty"` // Empty for public domain images
+	Artist     string `firestore:"artist,omitempty" json:"artist,omitempty"`
+	SourceURL  string `firestore:"sourceUrl" json:"sourceUrl"` // File description page
 }
 
 // Message represents a single debate contribution
 type Message struct {
-	ID           string    `firestore:"id" json:"id"`
-	PanelistID   string    `firestore:"panelistId" json:"panelistId"`
-	PanelistName string    `firestore:"panelistName" json:"panelistName"`
-	AvatarURL    string    `firestore:"avatarUrl" json:"avatarUrl"`
-	Text         string    `firestore:"text" json:"text"`
-	Timestamp    time.Time `firestore:"timestamp" json:"timestamp"`
-	Sequence     int       `firestore:"sequence" json:"sequence"`
-	IsComplete   bool      `firestore:"isComplete" json:"isComplete"`
+	ID           string     `firestore:"id" json:"id"`
+	PanelistID   string     `firestore:"panelistId" json:"panelistId"`
+	PanelistName string     `firestore:"panelistName" json:"panelistName"`
+	AvatarURL    string     `firestore:"avatarUrl" json:"avatarUrl"`
+	Text         string     `firestore:"text" json:"text"`
+	Addresses    []string   `firestore:"addresses,omitempty" json:"addresses,omitempty"`
+	Citations    []Citation `firestore:"citations,omitempty" json:"citations,omitempty"`     // Primary sources the message draws on
+	SpeakerType  string     `firestore:"speakerType,omitempty" json:"speakerType,omitempty"` // "panelist", "human", "moderator" or "audience"
+	Phase        string     `firestore:"phase,omitempty" json:"phase,omitempty"`             // ID of the format phase the message belongs to
+	Timestamp    time.Time  `firestore:"timestamp" json:"timestamp"`
+	Sequence     int        `firestore:"sequence" json:"sequence"`
+	IsComplete   bool       `firestore:"isComplete" json:"isComplete"`
+}
+
+// Citation is a reference to a primary source supporting a message, so that
+// readers can check what the figure actually wrote
+type Citation struct {
+	Work    string `firestore:"work" json:"work"`                           // Title of the work, e.g. "Summa Theologica"
+	Locator string `firestore:"locator,omitempty" json:"locator,omitempty"` // Book, chapter or section, e.g. "I-II, q. 96, a. 4"
+	Quote   string `firestore:"quote,omitempty" json:"quote,omitempty"`     // Short quotation from the passage
+}
+
+// Passage is an excerpt of a panelist's work retrieved from the local corpus
+// and given to the model as source material
+type Passage struct {
+	ID         string `firestore:"id" json:"id"` // Corpus file and position, e.g. "confessions.txt#12"
+	PanelistID string `firestore:"panelistId" json:"panelistId"`
+	Work       string `firestore:"work" json:"work"`
+	Locator    string `firestore:"locator,omitempty" json:"locator,omitempty"`
+	Text       string `firestore:"text" json:"text"`
+}
+
+// Speaker types of a message
+const (
+	SpeakerPanelist  = "panelist"  // A debate participant
+	SpeakerHuman     = "human"     // The user, taking a seat on the panel
+	SpeakerModerator = "moderator" // The generated moderator
+	SpeakerAudience  = "audience"  // A question submitted by the audience
+)
+
+// Style holds the length, register and tone options of a debate
+type Style struct {
+	TurnLength string `firestore:"turnLength,omitempty" json:"turnLength,omitempty"`
+	Register   string `firestore:"register,omitempty" json:"register,omitempty"`
+	Tone       string `firestore:"tone,omitempty" json:"tone,omitempty"`
+}
+
+// Judgement is the verdict of a judging pass over a finished debate
+type Judgement struct {
+	Verdicts []Verdict `firestore:"verdicts" json:"verdicts"` // One per panelist
+	Summary  string    `firestore:"summary" json:"summary"`   // Overall assessment of the debate
+	JudgedAt time.Time `firestore:"judgedAt" json:"judgedAt"`
+}
+
+// Verdict scores a panelist's contribution to a debate, from 1 to 10
+type Verdict struct {
+	PanelistID       string `firestore:"panelistId" json:"panelistId"`
+	ArgumentStrength int    `firestore:"argumentStrength" json:"argumentStrength"`
+	Evidence         int    `firestore:"evidence" json:"evidence"`             // Use of evidence and sources
+	Responsiveness   int    `firestore:"responsiveness" json:"responsiveness"` // Engagement with the other panelists
+	Fidelity         int    `firestore:"fidelity" json:"fidelity"`             // Fidelity to their historical position
+	Comment          string `firestore:"comment" json:"comment"`
+}
+
+// Review holds the findings of a fidelity check over a debate: messages that
+// are likely anachronistic or out of character for their panelist
+type Review struct {
+	Findings  []Finding `firestore:"findings" json:"findings"`
+	CheckedAt time.Time `firestore:"checkedAt" json:"checkedAt"`
 }
 
+// Finding flags a claim of a message for review
+type Finding struct {
+	MessageID   string `firestore:"messageId" json:"messageId"`
+	PanelistID  string `firestore:"panelistId" json:"panelistId"`
+	Kind        string `firestore:"kind" json:"kind"`               // "anachronism" or "out_of_character"
+	Excerpt     string `firestore:"excerpt" json:"excerpt"`         // The flagged words of the message
+	Explanation string `firestore:"explanation" json:"explanation"` // Why the claim is suspect
+}
+
+// Kinds of findings
+const (
+	FindingAnachronism    = "anachronism"      // Refers to events or ideas after the panelist's death
+	FindingOutOfCharacter = "out_of_character" // Contradicts the panelist's documented positions
+)
+
 // Metadata contains debate metadata
 type Metadata struct {
 	CreatedBy   string `firestore:"createdBy" json:"createdBy"`
@@ -43,35 +131,43 @@ type Metadata struct {
 	GeneratedBy string `firestore:"generatedBy" json:"generatedBy"`
 }
 
-// DebateDocument represents a complete debate stored in Firestore
+// Debate statuses
+const (
+	StatusInProgress = "in_progress" // Generation is running
+	StatusComplete   = "complete"    // Generation finished successfully
+	StatusFailed     = "failed"      // Generation stopped on an error
+	StatusAborted    = "aborted"     // Generation was cancelled or timed out
+
+	StatusAwaitingHuman = "awaiting_human" // Generation paused for the human panelist's turn
+)
+
+// DebateDocument represents a debate stored in Firestore
 type DebateDocument struct {
 	ID          string     `firestore:"id" json:"id"`
 	Topic       Topic      `firestore:"topic" json:"topic"`
 	Panelists   []Panelist `firestore:"panelists" json:"panelists"`
 	Messages    []Message  `firestore:"messages" json:"messages"`
 	Status      string     `firestore:"status" json:"status"`
+	Format      string     `firestore:"format,omitempty" json:"format,omitempty"` // Debate format ID
+	Style       Style      `firestore:"style,omitempty" json:"style,omitempty"`
+	Language    string     `firestore:"language,omitempty" json:"language,omitempty"`   // ISO 639-1 code, English when empty
+	Citations   bool       `firestore:"citations,omitempty" json:"citations,omitempty"` // Panelists cite primary sources
 	StartedAt   time.Time  `firestore:"startedAt" json:"startedAt"`
 	CompletedAt time.Time  `firestore:"completedAt" json:"completedAt"`
 	Metadata    Metadata   `firestore:"metadata" json:"metadata"`
-}
-
-// SaveDebate saves a debate document to Firestore
-func SaveDebate(ctx context.Context, uuid string, debate *DebateDocument) error {
-	_, err := GetClient().Collection("debates").Doc(uuid).Set(ctx, debate)
-	return err
-}
+	Error       string     `firestore:"error,omitempty" json:"error,omitempty"`         // Why generation failed or was aborted
+	Judgement   *Judgement `firestore:"judgement,omitempty" json:"judgement,omitempty"` // Set by the optional judging pass
+	Passages    []Passage  `firestore:"passages,omitempty" json:"passages,omitempty"`   // Corpus passages given to the model
+	Review      *Review    `firestore:"review,omitempty" json:"review,omitempty"`       // Set by the fidelity check
 
-// GetDebate retrieves a debate document from Firestore by UUID
-func GetDebate(ctx context.Context, uuid string) (*DebateDocument, error) {
-	doc, err := GetClient().Collection("debates").Doc(uuid).Get(ctx)
-	if err != nil {
-		return nil, err
-	}
+	// How the generated debate departs from the expected format, e.g. a
+	// missing conclusion or a panelist who never speaks
+	FormatIssues []string `firestore:"formatIssues,omitempty" json:"formatIssues,omitempty"`
 
-	var debate DebateDocument
-	if err := doc.DataTo(&debate); err != nil {
-		return nil, err
-	}
+	// Set on debates forked from another debate
+	ForkedFrom       string `firestore:"forkedFrom,omitempty" json:"forkedFrom,omitempty"`             // ID of the parent debate
+	ForkedAtSequence int    `firestore:"forkedAtSequence,omitempty" json:"forkedAtSequence,omitempty"` // First sequence not copied from the parent
 
-	return &debate, nil
+	// Set on translated copies of another debate
+	TranslatedFrom string `firestore:"translatedFrom,omitempty" json:"translatedFrom,omitempty"` // ID of the original debate
 }
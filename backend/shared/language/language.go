@@ -0,0 +1,50 @@
+// Package language lists the languages debates can be held in
+package language
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Default is the language of debates that do not set one
+const Default = "en"
+
+// names maps the supported ISO 639-1 codes to the English name of the
+// language, as used in prompts
+var names = map[string]string{
+	"de": "German",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"it": "Italian",
+	"nl": "Dutch",
+	"pt": "Portuguese",
+}
+
+// Name returns the English name of the language with the given code, falling
+// back to English for an empty or unsupported code
+func Name(code string) string {
+	if name, ok := names[code]; ok {
+		return name
+	}
+	return names[Default]
+}
+
+// Validate checks that code is empty or a supported language
+func Validate(code string) error {
+	if _, ok := names[code]; code != "" && !ok {
+		return fmt.Errorf("language must be one of %s", strings.Join(Codes(), ", "))
+	}
+	return nil
+}
+
+// Codes returns the supported language codes in alphabetical order
+func Codes() []string {
+	codes := make([]string, 0, len(names))
+	for code := range names {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"context"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/packages/ssestream"
+)
+
+// Anthropic streams completions from the Anthropic Messages API
+type Anthropic struct {
+	client anthropic.Client
+	model  anthropic.Model
+}
+
+// NewAnthropic creates an Anthropic model client
+func NewAnthropic(apiKey, model string) *Anthropic {
+	return &Anthropic{
+		client: anthropic.NewClient(
+			option.WithAPIKey(apiKey),
+		),
+		model: anthropic.Model(model),
+	}
+}
+
+// Stream starts a streaming Messages API request
+func (a *Anthropic) Stream(ctx context.Context, req Request) Stream {
+	params := anthropic.MessageNewParams{
+		Model:     a.model,
+		MaxTokens: int64(req.MaxTokens),
+		Messages:  make([]anthropic.MessageParam, 0, len(req.Messages)),
+	}
+
+	if req.System != "" {
+		params.System = []anthropic.TextBlockParam{{Text: req.System}}
+	}
+
+	for _, msg := range req.Messages {
+		block := anthropic.NewTextBlock(msg.Text)
+		if msg.Role == RoleAssistant {
+			params.Messages = append(params.Messages, anthropic.NewAssistantMessage(block))
+		} else {
+			params.Messages = append(params.Messages, anthropic.NewUserMessage(block))
+		}
+	}
+
+	return &anthropicStream{stream: a.client.Messages.NewStreaming(ctx, params)}
+}
+
+// anthropicStream adapts the SDK event stream to text deltas
+type anthropicStream struct {
+	stream     *ssestream.Stream[anthropic.MessageStreamEventUnion]
+	current    string
+	stopReason StopReason
+}
+
+func (s *anthropicStream) Next() bool {
+	for s.stream.Next() {
+		event := s.stream.Current()
+		if event.Delta.StopReason != "" {
+			s.stopReason = StopReason(event.Delta.StopReason)
+		}
+		if event.Delta.Text == "" {
+			continue
+		}
+		s.current = event.Delta.Text
+		return true
+	}
+	return false
+}
+
+func (s *anthropicStream) Current() string {
+	return s.current
+}
+
+func (s *anthropicStream) Err() error {
+	return s.stream.Err()
+}
+
+func (s *anthropicStream) StopReason() StopReason {
+	return s.stopReason
+}
+
+func (s *anthropicStream) Close() error {
+	return s.stream.Close()
+}
@@ -0,0 +1,133 @@
+package llm
+
+import (
+	"context"
+	"log"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// StreamWithContinuations streams req and, when the response is cut off at
+// MaxTokens, sends up to maxContinuations follow-up requests prefilled with
+// the text generated so far. The deltas of all responses are yielded as a
+// single stream, so callers parse them as one response.
+//
+// Models that answer a trailing assistant message instead of extending it,
+// such as OpenAI-compatible servers, are asked in a final user message to
+// continue exactly where the text stopped. They may still repeat or reword
+// the end of the text, so continuations are less seamless than prefills.
+func StreamWithContinuations(ctx context.Context, model LanguageModel, req Request, maxContinuations int) Stream {
+	return &continuingStream{
+		ctx:       ctx,
+		model:     model,
+		req:       req,
+		remaining: maxContinuations,
+		stream:    model.Stream(ctx, req),
+	}
+}
+
+// continueInstruction asks models that do not extend a prefill to resume a
+// response cut off at the token limit
+const continueInstruction = "Your response was cut off. Continue exactly where you stopped, " +
+	"even mid-word, without repeating any of the text above or adding any comment."
+
+// prefiller is implemented by models that may not extend a trailing assistant
+// message. Models that do not implement it are assumed to.
+type prefiller interface {
+	prefills() bool
+}
+
+// continuingStream chains the responses of a request and its continuations
+type continuingStream struct {
+	ctx       context.Context
+	model     LanguageModel
+	req       Request
+	remaining int
+	stream    Stream
+	current   string
+	text      strings.Builder // Text generated so far
+	seam      string          // Whitespace trimmed from the last prefill
+}
+
+func (s *continuingStream) Next() bool {
+	for {
+		if s.stream.Next() {
+			delta := s.trimSeam(s.stream.Current())
+			if delta == "" {
+				continue
+			}
+			s.current = delta
+			s.text.WriteString(delta)
+			return true
+		}
+
+		if s.stream.Err() != nil || s.stream.StopReason() != StopMaxTokens {
+			return false
+		}
+		if s.remaining == 0 {
+			log.Printf("Response still cut off at %d tokens after all continuations", s.req.MaxTokens)
+			return false
+		}
+
+		s.remaining--
+		s.stream.Close()
+		s.stream = s.model.Stream(s.ctx, s.continuation())
+	}
+}
+
+// continuation builds the request resuming the response after the text so far
+func (s *continuingStream) continuation() Request {
+	messages := append([]Message(nil), s.req.Messages...)
+	prefill := s.text.String()
+
+	// Extend the original prefill, if any
+	if n := len(messages); n > 0 && messages[n-1].Role == RoleAssistant {
+		prefill = messages[n-1].Text + prefill
+		messages = messages[:n-1]
+	}
+
+	// The final assistant message must not end with whitespace. That
+	// whitespace was already yielded, so it is dropped if the model repeats it.
+	trimmed := strings.TrimRightFunc(prefill, unicode.IsSpace)
+	s.seam = prefill[len(trimmed):]
+	messages = append(messages, Message{Role: RoleAssistant, Text: trimmed})
+	if p, ok := s.model.(prefiller); ok && !p.prefills() {
+		messages = append(messages, Message{Role: RoleUser, Text: continueInstruction})
+	}
+
+	req := s.req
+	req.Messages = messages
+	return req
+}
+
+// trimSeam drops from delta the whitespace trimmed from the last prefill
+// that the continuation starts by repeating
+func (s *continuingStream) trimSeam(delta string) string {
+	for s.seam != "" && delta != "" {
+		r, n := utf8.DecodeRuneInString(delta)
+		seam, size := utf8.DecodeRuneInString(s.seam)
+		if r != seam {
+			s.seam = ""
+			break
+		}
+		delta, s.seam = delta[n:], s.seam[size:]
+	}
+	return delta
+}
+
+func (s *continuingStream) Current() string {
+	return s.current
+}
+
+func (s *continuingStream) Err() error {
+	return s.stream.Err()
+}
+
+func (s *continuingStream) StopReason() StopReason {
+	return s.stream.StopReason()
+}
+
+func (s *continuingStream) Close() error {
+	return s.stream.Close()
+}
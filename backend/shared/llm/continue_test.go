@@ -0,0 +1,133 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStreamWithContinuations(t *testing.T) {
+	fake := NewFakeScripts(
+		Script{Deltas: []string{"[moderator]: Welcome.\n", "[augustine]: An unjust "}, StopReason: StopMaxTokens},
+		Script{Deltas: []string{"law is no law.\n[moderator]: Thank"}, StopReason: StopMaxTokens},
+		Script{Deltas: []string{" you."}},
+	)
+
+	req := Request{
+		Messages: []Message{
+			{Role: RoleUser, Text: "Begin"},
+			{Role: RoleAssistant, Text: "[moderator]: Hello.\n"},
+		},
+		MaxTokens: 100,
+	}
+	stream := StreamWithContinuations(context.Background(), fake, req, 3)
+	defer stream.Close()
+
+	deltas := drain(stream)
+	if got := strings.Join(deltas, ""); got != "[moderator]: Welcome.\n[augustine]: An unjust law is no law.\n[moderator]: Thank you." {
+		t.Errorf("stitched text = %q", got)
+	}
+	if stream.Err() != nil || stream.StopReason() != StopEndTurn {
+		t.Errorf("stream ended with %v (%q), want end of turn", stream.Err(), stream.StopReason())
+	}
+
+	// Continuations extend the original prefill with the text so far, without trailing space
+	requests := fake.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("model received %d requests, want 3", len(requests))
+	}
+	last := requests[2].Messages
+	if len(last) != 2 || last[1].Role != RoleAssistant ||
+		last[1].Text != "[moderator]: Hello.\n[moderator]: Welcome.\n[augustine]: An unjust law is no law.\n[moderator]: Thank" {
+		t.Errorf("continuation messages = %+v", last)
+	}
+	if requests[1].MaxTokens != 100 {
+		t.Errorf("continuation MaxTokens = %d, want 100", requests[1].MaxTokens)
+	}
+}
+
+func TestStreamWithContinuationsSeam(t *testing.T) {
+	tests := []struct {
+		name   string
+		deltas []string // Deltas of the continuation
+		want   string
+	}{
+		{name: "repeated space", deltas: []string{" cat."}, want: "[augustine]: I saw the cat."},
+		{name: "space alone", deltas: []string{" ", "cat."}, want: "[augustine]: I saw the cat."},
+		{name: "no space", deltas: []string{"cat."}, want: "[augustine]: I saw the cat."},
+		{name: "other whitespace", deltas: []string{"\ncat."}, want: "[augustine]: I saw the \ncat."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewFakeScripts(
+				Script{Deltas: []string{"[augustine]: I saw the "}, StopReason: StopMaxTokens},
+				Script{Deltas: tt.deltas},
+			)
+
+			stream := StreamWithContinuations(context.Background(), fake, UserPrompt("Begin", 10), 1)
+			if got := strings.Join(drain(stream), ""); got != tt.want {
+				t.Errorf("stitched text = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamWithContinuationsLimits(t *testing.T) {
+	t.Run("hard cap", func(t *testing.T) {
+		fake := NewFakeScripts(Script{Deltas: []string{"more "}, StopReason: StopMaxTokens})
+
+		stream := StreamWithContinuations(context.Background(), fake, UserPrompt("Begin", 10), 2)
+		if got := strings.Join(drain(stream), ""); got != "more more more " {
+			t.Errorf("text = %q, want the response and 2 continuations", got)
+		}
+		if stream.StopReason() != StopMaxTokens {
+			t.Errorf("StopReason() = %q, want %q", stream.StopReason(), StopMaxTokens)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		streamErr := errors.New("overloaded")
+		fake := NewFakeScripts(Script{Deltas: []string{"partial"}, Err: streamErr, StopReason: StopMaxTokens})
+
+		stream := StreamWithContinuations(context.Background(), fake, UserPrompt("Begin", 10), 2)
+		drain(stream)
+		if !errors.Is(stream.Err(), streamErr) || len(fake.Requests()) != 1 {
+			t.Errorf("Err() = %v after %d requests, want the error and no continuation", stream.Err(), len(fake.Requests()))
+		}
+	})
+}
+
+// chatModel is a fake model answering trailing assistant messages, as
+// OpenAI-compatible servers do
+type chatModel struct {
+	*Fake
+}
+
+func (chatModel) prefills() bool {
+	return false
+}
+
+func TestStreamWithContinuationsWithoutPrefill(t *testing.T) {
+	fake := NewFakeScripts(
+		Script{Deltas: []string{"[moderator]: Wel"}, StopReason: StopMaxTokens},
+		Script{Deltas: []string{"come."}},
+	)
+
+	stream := StreamWithContinuations(context.Background(), chatModel{fake}, UserPrompt("Begin", 10), 1)
+	if got := strings.Join(drain(stream), ""); got != "[moderator]: Welcome." {
+		t.Errorf("stitched text = %q", got)
+	}
+
+	// The text so far is followed by a request to continue it
+	requests := fake.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("model received %d requests, want 2", len(requests))
+	}
+	last := requests[1].Messages
+	if len(last) != 3 || last[1].Role != RoleAssistant || last[1].Text != "[moderator]: Wel" ||
+		last[2].Role != RoleUser || last[2].Text != continueInstruction {
+		t.Errorf("continuation messages = %+v", last)
+	}
+}
@@ -0,0 +1,107 @@
+package llm
+
+import (
+	"context"
+	"sync"
+)
+
+// Script is a canned model response: its deltas are replayed in order,
+// then Err (if any) is reported by the stream. StopReason defaults to
+// StopEndTurn.
+type Script struct {
+	Deltas     []string
+	Err        error
+	StopReason StopReason
+}
+
+// Fake is a deterministic LanguageModel replaying scripted responses.
+// Each call to Stream consumes the next script; the last one is repeated
+// once the list is exhausted.
+type Fake struct {
+	mu       sync.Mutex
+	scripts  []Script
+	calls    int
+	requests []Request
+}
+
+// NewFake creates a fake model answering every request with deltas
+func NewFake(deltas ...string) *Fake {
+	return NewFakeScripts(Script{Deltas: deltas})
+}
+
+// NewFakeScripts creates a fake model answering successive requests with scripts
+func NewFakeScripts(scripts ...Script) *Fake {
+	return &Fake{scripts: scripts}
+}
+
+// Stream replays the next script
+func (f *Fake) Stream(ctx context.Context, req Request) Stream {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, req)
+
+	var script Script
+	if len(f.scripts) > 0 {
+		script = f.scripts[min(f.calls, len(f.scripts)-1)]
+	}
+	f.calls++
+
+	return &fakeStream{ctx: ctx, script: script, pos: -1}
+}
+
+// Requests returns the requests received so far
+func (f *Fake) Requests() []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Request(nil), f.requests...)
+}
+
+// fakeStream replays a single script
+type fakeStream struct {
+	ctx    context.Context
+	script Script
+	pos    int
+	err    error
+}
+
+func (s *fakeStream) Next() bool {
+	if s.err != nil {
+		return false
+	}
+	for s.pos+1 < len(s.script.Deltas) {
+		if err := s.ctx.Err(); err != nil {
+			s.err = err
+			return false
+		}
+		s.pos++
+		if s.script.Deltas[s.pos] != "" {
+			return true
+		}
+	}
+	s.err = s.script.Err
+	return false
+}
+
+func (s *fakeStream) Current() string {
+	if s.pos < 0 || s.pos >= len(s.script.Deltas) {
+		return ""
+	}
+	return s.script.Deltas[s.pos]
+}
+
+func (s *fakeStream) Err() error {
+	return s.err
+}
+
+func (s *fakeStream) StopReason() StopReason {
+	if s.script.StopReason == "" {
+		return StopEndTurn
+	}
+	return s.script.StopReason
+}
+
+func (s *fakeStream) Close() error {
+	return nil
+}
@@ -0,0 +1,104 @@
+// Package llm abstracts the language models used to validate topics and generate debates
+package llm
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// Role identifies the author of a conversation message
+type Role string
+
+// Conversation roles
+const (
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+)
+
+// Provider names accepted in the LLM_PROVIDER environment variable
+const (
+	ProviderAnthropic = "anthropic"
+	ProviderOpenAI    = "openai"
+)
+
+// Message is a single turn of the conversation sent to the model
+type Message struct {
+	Role Role
+	Text string
+}
+
+// Request describes a streaming completion request
+type Request struct {
+	System    string    // Optional system prompt
+	Messages  []Message // Conversation, starting with a user message
+	MaxTokens int       // Maximum number of tokens to generate
+}
+
+// StopReason tells why a model response ended
+type StopReason string
+
+// Stop reasons
+const (
+	StopEndTurn   StopReason = "end_turn"   // The model finished its response
+	StopMaxTokens StopReason = "max_tokens" // The response was cut off at MaxTokens
+)
+
+// Stream yields the text deltas of a model response.
+// Next advances to the next non-empty delta and returns false at the end
+// of the response or on error; Err reports the error, if any, and
+// StopReason why the response ended (empty if unknown).
+type Stream interface {
+	Next() bool
+	Current() string
+	Err() error
+	StopReason() StopReason
+	Close() error
+}
+
+// LanguageModel streams text completions
+type LanguageModel interface {
+	Stream(ctx context.Context, req Request) Stream
+}
+
+// UserPrompt builds a request made of a single user message
+func UserPrompt(prompt string, maxTokens int) Request {
+	return Request{
+		Messages:  []Message{{Role: RoleUser, Text: prompt}},
+		MaxTokens: maxTokens,
+	}
+}
+
+// NewFromEnv creates the language model selected by the LLM_PROVIDER environment
+// variable (anthropic by default). LLM_MODEL overrides defaultModel.
+//
+// The openai provider talks to any OpenAI-compatible endpoint configured with
+// OPENAI_BASE_URL (and OPENAI_API_KEY if required); LLM_MODEL is mandatory
+// since defaultModel names an Anthropic model.
+func NewFromEnv(defaultModel string) (LanguageModel, error) {
+	model := os.Getenv("LLM_MODEL")
+	if model == "" {
+		model = defaultModel
+	}
+
+	switch provider := os.Getenv("LLM_PROVIDER"); provider {
+	case "", ProviderAnthropic:
+		apiKey := os.Getenv("ANTHROPIC_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("ANTHROPIC_API_KEY environment variable not set")
+		}
+		return NewAnthropic(apiKey, model), nil
+	case ProviderOpenAI:
+		model = os.Getenv("LLM_MODEL")
+		if model == "" {
+			return nil, fmt.Errorf("LLM_MODEL environment variable is required for the openai provider")
+		}
+		baseURL := os.Getenv("OPENAI_BASE_URL")
+		if baseURL == "" {
+			baseURL = DefaultOpenAIBaseURL
+		}
+		return NewOpenAI(baseURL, os.Getenv("OPENAI_API_KEY"), model), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q", provider)
+	}
+}
@@ -0,0 +1,93 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// drain collects all deltas of a stream
+func drain(s Stream) []string {
+	var deltas []string
+	for s.Next() {
+		deltas = append(deltas, s.Current())
+	}
+	return deltas
+}
+
+func TestFakeReplaysDeltas(t *testing.T) {
+	fake := NewFake("[modera", "tor]: Welcome", "", " everyone")
+
+	deltas := drain(fake.Stream(context.Background(), UserPrompt("hello", 100)))
+	if got := strings.Join(deltas, "|"); got != "[modera|tor]: Welcome| everyone" {
+		t.Errorf("deltas = %q, want empty deltas skipped", got)
+	}
+
+	requests := fake.Requests()
+	if len(requests) != 1 || requests[0].Messages[0].Text != "hello" || requests[0].MaxTokens != 100 {
+		t.Errorf("Requests() = %+v, want the single request sent", requests)
+	}
+}
+
+func TestFakeScriptsInOrder(t *testing.T) {
+	streamErr := errors.New("overloaded")
+	fake := NewFakeScripts(
+		Script{Deltas: []string{"first"}},
+		Script{Deltas: []string{"partial"}, Err: streamErr},
+	)
+
+	ctx := context.Background()
+	if got := drain(fake.Stream(ctx, Request{})); len(got) != 1 || got[0] != "first" {
+		t.Errorf("first stream = %v, want [first]", got)
+	}
+
+	s := fake.Stream(ctx, Request{})
+	if got := drain(s); len(got) != 1 || got[0] != "partial" {
+		t.Errorf("second stream = %v, want [partial]", got)
+	}
+	if !errors.Is(s.Err(), streamErr) {
+		t.Errorf("second stream Err() = %v, want %v", s.Err(), streamErr)
+	}
+
+	// The last script is repeated once exhausted
+	if got := drain(fake.Stream(ctx, Request{})); len(got) != 1 || got[0] != "partial" {
+		t.Errorf("third stream = %v, want last script repeated", got)
+	}
+}
+
+func TestFakeStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s := NewFake("never").Stream(ctx, Request{})
+	if s.Next() {
+		t.Error("Next() = true on cancelled context")
+	}
+	if !errors.Is(s.Err(), context.Canceled) {
+		t.Errorf("Err() = %v, want context.Canceled", s.Err())
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	t.Setenv("LLM_PROVIDER", "")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	if _, err := NewFromEnv("claude-sonnet-4-5"); err == nil {
+		t.Error("NewFromEnv() expected error for missing API key")
+	}
+
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("LLM_MODEL", "claude-opus-4-1")
+	model, err := NewFromEnv("claude-sonnet-4-5")
+	if err != nil {
+		t.Fatalf("NewFromEnv() error = %v", err)
+	}
+	if a, ok := model.(*Anthropic); !ok || a.model != "claude-opus-4-1" {
+		t.Errorf("NewFromEnv() = %#v, want Anthropic with LLM_MODEL override", model)
+	}
+
+	t.Setenv("LLM_PROVIDER", "unknown")
+	if _, err := NewFromEnv("claude-sonnet-4-5"); err == nil {
+		t.Error("NewFromEnv() expected error for unknown provider")
+	}
+}
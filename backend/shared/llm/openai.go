@@ -0,0 +1,219 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultOpenAIBaseURL is used when OPENAI_BASE_URL is not set (llama.cpp server default)
+const DefaultOpenAIBaseURL = "http://localhost:8080/v1"
+
+// OpenAI streams completions from any OpenAI-compatible chat completions
+// endpoint (OpenAI, llama.cpp server, vLLM, Ollama, ...)
+type OpenAI struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+	model   string
+}
+
+// NewOpenAI creates a client for the chat completions API at baseURL.
+// apiKey may be empty for local servers that do not require authentication.
+func NewOpenAI(baseURL, apiKey, model string) *OpenAI {
+	return &OpenAI{
+		client:  &http.Client{},
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+	}
+}
+
+// openAIMessage is a chat message in the OpenAI wire format
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// openAIRequest is the body of a chat completions request
+type openAIRequest struct {
+	Model     string          `json:"model"`
+	Messages  []openAIMessage `json:"messages"`
+	MaxTokens int             `json:"max_tokens,omitempty"`
+	Stream    bool            `json:"stream"`
+}
+
+// openAIChunk is a single streamed chat completion chunk
+type openAIChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Stream starts a streaming chat completions request.
+// The HTTP request is sent on the first call to Next.
+func (o *OpenAI) Stream(ctx context.Context, req Request) Stream {
+	body := openAIRequest{
+		Model:     o.model,
+		Messages:  make([]openAIMessage, 0, len(req.Messages)+1),
+		MaxTokens: req.MaxTokens,
+		Stream:    true,
+	}
+
+	if req.System != "" {
+		body.Messages = append(body.Messages, openAIMessage{Role: "system", Content: req.System})
+	}
+	for _, msg := range req.Messages {
+		body.Messages = append(body.Messages, openAIMessage{Role: string(msg.Role), Content: msg.Text})
+	}
+
+	return &openAIStream{ctx: ctx, client: o, body: body}
+}
+
+// prefills reports that chat completions answer a trailing assistant message
+// rather than extending it
+func (o *OpenAI) prefills() bool {
+	return false
+}
+
+// openAIStream parses the server-sent "data:" lines of a chat completions stream
+type openAIStream struct {
+	ctx     context.Context
+	client  *OpenAI
+	body    openAIRequest
+	resp    *http.Response
+	scanner *bufio.Scanner
+	current string
+	stop    StopReason
+	done    bool
+	err     error
+}
+
+func (s *openAIStream) Next() bool {
+	if s.done || s.err != nil {
+		return false
+	}
+
+	if s.scanner == nil {
+		if s.err = s.start(); s.err != nil {
+			return false
+		}
+	}
+
+	for s.scanner.Scan() {
+		line := strings.TrimSpace(s.scanner.Text())
+
+		// Skip blank lines, SSE comments and non-data fields
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			s.done = true
+			return false
+		}
+
+		var chunk openAIChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			s.err = fmt.Errorf("invalid stream chunk: %w", err)
+			return false
+		}
+		if chunk.Error != nil {
+			s.err = fmt.Errorf("model error: %s", chunk.Error.Message)
+			return false
+		}
+
+		for _, choice := range chunk.Choices {
+			if choice.FinishReason != nil {
+				s.stop = openAIStopReason(*choice.FinishReason)
+			}
+			if choice.Delta.Content != "" {
+				s.current = choice.Delta.Content
+				return true
+			}
+		}
+	}
+
+	if err := s.scanner.Err(); err != nil {
+		s.err = err
+	}
+	s.done = true
+	return false
+}
+
+// start sends the HTTP request and prepares the line scanner
+func (s *openAIStream) start() error {
+	payload, err := json.Marshal(s.body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(s.ctx, http.MethodPost, s.client.baseURL+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	if s.client.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+s.client.apiKey)
+	}
+
+	resp, err := s.client.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+
+	s.resp = resp
+	s.scanner = bufio.NewScanner(resp.Body)
+	s.scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	return nil
+}
+
+func (s *openAIStream) Current() string {
+	return s.current
+}
+
+func (s *openAIStream) Err() error {
+	return s.err
+}
+
+func (s *openAIStream) StopReason() StopReason {
+	return s.stop
+}
+
+// openAIStopReason maps a chat completions finish reason to a StopReason
+func openAIStopReason(reason string) StopReason {
+	switch reason {
+	case "stop":
+		return StopEndTurn
+	case "length":
+		return StopMaxTokens
+	default:
+		return StopReason(reason)
+	}
+}
+
+func (s *openAIStream) Close() error {
+	if s.resp != nil {
+		return s.resp.Body.Close()
+	}
+	return nil
+}
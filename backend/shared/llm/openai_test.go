@@ -0,0 +1,136 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// openAIServer serves a canned chat completions stream and records the request
+func openAIServer(t *testing.T, status int, lines ...string) (*httptest.Server, *openAIRequest) {
+	t.Helper()
+
+	var received openAIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("request path = %s, want /v1/chat/completions", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer local-key" {
+			t.Errorf("Authorization = %q, want bearer token", got)
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(status)
+		for _, line := range lines {
+			fmt.Fprintf(w, "%s\n", line)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &received
+}
+
+func TestOpenAIStream(t *testing.T) {
+	server, received := openAIServer(t, http.StatusOK,
+		`: keep-alive`,
+		`data: {"choices":[{"delta":{"role":"assistant"}}]}`,
+		``,
+		`data: {"choices":[{"delta":{"content":"[modera"}}]}`,
+		``,
+		`data:{"choices":[{"delta":{"content":"tor]: Welcome"}}]}`,
+		``,
+		`data: {"choices":[{"delta":{},"finish_reason":"stop"}]}`,
+		``,
+		`data: [DONE]`,
+	)
+
+	model := NewOpenAI(server.URL+"/v1/", "local-key", "llama-3.1-8b")
+	stream := model.Stream(context.Background(), Request{
+		System:    "You moderate debates",
+		Messages:  []Message{{Role: RoleUser, Text: "Begin"}},
+		MaxTokens: 512,
+	})
+	defer stream.Close()
+
+	deltas := drain(stream)
+	if err := stream.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+	if got := strings.Join(deltas, "|"); got != "[modera|tor]: Welcome" {
+		t.Errorf("deltas = %q", got)
+	}
+	if got := stream.StopReason(); got != StopEndTurn {
+		t.Errorf("StopReason() = %q, want %q", got, StopEndTurn)
+	}
+
+	if received.Model != "llama-3.1-8b" || !received.Stream || received.MaxTokens != 512 {
+		t.Errorf("request = %+v, want model, stream and max_tokens set", received)
+	}
+	if len(received.Messages) != 2 || received.Messages[0].Role != "system" || received.Messages[1].Content != "Begin" {
+		t.Errorf("request messages = %+v, want system then user", received.Messages)
+	}
+}
+
+func TestOpenAIStopReason(t *testing.T) {
+	server, _ := openAIServer(t, http.StatusOK,
+		`data: {"choices":[{"delta":{"content":"[moderator]: Welc"},"finish_reason":null}]}`,
+		`data: {"choices":[{"delta":{},"finish_reason":"length"}]}`,
+		`data: [DONE]`,
+	)
+
+	stream := NewOpenAI(server.URL+"/v1", "local-key", "llama-3.1-8b").Stream(context.Background(), Request{})
+	defer stream.Close()
+
+	drain(stream)
+	if got := stream.StopReason(); got != StopMaxTokens {
+		t.Errorf("StopReason() = %q, want %q", got, StopMaxTokens)
+	}
+}
+
+func TestOpenAIStreamErrors(t *testing.T) {
+	t.Run("non-200 status", func(t *testing.T) {
+		server, _ := openAIServer(t, http.StatusNotFound, `{"error":"model not found"}`)
+		stream := NewOpenAI(server.URL+"/v1", "local-key", "missing").Stream(context.Background(), Request{})
+
+		if stream.Next() {
+			t.Error("Next() = true on error status")
+		}
+		if err := stream.Err(); err == nil || !strings.Contains(err.Error(), "model not found") {
+			t.Errorf("Err() = %v, want status error with body", err)
+		}
+	})
+
+	t.Run("error chunk", func(t *testing.T) {
+		server, _ := openAIServer(t, http.StatusOK, `data: {"error":{"message":"context length exceeded"}}`)
+		stream := NewOpenAI(server.URL+"/v1", "local-key", "llama").Stream(context.Background(), Request{})
+
+		drain(stream)
+		if err := stream.Err(); err == nil || !strings.Contains(err.Error(), "context length exceeded") {
+			t.Errorf("Err() = %v, want model error", err)
+		}
+	})
+}
+
+func TestNewFromEnvOpenAI(t *testing.T) {
+	t.Setenv("LLM_PROVIDER", ProviderOpenAI)
+	t.Setenv("LLM_MODEL", "")
+	if _, err := NewFromEnv("claude-sonnet-4-5"); err == nil {
+		t.Error("NewFromEnv() expected error when LLM_MODEL is missing")
+	}
+
+	t.Setenv("LLM_MODEL", "qwen2.5")
+	t.Setenv("OPENAI_BASE_URL", "http://ollama:11434/v1")
+	model, err := NewFromEnv("claude-sonnet-4-5")
+	if err != nil {
+		t.Fatalf("NewFromEnv() error = %v", err)
+	}
+	o, ok := model.(*OpenAI)
+	if !ok || o.model != "qwen2.5" || o.baseURL != "http://ollama:11434/v1" {
+		t.Errorf("NewFromEnv() = %#v, want OpenAI client for ollama", model)
+	}
+}
@@ -0,0 +1,165 @@
+package sse
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Log is an append-only log of events that any number of clients can replay,
+// including while events are still being appended
+type Log struct {
+	mu       sync.Mutex
+	first    int // ID of the first event
+	events   []Event
+	closed   bool
+	closedAt time.Time
+	changed  chan struct{} // closed and replaced whenever the log changes
+}
+
+// NewLog creates an empty event log
+func NewLog() *Log {
+	return newLogAfter(0)
+}
+
+// newLogAfter creates an empty event log whose IDs follow lastID
+func newLogAfter(lastID int) *Log {
+	return &Log{first: lastID + 1, changed: make(chan struct{})}
+}
+
+// Append adds an event with the next ID (starting at 1) and wakes up replaying clients.
+// Events appended after Close are dropped.
+func (l *Log) Append(event string, data []byte) Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := Event{ID: l.first + len(l.events), Event: event, Data: data}
+	if l.closed {
+		return e
+	}
+
+	l.events = append(l.events, e)
+	l.notify()
+	return e
+}
+
+// Close marks the log complete so that replays end after the last event
+func (l *Log) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return
+	}
+	l.closed = true
+	l.closedAt = time.Now()
+	l.notify()
+}
+
+// notify wakes up replaying clients; l.mu must be held
+func (l *Log) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// lastID returns the ID of the last event appended, or the one before the
+// first if none was
+func (l *Log) lastID() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.first + len(l.events) - 1
+}
+
+// Replay writes the events after lastID to w, then follows the log until it
+// is closed or ctx is done. IDs before the log's first event replay it all.
+func (l *Log) Replay(ctx context.Context, w io.Writer, lastID int) error {
+	for {
+		l.mu.Lock()
+		var pending []Event
+		if next := max(lastID-l.first+1, 0); next < len(l.events) {
+			pending = l.events[next:]
+		}
+		closed := l.closed
+		changed := l.changed
+		l.mu.Unlock()
+
+		for _, event := range pending {
+			if err := Write(w, event); err != nil {
+				return err
+			}
+			lastID = event.ID
+		}
+
+		if closed {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// expired reports whether the log was closed more than ttl ago
+func (l *Log) expired(now time.Time, ttl time.Duration) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.closed && now.Sub(l.closedAt) > ttl
+}
+
+// Registry keeps event logs by stream ID so that clients can reconnect.
+// Logs are dropped ttl after they are closed. Logs are held in memory, so
+// clients can only resume on the instance that produced the stream.
+type Registry struct {
+	mu   sync.Mutex
+	logs map[string]*Log
+	ttl  time.Duration
+}
+
+// NewRegistry creates a registry keeping closed logs for ttl
+func NewRegistry(ttl time.Duration) *Registry {
+	return &Registry{
+		logs: make(map[string]*Log),
+		ttl:  ttl,
+	}
+}
+
+// Create registers a new log under id, replacing any previous one. Its IDs
+// follow those of the log replaced, so that clients resuming with an ID of
+// the previous stream get the whole new one.
+func (r *Registry) Create(id string) *Log {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sweep()
+	lastID := 0
+	if previous, ok := r.logs[id]; ok {
+		lastID = previous.lastID()
+	}
+	l := newLogAfter(lastID)
+	r.logs[id] = l
+	return l
+}
+
+// Get returns the log registered under id, if it has not expired
+func (r *Registry) Get(id string) (*Log, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sweep()
+	l, ok := r.logs[id]
+	return l, ok
+}
+
+// sweep drops expired logs; r.mu must be held
+func (r *Registry) sweep() {
+	now := time.Now()
+	for id, l := range r.logs {
+		if l.expired(now, r.ttl) {
+			delete(r.logs, id)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Package sse implements Server-Sent Events framing, and event logs that let
+// clients resume a stream with Last-Event-ID
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Event is a single server-sent event
+type Event struct {
+	ID    int
+	Event string
+	Data  []byte
+}
+
+// SetHeaders sets the response headers of an event stream
+func SetHeaders(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx buffering
+}
+
+// Write writes event in the SSE wire format and flushes it if w supports it
+func Write(w io.Writer, event Event) error {
+	var buf bytes.Buffer
+	if event.ID > 0 {
+		fmt.Fprintf(&buf, "id: %d\n", event.ID)
+	}
+	if event.Event != "" {
+		fmt.Fprintf(&buf, "event: %s\n", event.Event)
+	}
+	// Data containing newlines is split over several data: lines
+	for _, line := range strings.Split(string(event.Data), "\n") {
+		fmt.Fprintf(&buf, "data: %s\n", line)
+	}
+	buf.WriteByte('\n')
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// Writer sends events with increasing IDs over a single connection
+type Writer struct {
+	w      io.Writer
+	lastID int
+}
+
+// NewWriter creates a Writer sending events to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Send writes the next event
+func (w *Writer) Send(event string, data []byte) error {
+	w.lastID++
+	return Write(w.w, Event{ID: w.lastID, Event: event, Data: data})
+}
+
+// LastEventID returns the ID of the last event a reconnecting client received,
+// from the Last-Event-ID header or the lastEventId query parameter (for clients
+// that cannot set headers). It returns 0 when neither is set or valid.
+func LastEventID(r *http.Request) int {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("lastEventId")
+	}
+
+	id, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || id < 0 {
+		return 0
+	}
+	return id
+}
+
+// ReadEvents parses every event of an event stream
+func ReadEvents(r io.Reader) ([]Event, error) {
+	var events []Event
+	var current Event
+	var data []string
+	pending := false
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// A blank line dispatches the event
+		if line == "" {
+			if pending {
+				current.Data = []byte(strings.Join(data, "\n"))
+				events = append(events, current)
+			}
+			current, data, pending = Event{}, nil, false
+			continue
+		}
+
+		// Lines starting with a colon are comments
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		pending = true
+
+		switch field {
+		case "id":
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				return events, fmt.Errorf("invalid event id %q", value)
+			}
+			current.ID = id
+		case "event":
+			current.Event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+
+	return events, scanner.Err()
+}
@@ -0,0 +1,167 @@
+package sse
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteAndReadEvents(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Send("message", []byte(`{"text":"hello"}`))
+	w.Send("note", []byte("two\nlines"))
+
+	want := "id: 1\nevent: message\ndata: {\"text\":\"hello\"}\n\n" +
+		"id: 2\nevent: note\ndata: two\ndata: lines\n\n"
+	if buf.String() != want {
+		t.Errorf("wire format = %q, want %q", buf.String(), want)
+	}
+
+	events, err := ReadEvents(strings.NewReader(": comment\n\n" + buf.String()))
+	if err != nil {
+		t.Fatalf("ReadEvents() error = %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("ReadEvents() returned %d events, want 2", len(events))
+	}
+	if events[1].ID != 2 || events[1].Event != "note" || string(events[1].Data) != "two\nlines" {
+		t.Errorf("second event = %+v", events[1])
+	}
+}
+
+func TestLastEventID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		url    string
+		want   int
+	}{
+		{name: "none", url: "/", want: 0},
+		{name: "header", header: "12", url: "/", want: 12},
+		{name: "query", url: "/?lastEventId=7", want: 7},
+		{name: "header wins", header: "3", url: "/?lastEventId=7", want: 3},
+		{name: "invalid", header: "abc", url: "/", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.url, nil)
+			if tt.header != "" {
+				r.Header.Set("Last-Event-ID", tt.header)
+			}
+			if got := LastEventID(r); got != tt.want {
+				t.Errorf("LastEventID() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogReplay(t *testing.T) {
+	l := NewLog()
+	l.Append("message", []byte("a"))
+	l.Append("message", []byte("b"))
+
+	// Follow the log while events are still appended
+	done := make(chan []Event)
+	go func() {
+		var buf bytes.Buffer
+		l.Replay(context.Background(), &buf, 1)
+		events, _ := ReadEvents(&buf)
+		done <- events
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	l.Append("done", []byte("c"))
+	l.Close()
+	l.Append("late", []byte("dropped"))
+
+	select {
+	case events := <-done:
+		if len(events) != 2 || events[0].ID != 2 || events[1].ID != 3 || events[1].Event != "done" {
+			t.Errorf("replayed events = %+v, want IDs 2 and 3", events)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Replay() did not return after Close()")
+	}
+
+	// Replaying a closed log returns immediately
+	var buf bytes.Buffer
+	if err := l.Replay(context.Background(), &buf, 0); err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if events, _ := ReadEvents(&buf); len(events) != 3 {
+		t.Errorf("full replay returned %d events, want 3", len(events))
+	}
+}
+
+func TestLogReplayCancelled(t *testing.T) {
+	l := NewLog()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := l.Replay(ctx, &bytes.Buffer{}, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Replay() error = %v, want deadline exceeded", err)
+	}
+}
+
+func TestRegistryExpiry(t *testing.T) {
+	r := NewRegistry(10 * time.Millisecond)
+	open := r.Create("open")
+	closed := r.Create("closed")
+	closed.Close()
+
+	if _, ok := r.Get("closed"); !ok {
+		t.Fatal("Get() did not find a recently closed log")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := r.Get("closed"); ok {
+		t.Error("Get() returned an expired log")
+	}
+	if got, ok := r.Get("open"); !ok || got != open {
+		t.Error("Get() dropped a log that is still open")
+	}
+}
+
+func TestRegistryContinuesIDs(t *testing.T) {
+	r := NewRegistry(time.Minute)
+	first := r.Create("debate")
+	first.Append("message", []byte("a"))
+	first.Append("message", []byte("b"))
+	first.Close()
+
+	// A new run of the same stream follows the IDs of the previous one
+	second := r.Create("debate")
+	if e := second.Append("message", []byte("c")); e.ID != 3 {
+		t.Errorf("first ID of the new log = %d, want 3", e.ID)
+	}
+	second.Append("done", []byte("d"))
+	second.Close()
+
+	tests := []struct {
+		lastID int
+		want   []int
+	}{
+		{lastID: 1, want: []int{3, 4}}, // Resuming the previous run
+		{lastID: 3, want: []int{4}},
+		{lastID: 4, want: nil},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		second.Replay(context.Background(), &buf, tt.lastID)
+		events, _ := ReadEvents(&buf)
+		var ids []int
+		for _, e := range events {
+			ids = append(ids, e.ID)
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+			t.Errorf("Replay(%d) IDs = %v, want %v", tt.lastID, ids, tt.want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/raphink/debate/shared/firebase"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// debatesCollection is the Firestore collection holding debate documents
+const debatesCollection = "debates"
+
+// FirestoreStore stores debates in the Firestore "debates" collection
+type FirestoreStore struct {
+	client *firestore.Client
+}
+
+// NewFirestoreStore creates a store backed by the shared Firestore client,
+// initializing the client if needed
+func NewFirestoreStore(ctx context.Context) (*FirestoreStore, error) {
+	if firebase.GetClient() == nil {
+		if err := firebase.InitFirestore(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return &FirestoreStore{client: firebase.GetClient()}, nil
+}
+
+// SaveDebate saves a debate document to Firestore
+func (s *FirestoreStore) SaveDebate(ctx context.Context, id string, debate *firebase.DebateDocument) error {
+	_, err := s.client.Collection(debatesCollection).Doc(id).Set(ctx, debate)
+	return err
+}
+
+// CreateDebate creates a debate document in Firestore, failing if the
+// document exists
+func (s *FirestoreStore) CreateDebate(ctx context.Context, id string, debate *firebase.DebateDocument) error {
+	_, err := s.client.Collection(debatesCollection).Doc(id).Create(ctx, debate)
+	if status.Code(err) == codes.AlreadyExists {
+		return ErrExists
+	}
+	return err
+}
+
+// GetDebate retrieves a debate document from Firestore by UUID
+func (s *FirestoreStore) GetDebate(ctx context.Context, id string) (*firebase.DebateDocument, error) {
+	doc, err := s.client.Collection(debatesCollection).Doc(id).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	var debate firebase.DebateDocument
+	if err := doc.DataTo(&debate); err != nil {
+		return nil, err
+	}
+
+	return &debate, nil
+}
+
+// UpdateDebate applies update to the debate stored under id within a
+// Firestore transaction, which may run update more than once on contention
+func (s *FirestoreStore) UpdateDebate(ctx context.Context, id string, update func(debate *firebase.DebateDocument) error) error {
+	ref := s.client.Collection(debatesCollection).Doc(id)
+	return s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		var debate firebase.DebateDocument
+		if err := doc.DataTo(&debate); err != nil {
+			return err
+		}
+		if err := update(&debate); err != nil {
+			return err
+		}
+
+		return tx.Set(ref, &debate)
+	})
+}
+
+// ListDebates fetches debates ordered by startedAt descending with pagination
+func (s *FirestoreStore) ListDebates(ctx context.Context, opts ListOptions) ([]firebase.DebateDocument, error) {
+	query := s.debates(opts.Status).
+		OrderBy("startedAt", firestore.Desc).
+		Offset(opts.Offset)
+	if opts.Limit > 0 {
+		query = query.Limit(opts.Limit)
+	}
+
+	return s.collect(ctx, query)
+}
+
+// SearchDebates scans the most recent debates and ranks them with match
+func (s *FirestoreStore) SearchDebates(ctx context.Context, match Matcher, opts SearchOptions) ([]firebase.DebateDocument, error) {
+	query := s.debates(opts.Status).
+		OrderBy("startedAt", firestore.Desc)
+	if opts.Window > 0 {
+		query = query.Limit(opts.Window)
+	}
+
+	debates, err := s.collect(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return rankDebates(debates, match, opts.Limit), nil
+}
+
+// CountDebates returns the total number of debates in Firestore with status
+func (s *FirestoreStore) CountDebates(ctx context.Context, status string) (int, error) {
+	query := s.debates(status)
+	results, err := query.
+		NewAggregationQuery().
+		WithCount("total").
+		Get(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count, ok := results["total"]
+	if !ok {
+		return 0, fmt.Errorf("count aggregation missing from result")
+	}
+
+	value, ok := count.(*firestorepb.Value)
+	if !ok {
+		return 0, fmt.Errorf("unexpected count aggregation type %T", count)
+	}
+
+	return int(value.GetIntegerValue()), nil
+}
+
+// debates queries the debates with status, or all debates when it is empty.
+// Ordering a status query by startedAt needs a composite index on
+// (status, startedAt DESC).
+func (s *FirestoreStore) debates(status string) firestore.Query {
+	query := s.client.Collection(debatesCollection).Query
+	if status != "" {
+		query = query.Where("status", "==", status)
+	}
+	return query
+}
+
+// Close is a no-op: the Firestore client is shared and closed via firebase.Close
+func (s *FirestoreStore) Close() error {
+	return nil
+}
+
+// collect runs query and decodes every returned document
+func (s *FirestoreStore) collect(ctx context.Context, query firestore.Query) ([]firebase.DebateDocument, error) {
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	var debates []firebase.DebateDocument
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate debates: %w", err)
+		}
+
+		var debate firebase.DebateDocument
+		if err := doc.DataTo(&debate); err != nil {
+			return nil, fmt.Errorf("failed to parse debate data: %w", err)
+		}
+		if debate.ID == "" {
+			debate.ID = doc.Ref.ID
+		}
+
+		debates = append(debates, debate)
+	}
+
+	return debates, nil
+}
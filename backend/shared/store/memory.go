@@ -0,0 +1,176 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/raphink/debate/shared/firebase"
+)
+
+// MemoryStore keeps debates in process memory.
+// It is meant for tests and local development: data is lost on restart.
+type MemoryStore struct {
+	debates map[string]firebase.DebateDocument
+	mu      sync.RWMutex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		debates: make(map[string]firebase.DebateDocument),
+	}
+}
+
+// SaveDebate stores a copy of the debate under id
+func (s *MemoryStore) SaveDebate(ctx context.Context, id string, debate *firebase.DebateDocument) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.debates[id] = copyDebate(debate)
+	return nil
+}
+
+// CreateDebate stores a copy of the debate under id unless one exists
+func (s *MemoryStore) CreateDebate(ctx context.Context, id string, debate *firebase.DebateDocument) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.debates[id]; ok {
+		return ErrExists
+	}
+	s.debates[id] = copyDebate(debate)
+	return nil
+}
+
+// GetDebate returns a copy of the debate stored under id
+func (s *MemoryStore) GetDebate(ctx context.Context, id string) (*firebase.DebateDocument, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	debate, ok := s.debates[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	result := copyDebate(&debate)
+	return &result, nil
+}
+
+// UpdateDebate applies update to a copy of the debate stored under id and
+// stores the result, holding the lock throughout
+func (s *MemoryStore) UpdateDebate(ctx context.Context, id string, update func(debate *firebase.DebateDocument) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	debate, ok := s.debates[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	updated := copyDebate(&debate)
+	if err := update(&updated); err != nil {
+		return err
+	}
+	s.debates[id] = copyDebate(&updated)
+	return nil
+}
+
+// ListDebates returns debates ordered by startedAt descending with pagination
+func (s *MemoryStore) ListDebates(ctx context.Context, opts ListOptions) ([]firebase.DebateDocument, error) {
+	debates := s.sorted(opts.Status)
+
+	if opts.Offset >= len(debates) {
+		return []firebase.DebateDocument{}, nil
+	}
+	debates = debates[opts.Offset:]
+	if opts.Limit > 0 && len(debates) > opts.Limit {
+		debates = debates[:opts.Limit]
+	}
+
+	return debates, nil
+}
+
+// SearchDebates scans the most recent debates and ranks them with match
+func (s *MemoryStore) SearchDebates(ctx context.Context, match Matcher, opts SearchOptions) ([]firebase.DebateDocument, error) {
+	debates := s.sorted(opts.Status)
+	if opts.Window > 0 && len(debates) > opts.Window {
+		debates = debates[:opts.Window]
+	}
+
+	return rankDebates(debates, match, opts.Limit), nil
+}
+
+// CountDebates returns the number of stored debates with status
+func (s *MemoryStore) CountDebates(ctx context.Context, status string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, debate := range s.debates {
+		if status == "" || debate.Status == status {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Close is a no-op for the in-memory store
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// sorted returns copies of the debates with status, or of all debates when
+// status is empty, ordered by startedAt descending
+func (s *MemoryStore) sorted(status string) []firebase.DebateDocument {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	debates := make([]firebase.DebateDocument, 0, len(s.debates))
+	for _, debate := range s.debates {
+		if status == "" || debate.Status == status {
+			debates = append(debates, copyDebate(&debate))
+		}
+	}
+
+	sort.Slice(debates, func(i, j int) bool {
+		if debates[i].StartedAt.Equal(debates[j].StartedAt) {
+			return debates[i].ID < debates[j].ID
+		}
+		return debates[i].StartedAt.After(debates[j].StartedAt)
+	})
+
+	return debates
+}
+
+// copyDebate returns a copy of debate that shares no slices or pointers with
+// the original
+func copyDebate(debate *firebase.DebateDocument) firebase.DebateDocument {
+	c := *debate
+	c.Topic.SuggestedNames = append([]string(nil), debate.Topic.SuggestedNames...)
+	c.Panelists = append([]firebase.Panelist(nil), debate.Panelists...)
+	for i, p := range debate.Panelists {
+		if p.Attribution != nil {
+			attribution := *p.Attribution
+			c.Panelists[i].Attribution = &attribution
+		}
+	}
+	c.Messages = append([]firebase.Message(nil), debate.Messages...)
+	c.Passages = append([]firebase.Passage(nil), debate.Passages...)
+	for i := range c.Messages {
+		c.Messages[i].Addresses = append([]string(nil), debate.Messages[i].Addresses...)
+		c.Messages[i].Citations = append([]firebase.Citation(nil), debate.Messages[i].Citations...)
+	}
+	if debate.Judgement != nil {
+		judgement := *debate.Judgement
+		judgement.Verdicts = append([]firebase.Verdict(nil), debate.Judgement.Verdicts...)
+		c.Judgement = &judgement
+	}
+	if debate.Review != nil {
+		review := *debate.Review
+		review.Findings = append([]firebase.Finding(nil), debate.Review.Findings...)
+		c.Review = &review
+	}
+	c.FormatIssues = append([]string(nil), debate.FormatIssues...)
+	return c
+}
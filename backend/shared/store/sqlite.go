@@ -0,0 +1,205 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/raphink/debate/shared/firebase"
+	_ "modernc.org/sqlite" // Pure Go driver, works with CGO_ENABLED=0
+)
+
+// sqliteSchema creates the debates table if it does not exist yet.
+// Documents are stored as JSON; started_at is duplicated for ordering.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS debates (
+	id         TEXT PRIMARY KEY,
+	started_at INTEGER NOT NULL,
+	document   TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS debates_started_at ON debates (started_at DESC);
+`
+
+// sqliteStatusFilter matches the debates with the status given twice as
+// parameter, or all debates when it is empty
+const sqliteStatusFilter = `(? = '' OR json_extract(document, '$.status') = ?)`
+
+// SQLiteStore stores debates in a local SQLite database file
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (or creates) the SQLite database at path
+func NewSQLiteStore(ctx context.Context, path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database %s: %w", path, err)
+	}
+
+	// SQLite allows a single writer; serialize access through one connection
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create SQLite schema: %w", err)
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+// SaveDebate creates or replaces the debate stored under id
+func (s *SQLiteStore) SaveDebate(ctx context.Context, id string, debate *firebase.DebateDocument) error {
+	data, err := json.Marshal(debate)
+	if err != nil {
+		return fmt.Errorf("failed to encode debate: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO debates (id, started_at, document) VALUES (?, ?, ?)
+		 ON CONFLICT(id) DO UPDATE SET started_at = excluded.started_at, document = excluded.document`,
+		id, debate.StartedAt.UnixNano(), string(data))
+	return err
+}
+
+// CreateDebate stores the debate under id unless one exists
+func (s *SQLiteStore) CreateDebate(ctx context.Context, id string, debate *firebase.DebateDocument) error {
+	data, err := json.Marshal(debate)
+	if err != nil {
+		return fmt.Errorf("failed to encode debate: %w", err)
+	}
+
+	result, err := s.db.ExecContext(ctx,
+		`INSERT INTO debates (id, started_at, document) VALUES (?, ?, ?) ON CONFLICT(id) DO NOTHING`,
+		id, debate.StartedAt.UnixNano(), string(data))
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrExists
+	}
+	return nil
+}
+
+// GetDebate returns the debate stored under id
+func (s *SQLiteStore) GetDebate(ctx context.Context, id string) (*firebase.DebateDocument, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, `SELECT document FROM debates WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var debate firebase.DebateDocument
+	if err := json.Unmarshal([]byte(data), &debate); err != nil {
+		return nil, fmt.Errorf("failed to parse debate data: %w", err)
+	}
+
+	return &debate, nil
+}
+
+// UpdateDebate applies update to the debate stored under id within a
+// transaction
+func (s *SQLiteStore) UpdateDebate(ctx context.Context, id string, update func(debate *firebase.DebateDocument) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var data string
+	err = tx.QueryRowContext(ctx, `SELECT document FROM debates WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var debate firebase.DebateDocument
+	if err := json.Unmarshal([]byte(data), &debate); err != nil {
+		return fmt.Errorf("failed to parse debate data: %w", err)
+	}
+	if err := update(&debate); err != nil {
+		return err
+	}
+
+	updated, err := json.Marshal(&debate)
+	if err != nil {
+		return fmt.Errorf("failed to encode debate: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE debates SET started_at = ?, document = ? WHERE id = ?`,
+		debate.StartedAt.UnixNano(), string(updated), id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListDebates returns debates ordered by startedAt descending with pagination
+func (s *SQLiteStore) ListDebates(ctx context.Context, opts ListOptions) ([]firebase.DebateDocument, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = -1 // SQLite treats a negative LIMIT as unbounded
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT document FROM debates WHERE `+sqliteStatusFilter+`
+		 ORDER BY started_at DESC, id LIMIT ? OFFSET ?`,
+		opts.Status, opts.Status, limit, opts.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanDebates(rows)
+}
+
+// SearchDebates scans the most recent debates and ranks them with match
+func (s *SQLiteStore) SearchDebates(ctx context.Context, match Matcher, opts SearchOptions) ([]firebase.DebateDocument, error) {
+	debates, err := s.ListDebates(ctx, ListOptions{Limit: opts.Window, Status: opts.Status})
+	if err != nil {
+		return nil, err
+	}
+
+	return rankDebates(debates, match, opts.Limit), nil
+}
+
+// CountDebates returns the number of stored debates with status
+func (s *SQLiteStore) CountDebates(ctx context.Context, status string) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM debates WHERE `+sqliteStatusFilter, status, status).Scan(&count)
+	return count, err
+}
+
+// Close closes the underlying database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// scanDebates decodes every document column returned by rows
+func scanDebates(rows *sql.Rows) ([]firebase.DebateDocument, error) {
+	defer rows.Close()
+
+	var debates []firebase.DebateDocument
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to iterate debates: %w", err)
+		}
+
+		var debate firebase.DebateDocument
+		if err := json.Unmarshal([]byte(data), &debate); err != nil {
+			return nil, fmt.Errorf("failed to parse debate data: %w", err)
+		}
+		debates = append(debates, debate)
+	}
+
+	return debates, rows.Err()
+}
@@ -0,0 +1,169 @@
+// Package store provides pluggable persistence for debate documents
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/raphink/debate/shared/firebase"
+)
+
+// ErrNotFound is returned when a debate does not exist in the store
+var ErrNotFound = errors.New("debate not found")
+
+// ErrExists is returned when creating a debate under an ID already in use
+var ErrExists = errors.New("debate already exists")
+
+// Backend names accepted in the DEBATE_STORE environment variable
+const (
+	BackendFirestore = "firestore"
+	BackendMemory    = "memory"
+	BackendSQLite    = "sqlite"
+)
+
+// DefaultSQLitePath is used when SQLITE_PATH is not set
+const DefaultSQLitePath = "debates.db"
+
+// Matcher scores a debate against a search query.
+// Debates scoring zero or less are left out of search results.
+type Matcher func(debate *firebase.DebateDocument) int
+
+// ListOptions controls which debates ListDebates returns
+type ListOptions struct {
+	Limit  int    // Maximum number of debates to return, all when zero
+	Offset int    // Number of debates to skip
+	Status string // Only debates with this status, any when empty
+}
+
+// SearchOptions controls how SearchDebates scans and trims results
+type SearchOptions struct {
+	Window int    // Number of most recent debates to scan
+	Limit  int    // Maximum number of results to return
+	Status string // Only debates with this status, any when empty
+}
+
+// DebateStore persists and queries debate documents
+type DebateStore interface {
+	// SaveDebate creates or replaces the debate stored under id
+	SaveDebate(ctx context.Context, id string, debate *firebase.DebateDocument) error
+	// CreateDebate stores the debate under id, or returns ErrExists if a
+	// debate is already stored under it
+	CreateDebate(ctx context.Context, id string, debate *firebase.DebateDocument) error
+	// GetDebate returns the debate stored under id, or ErrNotFound
+	GetDebate(ctx context.Context, id string) (*firebase.DebateDocument, error)
+	// UpdateDebate applies update to the debate stored under id and saves the
+	// result atomically, or returns ErrNotFound. An error returned by update
+	// leaves the debate unchanged and is returned as is.
+	UpdateDebate(ctx context.Context, id string, update func(debate *firebase.DebateDocument) error) error
+	// ListDebates returns debates ordered by startedAt descending
+	ListDebates(ctx context.Context, opts ListOptions) ([]firebase.DebateDocument, error)
+	// SearchDebates scores recent debates with match and returns the best ones,
+	// ordered by score (DESC), then startedAt (DESC)
+	SearchDebates(ctx context.Context, match Matcher, opts SearchOptions) ([]firebase.DebateDocument, error)
+	// CountDebates returns the total number of stored debates with status,
+	// or of all debates when status is empty
+	CountDebates(ctx context.Context, status string) (int, error)
+	// Close releases any resources held by the store
+	Close() error
+}
+
+// New opens the store for the given backend name
+func New(ctx context.Context, backend string) (DebateStore, error) {
+	switch backend {
+	case "", BackendFirestore:
+		return NewFirestoreStore(ctx)
+	case BackendMemory:
+		return NewMemoryStore(), nil
+	case BackendSQLite:
+		path := os.Getenv("SQLITE_PATH")
+		if path == "" {
+			path = DefaultSQLitePath
+		}
+		return NewSQLiteStore(ctx, path)
+	default:
+		return nil, fmt.Errorf("unknown debate store backend %q", backend)
+	}
+}
+
+// NewFromEnv opens the store selected by the DEBATE_STORE environment variable
+// (firestore, memory or sqlite). Firestore is used when it is unset.
+func NewFromEnv(ctx context.Context) (DebateStore, error) {
+	return New(ctx, os.Getenv("DEBATE_STORE"))
+}
+
+var (
+	defaultStore DebateStore
+	defaultMu    sync.Mutex
+)
+
+// Default returns the process-wide store, opening it from the environment on first use
+func Default(ctx context.Context) (DebateStore, error) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	if defaultStore != nil {
+		return defaultStore, nil
+	}
+
+	s, err := NewFromEnv(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultStore = s
+	log.Printf("Debate store initialized (backend: %s)", backendName())
+	return defaultStore, nil
+}
+
+// SetDefault replaces the process-wide store (used by tests and local servers)
+func SetDefault(s DebateStore) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultStore = s
+}
+
+// backendName returns the configured backend for logging
+func backendName() string {
+	if backend := os.Getenv("DEBATE_STORE"); backend != "" {
+		return backend
+	}
+	return BackendFirestore
+}
+
+// rankDebates applies match to debates and returns the best scoring ones
+func rankDebates(debates []firebase.DebateDocument, match Matcher, limit int) []firebase.DebateDocument {
+	type scored struct {
+		debate firebase.DebateDocument
+		score  int
+	}
+
+	var matches []scored
+	for i := range debates {
+		if score := match(&debates[i]); score > 0 {
+			matches = append(matches, scored{debates[i], score})
+		}
+	}
+
+	// Sort by score (DESC), then by startedAt (DESC) for ties
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].debate.StartedAt.After(matches[j].debate.StartedAt)
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	results := make([]firebase.DebateDocument, len(matches))
+	for i, m := range matches {
+		results[i] = m.debate
+	}
+	return results
+}
@@ -0,0 +1,374 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/raphink/debate/shared/firebase"
+)
+
+// testStores returns every backend that can run without external services
+func testStores(t *testing.T) map[string]DebateStore {
+	t.Helper()
+
+	sqliteStore, err := NewSQLiteStore(context.Background(), filepath.Join(t.TempDir(), "debates.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore() error = %v", err)
+	}
+	t.Cleanup(func() { sqliteStore.Close() })
+
+	return map[string]DebateStore{
+		BackendMemory: NewMemoryStore(),
+		BackendSQLite: sqliteStore,
+	}
+}
+
+func newDebate(id, topic string, startedAt time.Time) *firebase.DebateDocument {
+	return &firebase.DebateDocument{
+		ID:    id,
+		Topic: firebase.Topic{Text: topic, IsRelevant: true},
+		Panelists: []firebase.Panelist{
+			{ID: "augustine", Name: "Augustine of Hippo"},
+			{ID: "aquinas", Name: "Thomas Aquinas"},
+		},
+		Messages: []firebase.Message{
+			{ID: "moderator-0", PanelistID: "moderator", Text: "Welcome", Sequence: 0, IsComplete: true},
+		},
+		Status:    "complete",
+		StartedAt: startedAt,
+	}
+}
+
+func TestSaveAndGetDebate(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := s.SaveDebate(ctx, "d1", newDebate("d1", "Is war ever just?", now)); err != nil {
+				t.Fatalf("SaveDebate() error = %v", err)
+			}
+
+			got, err := s.GetDebate(ctx, "d1")
+			if err != nil {
+				t.Fatalf("GetDebate() error = %v", err)
+			}
+			if got.Topic.Text != "Is war ever just?" {
+				t.Errorf("GetDebate() topic = %q, want %q", got.Topic.Text, "Is war ever just?")
+			}
+			if len(got.Panelists) != 2 || len(got.Messages) != 1 {
+				t.Errorf("GetDebate() got %d panelists and %d messages, want 2 and 1", len(got.Panelists), len(got.Messages))
+			}
+			if !got.StartedAt.Equal(now) {
+				t.Errorf("GetDebate() startedAt = %v, want %v", got.StartedAt, now)
+			}
+
+			// Saving again replaces the document
+			updated := newDebate("d1", "Is war ever just?", now)
+			updated.Status = "in_progress"
+			if err := s.SaveDebate(ctx, "d1", updated); err != nil {
+				t.Fatalf("SaveDebate() error = %v", err)
+			}
+			got, _ = s.GetDebate(ctx, "d1")
+			if got.Status != "in_progress" {
+				t.Errorf("GetDebate() after update status = %q, want in_progress", got.Status)
+			}
+
+			if _, err := s.GetDebate(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetDebate(missing) error = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestUpdateDebate(t *testing.T) {
+	ctx := context.Background()
+
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			s.SaveDebate(ctx, "d1", newDebate("d1", "Is war ever just?", time.Now()))
+
+			// Concurrent updates all apply
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					err := s.UpdateDebate(ctx, "d1", func(debate *firebase.DebateDocument) error {
+						debate.Messages = append(debate.Messages, firebase.Message{PanelistID: "augustine", Sequence: len(debate.Messages)})
+						return nil
+					})
+					if err != nil {
+						t.Errorf("UpdateDebate() error = %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+			if got, _ := s.GetDebate(ctx, "d1"); len(got.Messages) != 11 {
+				t.Errorf("got %d messages after concurrent updates, want 11", len(got.Messages))
+			}
+
+			// A failed update changes nothing
+			errConflict := errors.New("conflict")
+			err := s.UpdateDebate(ctx, "d1", func(debate *firebase.DebateDocument) error {
+				debate.Status = "in_progress"
+				return errConflict
+			})
+			if !errors.Is(err, errConflict) {
+				t.Errorf("UpdateDebate() error = %v, want the update's error", err)
+			}
+			if got, _ := s.GetDebate(ctx, "d1"); got.Status != "complete" {
+				t.Errorf("status = %q after a failed update, want complete", got.Status)
+			}
+
+			err = s.UpdateDebate(ctx, "missing", func(*firebase.DebateDocument) error { return nil })
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("UpdateDebate(missing) error = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestCreateDebate(t *testing.T) {
+	ctx := context.Background()
+
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			// Only one of concurrent creations succeeds
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			created := 0
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					err := s.CreateDebate(ctx, "d1", newDebate("d1", "Is war ever just?", time.Now()))
+					if err != nil && !errors.Is(err, ErrExists) {
+						t.Errorf("CreateDebate() error = %v", err)
+					}
+					if err == nil {
+						mu.Lock()
+						created++
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+			if created != 1 {
+				t.Errorf("%d concurrent creations succeeded, want 1", created)
+			}
+
+			// An existing debate is left unchanged
+			s.SaveDebate(ctx, "d2", newDebate("d2", "Free will", time.Now()))
+			if err := s.CreateDebate(ctx, "d2", newDebate("d2", "Other topic", time.Now())); !errors.Is(err, ErrExists) {
+				t.Errorf("CreateDebate(existing) error = %v, want ErrExists", err)
+			}
+			if got, _ := s.GetDebate(ctx, "d2"); got.Topic.Text != "Free will" {
+				t.Errorf("topic = %q after a failed creation, want Free will", got.Topic.Text)
+			}
+		})
+	}
+}
+
+func TestListAndCountDebates(t *testing.T) {
+	ctx := context.Background()
+	base := time.Now().UTC()
+
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for i, id := range []string{"oldest", "middle", "newest"} {
+				s.SaveDebate(ctx, id, newDebate(id, "Topic "+id, base.Add(time.Duration(i)*time.Minute)))
+			}
+
+			count, err := s.CountDebates(ctx, "")
+			if err != nil {
+				t.Fatalf("CountDebates() error = %v", err)
+			}
+			if count != 3 {
+				t.Errorf("CountDebates() = %d, want 3", count)
+			}
+
+			debates, err := s.ListDebates(ctx, ListOptions{Limit: 2})
+			if err != nil {
+				t.Fatalf("ListDebates() error = %v", err)
+			}
+			if len(debates) != 2 || debates[0].ID != "newest" || debates[1].ID != "middle" {
+				t.Errorf("ListDebates(2, 0) = %v, want [newest middle]", ids(debates))
+			}
+
+			debates, _ = s.ListDebates(ctx, ListOptions{Limit: 2, Offset: 2})
+			if len(debates) != 1 || debates[0].ID != "oldest" {
+				t.Errorf("ListDebates(2, 2) = %v, want [oldest]", ids(debates))
+			}
+
+			debates, _ = s.ListDebates(ctx, ListOptions{Limit: 2, Offset: 10})
+			if len(debates) != 0 {
+				t.Errorf("ListDebates(2, 10) = %v, want none", ids(debates))
+			}
+		})
+	}
+}
+
+func TestListDebatesByStatus(t *testing.T) {
+	ctx := context.Background()
+	base := time.Now().UTC()
+
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for i, status := range []string{"complete", "in_progress", "failed", "complete"} {
+				id := fmt.Sprintf("d%d", i)
+				debate := newDebate(id, "Is war ever just?", base.Add(time.Duration(i)*time.Minute))
+				debate.Status = status
+				s.SaveDebate(ctx, id, debate)
+			}
+
+			debates, err := s.ListDebates(ctx, ListOptions{Status: "complete"})
+			if err != nil {
+				t.Fatalf("ListDebates() error = %v", err)
+			}
+			if got := ids(debates); strings.Join(got, ",") != "d3,d0" {
+				t.Errorf("ListDebates(complete) = %v, want [d3 d0]", got)
+			}
+
+			debates, _ = s.ListDebates(ctx, ListOptions{Limit: 1, Offset: 1, Status: "complete"})
+			if got := ids(debates); strings.Join(got, ",") != "d0" {
+				t.Errorf("ListDebates(complete, offset 1) = %v, want [d0]", got)
+			}
+
+			if count, _ := s.CountDebates(ctx, "complete"); count != 2 {
+				t.Errorf("CountDebates(complete) = %d, want 2", count)
+			}
+			if count, _ := s.CountDebates(ctx, ""); count != 4 {
+				t.Errorf("CountDebates() = %d, want 4", count)
+			}
+
+			all := func(*firebase.DebateDocument) int { return 1 }
+			debates, _ = s.SearchDebates(ctx, all, SearchOptions{Window: 50, Limit: 10, Status: "complete"})
+			if got := ids(debates); strings.Join(got, ",") != "d3,d0" {
+				t.Errorf("SearchDebates(complete) = %v, want [d3 d0]", got)
+			}
+		})
+	}
+}
+
+func TestSearchDebates(t *testing.T) {
+	ctx := context.Background()
+	base := time.Now().UTC()
+
+	// Score is the number of query words found in the topic
+	matchWords := func(words ...string) Matcher {
+		return func(debate *firebase.DebateDocument) int {
+			score := 0
+			for _, w := range words {
+				if strings.Contains(strings.ToLower(debate.Topic.Text), w) {
+					score++
+				}
+			}
+			return score
+		}
+	}
+
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			s.SaveDebate(ctx, "a", newDebate("a", "Climate change and stewardship", base))
+			s.SaveDebate(ctx, "b", newDebate("b", "Climate policy", base.Add(time.Minute)))
+			s.SaveDebate(ctx, "c", newDebate("c", "Free will", base.Add(2*time.Minute)))
+			s.SaveDebate(ctx, "d", newDebate("d", "Climate change ethics", base.Add(3*time.Minute)))
+
+			debates, err := s.SearchDebates(ctx, matchWords("climate", "change"), SearchOptions{Window: 50, Limit: 10})
+			if err != nil {
+				t.Fatalf("SearchDebates() error = %v", err)
+			}
+			if got := ids(debates); strings.Join(got, ",") != "d,a,b" {
+				t.Errorf("SearchDebates() = %v, want [d a b]", got)
+			}
+
+			// Window only scans the most recent debates
+			debates, _ = s.SearchDebates(ctx, matchWords("climate"), SearchOptions{Window: 2, Limit: 10})
+			if got := ids(debates); strings.Join(got, ",") != "d" {
+				t.Errorf("SearchDebates(window=2) = %v, want [d]", got)
+			}
+
+			debates, _ = s.SearchDebates(ctx, matchWords("climate"), SearchOptions{Window: 50, Limit: 1})
+			if len(debates) != 1 {
+				t.Errorf("SearchDebates(limit=1) returned %d debates", len(debates))
+			}
+		})
+	}
+}
+
+func TestMemoryStoreReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	debate := newDebate("d1", "Is war ever just?", time.Now())
+	debate.Messages[0].Citations = []firebase.Citation{{Work: "City of God"}}
+	debate.Passages = []firebase.Passage{{ID: "city-of-god.txt#1", Text: "Two cities"}}
+	debate.Panelists[0].Attribution = &firebase.Attribution{License: "Public domain"}
+	debate.Judgement = &firebase.Judgement{Verdicts: []firebase.Verdict{{PanelistID: "augustine", Fidelity: 9}}}
+	debate.Review = &firebase.Review{Findings: []firebase.Finding{{MessageID: "moderator-0"}}}
+	s.SaveDebate(ctx, "d1", debate)
+	debate.Messages[0].Text = "mutated"
+	debate.Messages[0].Citations[0].Work = "mutated"
+	debate.Passages[0].Text = "mutated"
+	debate.Panelists[0].Attribution.License = "mutated"
+	debate.Judgement.Verdicts[0].Fidelity = 1
+	debate.Review.Findings[0].MessageID = "mutated"
+
+	got, _ := s.GetDebate(ctx, "d1")
+	if got.Messages[0].Text != "Welcome" {
+		t.Errorf("stored debate was mutated through caller's slice: %q", got.Messages[0].Text)
+	}
+	if got.Messages[0].Citations[0].Work != "City of God" {
+		t.Errorf("stored citations were mutated through caller's slice: %+v", got.Messages[0].Citations)
+	}
+	if got.Passages[0].Text != "Two cities" {
+		t.Errorf("stored passages were mutated through caller's slice: %+v", got.Passages)
+	}
+	if got.Panelists[0].Attribution.License != "Public domain" {
+		t.Errorf("stored attribution was mutated through caller's pointer: %+v", got.Panelists[0].Attribution)
+	}
+	if got.Judgement.Verdicts[0].Fidelity != 9 || got.Review.Findings[0].MessageID != "moderator-0" {
+		t.Errorf("stored judgement or review was mutated through caller's pointer: %+v, %+v", got.Judgement, got.Review)
+	}
+
+	// Debates returned are copies too
+	got.Messages[0].Citations[0].Work = "mutated"
+	got.Panelists[0].Attribution.License = "mutated"
+	got.Review.Findings[0].MessageID = "mutated"
+	again, _ := s.GetDebate(ctx, "d1")
+	if again.Messages[0].Citations[0].Work != "City of God" || again.Panelists[0].Attribution.License != "Public domain" || again.Review.Findings[0].MessageID != "moderator-0" {
+		t.Errorf("stored debate was mutated through a returned debate: %+v", again)
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Setenv("SQLITE_PATH", filepath.Join(t.TempDir(), "env.db"))
+
+	for _, backend := range []string{BackendMemory, BackendSQLite} {
+		s, err := New(context.Background(), backend)
+		if err != nil {
+			t.Errorf("New(%q) error = %v", backend, err)
+			continue
+		}
+		s.Close()
+	}
+
+	if _, err := New(context.Background(), "postgres"); err == nil {
+		t.Error("New(postgres) expected error for unknown backend")
+	}
+}
+
+func ids(debates []firebase.DebateDocument) []string {
+	result := make([]string, len(debates))
+	for i, d := range debates {
+		result[i] = d.ID
+	}
+	return result
+}
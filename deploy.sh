@@ -101,8 +101,13 @@ check_prerequisites() {
 deploy_backend() {
     log_info "Deploying backend Cloud Functions to $REGION..."
     
-    # Deploy validate-topic function
+    # Deploy validate-topic function (with shared module)
     log_info "Deploying validate-topic function..."
+
+    # Vendor dependencies including shared module
+    log_info "Vendoring dependencies for validate-topic..."
+    (cd ./backend/functions/validate-topic && go mod vendor)
+
     gcloud functions deploy validate-topic \
         --gen2 \
         --runtime="$RUNTIME" \
@@ -118,6 +123,9 @@ deploy_backend() {
         --max-instances=100 \
         --min-instances=0 \
         --quiet
+
+    # Clean up vendor directory
+    rm -rf ./backend/functions/validate-topic/vendor
     
     # Get the URL
     VALIDATE_URL=$(gcloud functions describe validate-topic --region="$REGION" --gen2 --format="value(serviceConfig.uri)")
@@ -147,15 +155,134 @@ deploy_backend() {
         --max-instances=100 \
         --min-instances=0 \
         --quiet
+
+    # continue-debate shares the generate-debate source
+    gcloud functions deploy continue-debate \
+        --gen2 \
+        --runtime="$RUNTIME" \
+        --region="$REGION" \
+        --source=./backend/functions/generate-debate \
+        --entry-point=HandleContinueDebate \
+        --trigger-http \
+        --allow-unauthenticated \
+        --set-secrets=ANTHROPIC_API_KEY=anthropic-api-key:latest \
+        --set-env-vars=ALLOWED_ORIGIN=https://debates.jollygood.ch,GCP_PROJECT_ID=$PROJECT_ID \
+        --memory=512MB \
+        --timeout=300s \
+        --max-instances=100 \
+        --min-instances=0 \
+        --quiet
+    
+    # fork-debate shares the generate-debate source
+    gcloud functions deploy fork-debate \
+        --gen2 \
+        --runtime="$RUNTIME" \
+        --region="$REGION" \
+        --source=./backend/functions/generate-debate \
+        --entry-point=HandleForkDebate \
+        --trigger-http \
+        --allow-unauthenticated \
+        --set-secrets=ANTHROPIC_API_KEY=anthropic-api-key:latest \
+        --set-env-vars=ALLOWED_ORIGIN=https://debates.jollygood.ch,GCP_PROJECT_ID=$PROJECT_ID \
+        --memory=512MB \
+        --timeout=300s \
+        --max-instances=100 \
+        --min-instances=0 \
+        --quiet
+    
+    # interject-debate shares the generate-debate source
+    gcloud functions deploy interject-debate \
+        --gen2 \
+        --runtime="$RUNTIME" \
+        --region="$REGION" \
+        --source=./backend/functions/generate-debate \
+        --entry-point=HandleInterjectDebate \
+        --trigger-http \
+        --allow-unauthenticated \
+        --set-secrets=ANTHROPIC_API_KEY=anthropic-api-key:latest \
+        --set-env-vars=ALLOWED_ORIGIN=https://debates.jollygood.ch,GCP_PROJECT_ID=$PROJECT_ID \
+        --memory=512MB \
+        --timeout=300s \
+        --max-instances=100 \
+        --min-instances=0 \
+        --quiet
+    
+    # reply-debate shares the generate-debate source
+    gcloud functions deploy reply-debate \
+        --gen2 \
+        --runtime="$RUNTIME" \
+        --region="$REGION" \
+        --source=./backend/functions/generate-debate \
+        --entry-point=HandleReplyDebate \
+        --trigger-http \
+        --allow-unauthenticated \
+        --set-secrets=ANTHROPIC_API_KEY=anthropic-api-key:latest \
+        --set-env-vars=ALLOWED_ORIGIN=https://debates.jollygood.ch,GCP_PROJECT_ID=$PROJECT_ID \
+        --memory=512MB \
+        --timeout=300s \
+        --max-instances=100 \
+        --min-instances=0 \
+        --quiet
+    
+    # translate-debate shares the generate-debate source
+    gcloud functions deploy translate-debate \
+        --gen2 \
+        --runtime="$RUNTIME" \
+        --region="$REGION" \
+        --source=./backend/functions/generate-debate \
+        --entry-point=HandleTranslateDebate \
+        --trigger-http \
+        --allow-unauthenticated \
+        --set-secrets=ANTHROPIC_API_KEY=anthropic-api-key:latest \
+        --set-env-vars=ALLOWED_ORIGIN=https://debates.jollygood.ch,GCP_PROJECT_ID=$PROJECT_ID \
+        --memory=512MB \
+        --timeout=300s \
+        --max-instances=100 \
+        --min-instances=0 \
+        --quiet
+    
+    # check-debate shares the generate-debate source
+    gcloud functions deploy check-debate \
+        --gen2 \
+        --runtime="$RUNTIME" \
+        --region="$REGION" \
+        --source=./backend/functions/generate-debate \
+        --entry-point=HandleCheckDebate \
+        --trigger-http \
+        --allow-unauthenticated \
+        --set-secrets=ANTHROPIC_API_KEY=anthropic-api-key:latest \
+        --set-env-vars=ALLOWED_ORIGIN=https://debates.jollygood.ch,GCP_PROJECT_ID=$PROJECT_ID \
+        --memory=512MB \
+        --timeout=300s \
+        --max-instances=100 \
+        --min-instances=0 \
+        --quiet
     
     # Clean up vendor directory
     rm -rf ./backend/functions/generate-debate/vendor
     
     DEBATE_URL=$(gcloud functions describe generate-debate --region="$REGION" --gen2 --format="value(serviceConfig.uri)")
     log_info "generate-debate deployed: $DEBATE_URL"
-    
-    # Deploy get-portrait function
+    CONTINUE_URL=$(gcloud functions describe continue-debate --region="$REGION" --gen2 --format="value(serviceConfig.uri)")
+    log_info "continue-debate deployed: $CONTINUE_URL"
+    FORK_URL=$(gcloud functions describe fork-debate --region="$REGION" --gen2 --format="value(serviceConfig.uri)")
+    log_info "fork-debate deployed: $FORK_URL"
+    INTERJECT_URL=$(gcloud functions describe interject-debate --region="$REGION" --gen2 --format="value(serviceConfig.uri)")
+    log_info "interject-debate deployed: $INTERJECT_URL"
+    REPLY_URL=$(gcloud functions describe reply-debate --region="$REGION" --gen2 --format="value(serviceConfig.uri)")
+    log_info "reply-debate deployed: $REPLY_URL"
+    TRANSLATE_URL=$(gcloud functions describe translate-debate --region="$REGION" --gen2 --format="value(serviceConfig.uri)")
+    log_info "translate-debate deployed: $TRANSLATE_URL"
+    CHECK_URL=$(gcloud functions describe check-debate --region="$REGION" --gen2 --format="value(serviceConfig.uri)")
+    log_info "check-debate deployed: $CHECK_URL"
+    
+    # Deploy get-portrait function (with shared module)
     log_info "Deploying get-portrait function..."
+    
+    # Vendor dependencies including shared module
+    log_info "Vendoring dependencies for get-portrait..."
+    (cd ./backend/functions/get-portrait && go mod vendor)
+    
     gcloud functions deploy get-portrait \
         --gen2 \
         --runtime="$RUNTIME" \
@@ -164,13 +291,16 @@ deploy_backend() {
         --entry-point=GetPortrait \
         --trigger-http \
         --allow-unauthenticated \
-        --set-env-vars=ALLOWED_ORIGIN=https://debates.jollygood.ch \
-        --memory=256MB \
+        --set-env-vars=ALLOWED_ORIGIN=https://debates.jollygood.ch,GCP_PROJECT_ID=$PROJECT_ID,PORTRAIT_CACHE=store \
+        --memory=512MB \
         --timeout=10s \
         --max-instances=100 \
         --min-instances=0 \
         --quiet
     
+    # Clean up vendor directory
+    rm -rf ./backend/functions/get-portrait/vendor
+    
     PORTRAIT_URL=$(gcloud functions describe get-portrait --region="$REGION" --gen2 --format="value(serviceConfig.uri)")
     log_info "get-portrait deployed: $PORTRAIT_URL"
     
@@ -337,6 +467,12 @@ main() {
         log_info "Backend Functions:"
         log_info "  - validate-topic: $VALIDATE_URL"
         log_info "  - generate-debate: $DEBATE_URL"
+        log_info "  - continue-debate: $CONTINUE_URL"
+        log_info "  - fork-debate: $FORK_URL"
+        log_info "  - interject-debate: $INTERJECT_URL"
+        log_info "  - reply-debate: $REPLY_URL"
+        log_info "  - translate-debate: $TRANSLATE_URL"
+        log_info "  - check-debate: $CHECK_URL"
         log_info "  - get-portrait: $PORTRAIT_URL"
     fi
     
@@ -159,7 +159,7 @@ const loadImageAsDataURL = (url) => {
  * Generates a PDF document from debate data
  * @param {Object} debateData - The debate data to export
  * @param {string} debateData.topic - The debate topic
- * @param {Array} debateData.panelists - Array of panelist objects with avatarUrl
+ * @param {Array} debateData.panelists - Array of panelist objects with avatarUrl and portrait attribution
  * @param {Array} debateData.messages - Array of message objects {panelistId, text}
  * @returns {Promise<jsPDF>} The generated PDF document
  */
@@ -476,6 +476,39 @@ export const generateDebatePDF = async (debateData) => {
     pdf.setTextColor(31, 41, 55);
   });
 
+  // ========== PORTRAIT CREDITS ==========
+
+  // Wikimedia licenses require crediting the portraits
+  const credited = panelists.filter(p => p.attribution);
+  if (credited.length > 0) {
+    yPosition += 5;
+    checkPageBreak(20);
+
+    pdf.setFontSize(14);
+    pdf.setFont('helvetica', 'bold');
+    pdf.setTextColor(31, 41, 55);
+    pdf.text('Portrait credits:', margin, yPosition);
+    yPosition += 8;
+
+    pdf.setFontSize(8);
+    pdf.setFont('helvetica', 'normal');
+    pdf.setTextColor(75, 85, 99);
+    credited.forEach((panelist) => {
+      const { artist, license, licenseUrl, sourceUrl } = panelist.attribution;
+      const credit = [
+        `${panelist.name}: ${artist || 'Unknown author'}`,
+        license && (licenseUrl ? `${license} (${licenseUrl})` : license),
+        sourceUrl && `via Wikimedia Commons, ${sourceUrl}`,
+      ].filter(Boolean).join(', ');
+      const lines = pdf.splitTextToSize(credit, contentWidth);
+
+      checkPageBreak(lines.length * 4 + 2);
+      pdf.text(lines, margin, yPosition);
+      yPosition += lines.length * 4 + 2;
+    });
+    pdf.setTextColor(31, 41, 55);
+  }
+
   // Add page numbers to all pages
   const totalPages = pdf.internal.getNumberOfPages();
   for (let i = 1; i <= totalPages; i++) {
@@ -1,6 +1,10 @@
 import { useState, useCallback } from 'react';
 import { validateTopic } from '../services/topicService';
-import { getPortrait } from '../services/portraitService';
+import { getPortraits } from '../services/portraitService';
+
+// Delay after the last panelist arrived before fetching the portraits received
+// so far, so that panelists streamed together share a request
+const PORTRAIT_BATCH_DELAY_MS = 300;
 
 /**
  * Custom hook for topic validation with streaming panelist results
@@ -23,6 +27,33 @@ const useTopicValidation = () => {
     setValidationResult(null);
     setPanelists([]);
 
+    // Panelists whose portraits have not been requested yet
+    let received = [];
+    let batchTimer = null;
+
+    // Fetch the portraits of the panelists received so far asynchronously
+    const flushPortraits = () => {
+      clearTimeout(batchTimer);
+      batchTimer = null;
+      if (received.length === 0) {
+        return;
+      }
+
+      const batch = received;
+      received = [];
+      getPortraits(batch).then(portraits => {
+        setPanelists(prevPanelists =>
+          prevPanelists.map(p =>
+            portraits[p.id]
+              ? { ...p, avatarUrl: portraits[p.id].portraitUrl, attribution: portraits[p.id].attribution }
+              : p
+          )
+        );
+      }).catch(err => {
+        console.error('Failed to fetch portraits:', err);
+      });
+    };
+
     try {
       await validateTopic(
         topic,
@@ -49,31 +80,27 @@ const useTopicValidation = () => {
           });
           // Add panelist with placeholder first
           setPanelists((prev) => [...prev, panelist]);
-          
-          // Fetch portrait asynchronously
           if (panelist.id && panelist.name) {
-            getPortrait(panelist.id, panelist.name).then(portraitUrl => {
-              setPanelists(prevPanelists =>
-                prevPanelists.map(p =>
-                  p.id === panelist.id ? { ...p, avatarUrl: portraitUrl } : p
-                )
-              );
-            }).catch(err => {
-              console.error(`Failed to fetch portrait for ${panelist.name}:`, err);
-            });
+            received.push(panelist);
+            clearTimeout(batchTimer);
+            batchTimer = setTimeout(flushPortraits, PORTRAIT_BATCH_DELAY_MS);
           }
         },
         // onError callback
         (err) => {
           setError(err);
           setIsValidating(false);
+          // Panelists already shown still get their portraits
+          flushPortraits();
         },
         // onComplete callback
         () => {
           setIsValidating(false);
+          flushPortraits();
         }
       );
     } catch (err) {
+      flushPortraits();
       setError(err);
       setIsValidating(false);
       throw err;
@@ -1,7 +1,12 @@
 import apiClient from './api';
+import { parseEventStream } from './eventStream';
+
+// Number of times a dropped debate stream is resumed before giving up
+const MAX_RECONNECTS = 3;
 
 /**
- * Generate a debate with streaming responses via Server-Sent Events
+ * Generate a debate with streaming responses via Server-Sent Events.
+ * Dropped connections are resumed from the last received event.
  * 
  * @param {string} topic - The debate topic
  * @param {Array} selectedPanelists - Array of selected panelist objects
@@ -24,78 +29,116 @@ export const generateDebateStream = (topic, selectedPanelists, onMessage, onErro
       bio: p.bio,
       avatarUrl: p.avatarUrl,
       position: p.position,
+      born: p.born,
+      died: p.died,
+      attribution: p.attribution,
     })),
   };
 
-  // Use fetch with ReadableStream for SSE
+  // Use fetch with ReadableStream for SSE (EventSource cannot POST)
   const abortController = new AbortController();
+  let debateId = null;
+  let lastEventId = null;
+  let reconnects = 0;
+
+  // Read events until the stream ends; returns true once the debate finished
+  const readEvents = async (response) => {
+    const reader = response.body.getReader();
+    const decoder = new TextDecoder();
+    let buffer = '';
+
+    // eslint-disable-next-line no-constant-condition
+    while (true) {
+      const { done, value } = await reader.read();
+      if (done) return false;
+
+      // Decode the chunk and process complete events from buffer
+      buffer += decoder.decode(value, { stream: true });
+      const { events, rest } = parseEventStream(buffer);
+      buffer = rest; // Keep incomplete event in buffer
+
+      for (const event of events) {
+        if (event.id) lastEventId = event.id;
+
+        let chunk;
+        try {
+          chunk = JSON.parse(event.data);
+        } catch (err) {
+          console.warn('Failed to parse chunk:', event.data, err);
+          continue;
+        }
 
-  fetch(url, {
-    method: 'POST',
-    headers: {
-      'Content-Type': 'application/json',
-    },
-    body: JSON.stringify(requestBody),
-    signal: abortController.signal,
-  })
-    .then(async (response) => {
-      if (!response.ok) {
-        const errorData = await response.json();
-        throw new Error(errorData.error || 'Failed to generate debate');
-      }
-
-      // Extract debate ID from header
-      const debateId = response.headers.get('X-Debate-Id');
-      if (debateId && onDebateId) {
-        onDebateId(debateId);
+        if (chunk.type === 'message' && chunk.panelistId && chunk.text) {
+          onMessage(chunk.panelistId, chunk.text);
+        } else if (chunk.type === 'error') {
+          onError(new Error(chunk.error || 'Unknown error occurred'));
+          return true;
+        } else if (chunk.type === 'done') {
+          if (onComplete) onComplete();
+          return true;
+        }
       }
+    }
+  };
 
-      const reader = response.body.getReader();
-      const decoder = new TextDecoder();
-      let buffer = '';
-
-      // eslint-disable-next-line no-constant-condition
-      while (true) {
-        const { done, value } = await reader.read();
+  // Reconnect to the debate, receiving only the events after lastEventId
+  const resume = () => {
+    const headers = lastEventId ? { 'Last-Event-ID': lastEventId } : {};
+    return fetch(`${url}?debateId=${encodeURIComponent(debateId)}`, {
+      headers,
+      signal: abortController.signal,
+    });
+  };
 
-        if (done) {
-          if (onComplete) onComplete();
-          break;
-        }
+  const run = async () => {
+    let response = await fetch(url, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify(requestBody),
+      signal: abortController.signal,
+    });
 
-        // Decode the chunk and add to buffer
-        buffer += decoder.decode(value, { stream: true });
-
-        // Process complete JSON objects from buffer
-        const lines = buffer.split('\n');
-        buffer = lines.pop() || ''; // Keep incomplete line in buffer
-
-        for (const line of lines) {
-          if (!line.trim()) continue;
-
-          try {
-            const chunk = JSON.parse(line);
-
-            if (chunk.type === 'message' && chunk.panelistId && chunk.text) {
-              onMessage(chunk.panelistId, chunk.text);
-            } else if (chunk.type === 'error') {
-              onError(new Error(chunk.error || 'Unknown error occurred'));
-              return;
-            } else if (chunk.type === 'done') {
-              if (onComplete) onComplete();
-              return;
-            }
-          } catch (err) {
-            console.warn('Failed to parse chunk:', line, err);
-          }
-        }
+    if (!response.ok) {
+      const errorData = await response.json();
+      throw new Error(errorData.error || 'Failed to generate debate');
+    }
+
+    // Extract debate ID from header
+    debateId = response.headers.get('X-Debate-Id');
+    if (debateId && onDebateId) {
+      onDebateId(debateId);
+    }
+
+    // eslint-disable-next-line no-constant-condition
+    while (true) {
+      let streamError = null;
+      try {
+        if (await readEvents(response)) return;
+      } catch (err) {
+        if (err.name === 'AbortError') throw err;
+        streamError = err;
       }
-    })
-    .catch((error) => {
-      if (error.name !== 'AbortError') {
-        onError(error);
+
+      // The connection dropped before the debate finished: resume it
+      if (debateId && reconnects < MAX_RECONNECTS) {
+        reconnects += 1;
+        response = await resume();
+        if (response.ok) continue;
       }
-    });
+
+      if (streamError) throw streamError;
+      if (onComplete) onComplete();
+      return;
+    }
+  };
+
+  run().catch((error) => {
+    if (error.name !== 'AbortError') {
+      onError(error);
+    }
+  });
 
   // Return cleanup function
   return () => {
@@ -0,0 +1,44 @@
+/**
+ * Parse complete Server-Sent Events from a text buffer
+ *
+ * Events are separated by a blank line. The trailing incomplete event is
+ * returned as `rest` so it can be prepended to the next chunk of the stream.
+ *
+ * @param {string} buffer - Text received so far
+ * @returns {{events: Array<{id: string|null, event: string, data: string}>, rest: string}}
+ */
+export const parseEventStream = (buffer) => {
+  const blocks = buffer.replace(/\r\n/g, '\n').split('\n\n');
+  const rest = blocks.pop() || '';
+  const events = [];
+
+  for (const block of blocks) {
+    let id = null;
+    let event = 'message';
+    const data = [];
+
+    for (const line of block.split('\n')) {
+      // Skip blank lines and comments
+      if (!line || line.startsWith(':')) continue;
+
+      const colon = line.indexOf(':');
+      const field = colon === -1 ? line : line.slice(0, colon);
+      let value = colon === -1 ? '' : line.slice(colon + 1);
+      if (value.startsWith(' ')) value = value.slice(1);
+
+      if (field === 'id') id = value;
+      else if (field === 'event') event = value;
+      else if (field === 'data') data.push(value);
+    }
+
+    if (data.length > 0) {
+      events.push({ id, event, data: data.join('\n') });
+    }
+  }
+
+  return { events, rest };
+};
+
+export default {
+  parseEventStream,
+};
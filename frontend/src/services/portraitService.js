@@ -1,12 +1,14 @@
 const GET_PORTRAIT_URL = process.env.REACT_APP_GET_PORTRAIT_URL || 'http://localhost:8082';
 
 /**
- * Fetches portrait URL for a panelist from Wikimedia Commons
+ * Fetches portrait URL for a panelist from Wikimedia Commons, served through
+ * get-portrait so images are square and can be embedded in PDF exports
  * @param {string} panelistId - Unique panelist identifier
  * @param {string} panelistName - Full name for Wikimedia search
+ * @param {string} [tagline] - Panelist tagline, whose lifespan disambiguates the name
  * @returns {Promise<string>} Portrait URL or placeholder
  */
-export const getPortrait = async (panelistId, panelistName) => {
+export const getPortrait = async (panelistId, panelistName, tagline) => {
   try {
     const response = await fetch(GET_PORTRAIT_URL, {
       method: 'POST',
@@ -16,6 +18,8 @@ export const getPortrait = async (panelistId, panelistName) => {
       body: JSON.stringify({
         panelistId,
         panelistName,
+        tagline,
+        proxy: true,
       }),
     });
 
@@ -32,6 +36,51 @@ export const getPortrait = async (panelistId, panelistName) => {
   }
 };
 
+/**
+ * Fetches the portrait URLs of several panelists in one request
+ * @param {Array<{id: string, name: string, tagline?: string}>} panelists - Panelists to fetch
+ * @returns {Promise<Object<string, {portraitUrl: string, attribution?: Object}>>} Portrait URL
+ * or placeholder, and its license and credits, by panelist ID
+ */
+export const getPortraits = async (panelists) => {
+  const placeholders = Object.fromEntries(panelists.map(p => [p.id, { portraitUrl: 'placeholder-avatar.svg' }]));
+  try {
+    const response = await fetch(GET_PORTRAIT_URL, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify({
+        panelists: panelists.map(p => ({
+          panelistId: p.id,
+          panelistName: p.name,
+          tagline: p.tagline,
+          proxy: true,
+        })),
+      }),
+    });
+
+    if (!response.ok) {
+      console.error('Batch portrait fetch failed:', response.status);
+      return placeholders;
+    }
+
+    const data = await response.json();
+    const portraits = { ...placeholders };
+    Object.entries(data.portraits || {}).forEach(([id, portrait]) => {
+      portraits[id] = {
+        portraitUrl: portrait.portraitUrl || 'placeholder-avatar.svg',
+        attribution: portrait.attribution,
+      };
+    });
+    return portraits;
+  } catch (error) {
+    console.error('Error fetching portraits:', error);
+    return placeholders;
+  }
+};
+
 export default {
   getPortrait,
+  getPortraits,
 };
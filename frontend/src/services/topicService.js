@@ -1,3 +1,5 @@
+import { parseEventStream } from './eventStream';
+
 const VALIDATE_TOPIC_URL = process.env.REACT_APP_VALIDATE_TOPIC_URL || 'http://localhost:8080';
 
 /**
@@ -50,28 +52,26 @@ export const validateTopic = async (
       }
 
       buffer += decoder.decode(value, { stream: true });
-      const lines = buffer.split('\n');
-      buffer = lines.pop(); // Keep incomplete line in buffer
+      const { events, rest } = parseEventStream(buffer);
+      buffer = rest; // Keep incomplete event in buffer
+
+      for (const event of events) {
+        try {
+          const chunk = JSON.parse(event.data);
 
-      for (const line of lines) {
-        if (line.trim()) {
-          try {
-            const chunk = JSON.parse(line);
-            
-            if (chunk.type === 'validation') {
-              const data = JSON.parse(chunk.data);
-              if (onValidation) onValidation(data);
-            } else if (chunk.type === 'panelist') {
-              const panelist = JSON.parse(chunk.data);
-              if (onPanelist) onPanelist(panelist);
-            } else if (chunk.type === 'error') {
-              if (onError) onError(new Error(chunk.error));
-            } else if (chunk.type === 'done') {
-              if (onComplete) onComplete();
-            }
-          } catch (err) {
-            console.error('Failed to parse chunk:', err, line);
+          if (chunk.type === 'validation') {
+            const data = JSON.parse(chunk.data);
+            if (onValidation) onValidation(data);
+          } else if (chunk.type === 'panelist') {
+            const panelist = JSON.parse(chunk.data);
+            if (onPanelist) onPanelist(panelist);
+          } else if (chunk.type === 'error') {
+            if (onError) onError(new Error(chunk.error));
+          } else if (chunk.type === 'done') {
+            if (onComplete) onComplete();
           }
+        } catch (err) {
+          console.error('Failed to parse chunk:', err, event.data);
         }
       }
     }